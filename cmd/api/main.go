@@ -17,6 +17,7 @@ import (
 	"github.com/ETAnderson/conductor/internal/logging"
 	"github.com/ETAnderson/conductor/internal/migrate"
 	"github.com/ETAnderson/conductor/internal/state"
+	"github.com/ETAnderson/conductor/internal/webhooks"
 )
 
 func main() {
@@ -104,16 +105,20 @@ ON DUPLICATE KEY UPDATE name=VALUES(name)`)
 	})
 
 	// Handlers (tenant is resolved from request context now; no TenantID fields here)
+	notifier := webhooks.Notifier{}
+
 	debugUpsert := handlers.DebugUpsertHandler{
 		Processor:       proc,
 		Store:           store,
 		EnabledChannels: []string{"google"},
+		Webhooks:        notifier,
 	}
 
 	debugBulk := handlers.DebugBulkUpsertHandler{
 		Processor:       proc,
 		Store:           store,
 		EnabledChannels: []string{"google"},
+		Webhooks:        notifier,
 	}
 
 	mux.Handle("/v1/debug/products:upsert", middleware.IdempotencyMiddleware{
@@ -134,6 +139,33 @@ ON DUPLICATE KEY UPDATE name=VALUES(name)`)
 		Store: store,
 	})
 
+	mux.Handle("/v1/runs/", handlers.RunsHandler{
+		Store: store,
+	})
+
+	products := handlers.ProductsHandler{
+		Store: store,
+	}
+	mux.Handle("/v1/products", products)
+	mux.Handle("/v1/products/", products)
+
+	feeds := handlers.FeedsHandler{
+		Store: store,
+	}
+	mux.Handle("/v1/feeds", feeds)
+	mux.Handle("/v1/feeds/", feeds)
+
+	mux.Handle("/v1/tenant/validation", handlers.TenantValidationHandler{
+		Store: store,
+	})
+
+	hooks := handlers.WebhooksHandler{
+		Store:             store,
+		AllowInsecureURLs: cfg.WebhooksAllowInsecure,
+	}
+	mux.Handle("/v1/webhooks", hooks)
+	mux.Handle("/v1/webhooks/", hooks)
+
 	// Wrap handler chain (order matters!)
 	var root http.Handler = mux
 
@@ -11,6 +11,7 @@ import (
 	"github.com/ETAnderson/conductor/internal/execute"
 	"github.com/ETAnderson/conductor/internal/logging"
 	"github.com/ETAnderson/conductor/internal/state"
+	"github.com/ETAnderson/conductor/internal/webhooks"
 	"github.com/ETAnderson/conductor/internal/worker"
 )
 
@@ -37,7 +38,8 @@ func main() {
 	store := factoryRes.Store
 
 	exec := execute.Executor{
-		Store: store,
+		Store:    store,
+		Channels: []string{"google"},
 		// OnExecute is intentionally nil for now (no external pushes yet).
 		// The executor still validates run ownership and loads enqueued products.
 	}
@@ -47,6 +49,14 @@ func main() {
 		Executor:    exec,
 		PollEvery:   1 * time.Second,
 		MaxPerClaim: 10,
+		Notifier:    webhooks.Notifier{},
+		Logger:      logger,
+	}
+
+	dispatcher := webhooks.Dispatcher{
+		Store:                store,
+		PollEvery:            1 * time.Second,
+		AllowPrivateNetworks: cfg.WebhooksAllowInsecure,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -62,6 +72,14 @@ func main() {
 		}
 	}()
 
+	go func() {
+		err := dispatcher.Run(ctx)
+		if err != nil && err != context.Canceled {
+			logger.Printf("webhook dispatcher stopped: %v", err)
+			os.Exit(1)
+		}
+	}()
+
 	waitForShutdown(logger, cancel)
 }
 
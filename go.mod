@@ -4,6 +4,8 @@ go 1.22
 
 require github.com/go-sql-driver/mysql v1.9.3
 
+require golang.org/x/text v0.21.0
+
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/golang-jwt/jwt/v5 v5.3.0
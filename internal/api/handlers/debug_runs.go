@@ -100,7 +100,16 @@ func (h DebugRunDetailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		limit = 2000
 	}
 
-	products, err := h.Store.ListRunProducts(r.Context(), runID, limit)
+	q := r.URL.Query()
+	filter := state.RunProductFilter{
+		Disposition:      strings.TrimSpace(q.Get("disposition")),
+		Reason:           strings.TrimSpace(q.Get("reason")),
+		IssueCode:        strings.TrimSpace(q.Get("issue_code")),
+		IssuePath:        strings.TrimSpace(q.Get("issue_path")),
+		ProductKeyPrefix: q.Get("product_key_prefix"),
+	}
+
+	products, err := h.Store.ListRunProducts(r.Context(), runID, filter, limit)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"error":   "list_run_products_failed",
@@ -91,3 +91,49 @@ func TestDebugRuns_ListAndDetail(t *testing.T) {
 		t.Fatalf("unexpected products: %#v", detailResp.Products)
 	}
 }
+
+func TestDebugRunDetail_FiltersProducts(t *testing.T) {
+	st := state.NewMemoryStore()
+	tenantID := uint64(1)
+	ctx := context.Background()
+
+	const runID = "run_test_filter"
+	_ = st.InsertRun(ctx, state.RunRecord{
+		RunID:     runID,
+		TenantID:  tenantID,
+		Status:    string(domain.RunStatusCompleted),
+		Received:  2,
+		Valid:     1,
+		Rejected:  1,
+		CreatedAt: time.Now().UTC(),
+	})
+	_ = st.InsertRunProducts(ctx, runID, []ingest.ProductProcessResult{
+		{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued, Reason: "new_product"},
+		{
+			ProductKey:  "sku2",
+			Disposition: domain.ProductDispositionRejected,
+			Reason:      "base_validation_failed",
+			Issues:      []ingest.ValidationIssue{{Path: "price.currency", Code: "invalid_currency"}},
+		},
+	})
+
+	h := DebugRunDetailHandler{Store: st}
+	req := httptest.NewRequest(http.MethodGet, "/v1/debug/runs/"+runID+"?disposition=rejected&issue_code=invalid_currency", nil)
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), tenantID))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Products []ingest.ProductProcessResult `json:"products"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(resp.Products) != 1 || resp.Products[0].ProductKey != "sku2" {
+		t.Fatalf("unexpected products: %#v", resp.Products)
+	}
+}
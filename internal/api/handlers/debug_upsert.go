@@ -10,6 +10,7 @@ import (
 	"github.com/ETAnderson/conductor/internal/domain"
 	"github.com/ETAnderson/conductor/internal/ingest"
 	"github.com/ETAnderson/conductor/internal/state"
+	"github.com/ETAnderson/conductor/internal/webhooks"
 )
 
 type DebugUpsertHandler struct {
@@ -19,6 +20,8 @@ type DebugUpsertHandler struct {
 	TenantID uint64
 
 	EnabledChannels []string
+	// Webhooks builds run.rejected_products, committed with the run.
+	Webhooks webhooks.Notifier
 }
 
 type RunResponse struct {
@@ -72,11 +75,21 @@ func (h DebugUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lookup := func(productKey string) (string, bool, error) {
-		return h.Store.GetProductHash(r.Context(), tenantID, productKey)
+	proc := h.Processor
+	feedID, ok := resolveFeed(w, r, h.Store, tenantID, &proc)
+	if !ok {
+		return
+	}
+
+	policy, ok := duplicatePolicy(w, r, proc.Duplicates)
+	if !ok {
+		return
 	}
+	proc.Duplicates = policy
 
-	out, err := h.Processor.ProcessProducts(parsed.Products, h.EnabledChannels, lookup)
+	lookup := previousStateLookup(r.Context(), h.Store, tenantID)
+
+	out, err := proc.ProcessProducts(parsed.Products, h.EnabledChannels, lookup)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"error":   "processing_failed",
@@ -85,39 +98,14 @@ func (h DebugUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Persist canonical state for relevant dispositions
-	for _, pr := range out.Products {
-		if pr.Hash == "" {
-			continue
-		}
-
-		switch pr.Disposition {
-		case domain.ProductDispositionEnqueued, domain.ProductDispositionUnchanged:
-			if err := h.Store.UpsertProductHash(r.Context(), tenantID, pr.ProductKey, pr.Hash); err != nil {
-				writeJSON(w, http.StatusInternalServerError, map[string]any{
-					"error":   "persist_product_state_failed",
-					"message": err.Error(),
-					"product": pr.ProductKey,
-				})
-				return
-			}
-		}
-	}
-
 	pushTriggered := out.Summary.Enqueued > 0
+	status := ingestRunStatus(out.Summary)
 
-	status := domain.RunStatusCompleted
-	if !pushTriggered && out.Summary.Rejected == 0 {
-		status = domain.RunStatusNoChangeDetected
-	} else if pushTriggered {
-		status = domain.RunStatusHasChanges
-	}
-
-	// Persist run + run_products (do NOT ignore errors)
+	// Persist run, run_products, canonical state and webhook events together (do NOT ignore errors)
 	runRec := state.RunRecord{
 		RunID:         runID,
 		TenantID:      tenantID,
-		FeedID:        nil,
+		FeedID:        feedID,
 		Status:        string(status),
 		PushTriggered: pushTriggered,
 		Received:      out.Summary.Received,
@@ -125,22 +113,24 @@ func (h DebugUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Rejected:      out.Summary.Rejected,
 		Unchanged:     out.Summary.Unchanged,
 		Enqueued:      out.Summary.Enqueued,
+		Duplicates:    out.Summary.Duplicates,
+		Errored:       out.Summary.Errored,
 		Warnings:      parsed.Warnings,
 		CreatedAt:     time.Now().UTC(),
 	}
 
-	if err := h.Store.InsertRun(r.Context(), runRec); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{
-			"error":   "persist_run_failed",
-			"message": err.Error(),
-			"run_id":  runID,
+	events, err := h.Webhooks.RunIngested(runRec, out.Products)
+	if err == nil {
+		err = h.Store.CommitIngestRun(r.Context(), state.IngestRun{
+			Run:      runRec,
+			Products: out.Products,
+			States:   productStateWrites(runID, out.Products),
+			Webhooks: events,
 		})
-		return
 	}
-
-	if err := h.Store.InsertRunProducts(r.Context(), runID, out.Products); err != nil {
+	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
-			"error":   "persist_run_products_failed",
+			"error":   "persist_run_failed",
 			"message": err.Error(),
 			"run_id":  runID,
 		})
@@ -160,3 +150,38 @@ func (h DebugUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(resp)
 }
+
+// duplicatePolicy resolves the batch's duplicate product_key policy: the
+// ?duplicate_policy= query parameter overrides the processor default.
+func duplicatePolicy(w http.ResponseWriter, r *http.Request, def ingest.DuplicatePolicy) (ingest.DuplicatePolicy, bool) {
+	v := r.URL.Query().Get("duplicate_policy")
+	if v == "" {
+		v = string(def)
+	}
+
+	policy, err := ingest.ParseDuplicatePolicy(v)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_duplicate_policy",
+			"message": err.Error(),
+		})
+		return "", false
+	}
+
+	return policy, true
+}
+
+// ingestRunStatus is the status a run is created with. Runs with changes wait for a
+// worker, which decides between completed and partial_success when it finishes.
+func ingestRunStatus(sum ingest.ProcessSummary) domain.RunStatus {
+	switch {
+	case sum.Enqueued > 0:
+		return domain.RunStatusHasChanges
+	case sum.Errored > 0:
+		return domain.RunStatusPartialSuccess
+	case sum.Rejected == 0:
+		return domain.RunStatusNoChangeDetected
+	default:
+		return domain.RunStatusCompleted
+	}
+}
@@ -1,19 +1,22 @@
 package handlers
 
 import (
-	"bufio"
-	"bytes"
 	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/ETAnderson/conductor/internal/api/middleware"
 	"github.com/ETAnderson/conductor/internal/api/tenantctx"
 	"github.com/ETAnderson/conductor/internal/domain"
 	"github.com/ETAnderson/conductor/internal/ingest"
 	"github.com/ETAnderson/conductor/internal/state"
+	"github.com/ETAnderson/conductor/internal/webhooks"
 )
 
 type DebugBulkUpsertHandler struct {
@@ -21,8 +24,24 @@ type DebugBulkUpsertHandler struct {
 	Store     state.Store
 
 	EnabledChannels []string
+	// Webhooks builds run.rejected_products, committed with the run.
+	Webhooks webhooks.Notifier
+
+	// ProductAttempts bounds tries per batched state lookup before the chunk falls
+	// back to per-product lookups, and per run commit (default 3); RetryBackoff is the
+	// first retry delay (default 50ms).
+	ProductAttempts int
+	RetryBackoff    time.Duration
+
+	// ChunkSize is how many lines share one state lookup (default 500).
+	ChunkSize int
+
+	// Workers per parse/validate stage of the ingest pipeline (default GOMAXPROCS).
+	Workers int
 }
 
+const defaultBulkChunkSize = 500
+
 func (h DebugBulkUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	tenantID := tenantctx.TenantID(r.Context())
 
@@ -48,6 +67,22 @@ func (h DebugBulkUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	proc := h.Processor
+	feedID, ok := resolveFeed(w, r, h.Store, tenantID, &proc)
+	if !ok {
+		return
+	}
+
+	policy, ok := duplicatePolicy(w, r, proc.Duplicates)
+	if !ok {
+		return
+	}
+
+	mode, ok := bulkResponseMode(w, r)
+	if !ok {
+		return
+	}
+
 	reader, err := wrapMaybeGzip(r.Body, r.Header.Get("Content-Encoding"))
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{
@@ -58,117 +93,68 @@ func (h DebugBulkUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 	}
 	defer reader.Close()
 
-	unknown := make(map[string]struct{})
+	// Parse, validate and hash the whole batch first: duplicate resolution needs every
+	// product_key before any product is decided.
+	pipe := ingest.BulkPipeline{
+		Processor:       proc,
+		EnabledChannels: h.EnabledChannels,
+		Workers:         h.Workers,
+		DropProducts:    true,
+	}
+	parsed, err := pipe.Run(r.Context(), reader)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "read_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	lines := parsed.Items
 
 	out := ingest.ProcessOutput{
-		Summary:  ingest.ProcessSummary{Received: 0},
-		Products: make([]ingest.ProductProcessResult, 0, 1024),
+		Summary:  ingest.ProcessSummary{Received: len(lines)},
+		Products: make([]ingest.ProductProcessResult, 0, len(lines)),
 	}
 
-	sc := bufio.NewScanner(reader)
-	buf := make([]byte, 0, 64*1024)
-	sc.Buffer(buf, 10*1024*1024)
-
-	lookup := func(productKey string) (string, bool, error) {
-		return h.Store.GetProductHash(r.Context(), tenantID, productKey)
+	keys := make([]string, len(lines))
+	for i, l := range lines {
+		keys[i] = l.ProductKey
 	}
+	dups := ingest.ResolveDuplicates(keys, policy)
 
-	for sc.Scan() {
-		line := bytes.TrimSpace(sc.Bytes())
-		if len(line) == 0 {
-			continue
-		}
-
-		out.Summary.Received++
+	retry := retryPolicy{Attempts: h.ProductAttempts, Backoff: h.RetryBackoff}
 
-		prod, unk, err := ingest.ParseProductObjectAllowUnknown(line)
-		if err != nil {
-			out.Products = append(out.Products, ingest.ProductProcessResult{
-				ProductKey:  "",
-				Disposition: domain.ProductDispositionRejected,
-				Reason:      "invalid_json_line",
-				Issues: []ingest.ValidationIssue{
-					{Path: "$", Code: "invalid_json", Message: err.Error()},
-				},
-			})
-			out.Summary.Rejected++
-			continue
-		}
-
-		for k := range unk {
-			unknown[k] = struct{}{}
-		}
+	chunkSize := h.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultBulkChunkSize
+	}
 
-		res, valid, err := h.Processor.ProcessProduct(prod, h.EnabledChannels, lookup)
-		if err != nil {
+	for start := 0; start < len(lines); start += chunkSize {
+		end := min(start+chunkSize, len(lines))
+		if err := h.processChunk(r.Context(), tenantID, lines[start:end], start, dups, retry, &out); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{
 				"error":   "processing_failed",
 				"message": err.Error(),
 			})
 			return
 		}
-
-		out.Products = append(out.Products, res)
-
-		if !valid {
-			out.Summary.Rejected++
-			continue
-		}
-
-		out.Summary.Valid++
-
-		switch res.Disposition {
-		case domain.ProductDispositionUnchanged:
-			out.Summary.Unchanged++
-			if res.Hash != "" {
-				if err := h.Store.UpsertProductHash(r.Context(), tenantID, res.ProductKey, res.Hash); err != nil {
-					writeJSON(w, http.StatusInternalServerError, map[string]any{
-						"error":   "persist_product_state_failed",
-						"message": err.Error(),
-						"product": res.ProductKey,
-					})
-					return
-				}
-			}
-
-		case domain.ProductDispositionEnqueued:
-			out.Summary.Enqueued++
-			if res.Hash != "" {
-				if err := h.Store.UpsertProductHash(r.Context(), tenantID, res.ProductKey, res.Hash); err != nil {
-					writeJSON(w, http.StatusInternalServerError, map[string]any{
-						"error":   "persist_product_state_failed",
-						"message": err.Error(),
-						"product": res.ProductKey,
-					})
-					return
-				}
-			}
-		}
 	}
+	// Decided items live on in out.Products; the batch can be reclaimed before the commit
+	parsed.Items = nil
 
-	if err := sc.Err(); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{
-			"error":   "read_failed",
-			"message": err.Error(),
-		})
-		return
-	}
-
-	warnings := ingest.UnknownKeyWarning{UnknownKeys: ingest.SortedUnknownKeys(unknown)}
+	warnings := ingest.UnknownKeyWarning{UnknownKeys: ingest.SortedUnknownKeys(parsed.UnknownKeys)}
 
 	pushTriggered := out.Summary.Enqueued > 0
-	status := domain.RunStatusCompleted
-	if !pushTriggered && out.Summary.Rejected == 0 {
-		status = domain.RunStatusNoChangeDetected
-	} else if pushTriggered {
-		status = domain.RunStatusHasChanges
-	}
+	status := ingestRunStatus(out.Summary)
 
-	// Persist run + run_products (do NOT ignore errors)
+	// Nothing has been written yet: the run, its products, the canonical state they
+	// advance and the webhook events they owe are committed together, so a failure (or a client that goes away) leaves
+	// neither a run nor moved hashes behind. The commit is all-or-nothing, which makes
+	// retrying it safe.
 	runRec := state.RunRecord{
 		RunID:         runID,
 		TenantID:      tenantID,
-		FeedID:        nil,
+		FeedID:        feedID,
 		Status:        string(status),
 		PushTriggered: pushTriggered,
 		Received:      out.Summary.Received,
@@ -176,11 +162,14 @@ func (h DebugBulkUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		Rejected:      out.Summary.Rejected,
 		Unchanged:     out.Summary.Unchanged,
 		Enqueued:      out.Summary.Enqueued,
+		Duplicates:    out.Summary.Duplicates,
+		Errored:       out.Summary.Errored,
 		Warnings:      warnings,
 		CreatedAt:     time.Now().UTC(),
 	}
 
-	if err := h.Store.InsertRun(r.Context(), runRec); err != nil {
+	events, err := h.Webhooks.RunIngested(runRec, out.Products)
+	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"error":   "persist_run_failed",
 			"message": err.Error(),
@@ -189,24 +178,300 @@ func (h DebugBulkUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := h.Store.InsertRunProducts(r.Context(), runID, out.Products); err != nil {
+	commit := state.IngestRun{
+		Run:      runRec,
+		Products: out.Products,
+		States:   productStateWrites(runID, out.Products),
+		Webhooks: events,
+	}
+	attempts := 0
+	err = retry.do(r.Context(), func() error {
+		attempts++
+		err := h.Store.CommitIngestRun(r.Context(), commit)
+		if attempts > 1 && errors.Is(err, state.ErrRunExists) {
+			// An earlier attempt committed but its acknowledgement was lost: the run
+			// id is fresh, so finding the run means it is ours.
+			if _, ok, gerr := h.Store.GetRun(r.Context(), tenantID, runID); gerr == nil && ok {
+				return nil
+			}
+		}
+		return err
+	})
+	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
-			"error":   "persist_run_products_failed",
+			"error":   "persist_run_failed",
 			"message": err.Error(),
 			"run_id":  runID,
 		})
 		return
 	}
 
-	resp := RunResponse{
+	summary := RunSummaryResponse{
 		RunID:         runID,
 		Status:        status,
 		PushTriggered: pushTriggered,
 		Warnings:      warnings,
-		Result:        out,
+		Summary:       out.Summary,
+	}
+
+	// Respond only now that the run is committed: a 200 (or any NDJSON line) means
+	// the results are recorded.
+	switch mode {
+	case bulkResponseNDJSON:
+		stream := startBulkStream(w, runID)
+		for i := range out.Products {
+			stream.write(BulkStreamLine{Type: "product", Product: &out.Products[i]})
+		}
+		stream.write(BulkStreamLine{Type: "run", Run: &summary})
+
+	case bulkResponseSummary:
+		w.Header().Set(middleware.RunIDHeader, runID)
+		writeJSON(w, http.StatusOK, summary)
+
+	default:
+		w.Header().Set(middleware.RunIDHeader, runID)
+		writeJSON(w, http.StatusOK, RunResponse{
+			RunID:         runID,
+			Status:        status,
+			PushTriggered: pushTriggered,
+			Warnings:      warnings,
+			Result:        out,
+		})
+	}
+}
+
+// ReplayRun rebuilds the response to an idempotent retry from the stored run: the
+// same shape as the original response for the requested mode, with products in
+// input order and the run's current status.
+func (h DebugBulkUpsertHandler) ReplayRun(w http.ResponseWriter, r *http.Request, run state.RunRecord) {
+	mode, ok := bulkResponseMode(w, r)
+	if !ok {
+		return
+	}
+
+	summary := RunSummaryResponse{
+		RunID:         run.RunID,
+		Status:        domain.RunStatus(run.Status),
+		PushTriggered: run.PushTriggered,
+		Warnings:      run.Warnings,
+		Summary:       run.Summary(),
+	}
+
+	switch mode {
+	case bulkResponseSummary:
+		w.Header().Set(middleware.RunIDHeader, run.RunID)
+		writeJSON(w, http.StatusOK, summary)
+
+	case bulkResponseNDJSON:
+		stream := startBulkStream(w, run.RunID)
+		err := h.Store.EachRunProduct(r.Context(), run.RunID, func(p ingest.ProductProcessResult) error {
+			stream.write(BulkStreamLine{Type: "product", Product: &p})
+			return nil
+		})
+		if err != nil {
+			stream.write(BulkStreamLine{Type: "error", Error: map[string]any{
+				"error":   "replay_failed",
+				"message": err.Error(),
+				"run_id":  run.RunID,
+			}})
+			return
+		}
+		stream.write(BulkStreamLine{Type: "run", Run: &summary})
+
+	default:
+		products := make([]ingest.ProductProcessResult, 0, run.Received)
+		err := h.Store.EachRunProduct(r.Context(), run.RunID, func(p ingest.ProductProcessResult) error {
+			products = append(products, p)
+			return nil
+		})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"error":   "replay_failed",
+				"message": err.Error(),
+				"run_id":  run.RunID,
+			})
+			return
+		}
+
+		w.Header().Set(middleware.RunIDHeader, run.RunID)
+		writeJSON(w, http.StatusOK, RunResponse{
+			RunID:         run.RunID,
+			Status:        summary.Status,
+			PushTriggered: run.PushTriggered,
+			Warnings:      run.Warnings,
+			Result:        ingest.ProcessOutput{Summary: summary.Summary, Products: products},
+		})
+	}
+}
+
+// Bulk response modes, chosen with ?response= (NDJSON also via Accept: application/x-ndjson).
+// Full returns every product result in one document; summary returns counts only
+// (results stay available from the run); ndjson streams one line per result once the
+// run is committed.
+const (
+	bulkResponseFull    = "full"
+	bulkResponseSummary = "summary"
+	bulkResponseNDJSON  = "ndjson"
+)
+
+func bulkResponseMode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	mode := r.URL.Query().Get("response")
+	if mode == "" {
+		mode = bulkResponseFull
+		if strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+			mode = bulkResponseNDJSON
+		}
+	}
+
+	switch mode {
+	case bulkResponseFull, bulkResponseSummary, bulkResponseNDJSON:
+		return mode, true
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_response_mode",
+			"message": fmt.Sprintf("unknown response mode %q (want full, summary or ndjson)", mode),
+		})
+		return "", false
+	}
+}
+
+// RunSummaryResponse is RunResponse without per-product results.
+type RunSummaryResponse struct {
+	RunID         string                   `json:"run_id"`
+	Status        domain.RunStatus         `json:"status"`
+	PushTriggered bool                     `json:"push_triggered"`
+	Warnings      ingest.UnknownKeyWarning `json:"warnings,omitempty"`
+	Summary       ingest.ProcessSummary    `json:"summary"`
+}
+
+// BulkStreamLine is one line of an NDJSON bulk response: "product" lines in input
+// order, then a closing "run" line. The stream starts only after the run is
+// committed; failures before that are ordinary JSON error responses. An "error" line
+// (with the error body the other modes would return) ends a replayed stream whose
+// run products could not be read; a stream without the closing "run" line is incomplete.
+type BulkStreamLine struct {
+	Type    string                       `json:"type"`
+	Product *ingest.ProductProcessResult `json:"product,omitempty"`
+	Run     *RunSummaryResponse          `json:"run,omitempty"`
+	Error   map[string]any               `json:"error,omitempty"`
+}
+
+type bulkStream struct {
+	w   http.ResponseWriter
+	enc *json.Encoder
+}
+
+// startBulkStream commits the response as a 200 NDJSON stream for runID.
+func startBulkStream(w http.ResponseWriter, runID string) *bulkStream {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set(middleware.RunIDHeader, runID)
+	w.WriteHeader(http.StatusOK)
+	return &bulkStream{w: w, enc: json.NewEncoder(w)}
+}
+
+func (s *bulkStream) write(line BulkStreamLine) {
+	_ = s.enc.Encode(line)
+}
+
+// processChunk decides one window of the batch: a single state lookup, then
+// per-product decisions. Nothing is written here; state is committed with the run.
+// Lookup errors only cost the products they hit: when the batched lookup keeps
+// failing the chunk falls back to per-product lookups, and products that still fail
+// are recorded as errored. offset is the chunk's position in the batch (for dups).
+// Only returns an error once the request context has ended.
+func (h DebugBulkUpsertHandler) processChunk(ctx context.Context, tenantID uint64, chunk []ingest.BulkItem, offset int, dups ingest.DuplicateResolution, retry retryPolicy, out *ingest.ProcessOutput) error {
+	keys := make([]string, 0, len(chunk))
+	for i, l := range chunk {
+		if l.ParseErr == nil && l.Valid && dups.Keep(offset+i) {
+			keys = append(keys, l.ProductKey)
+		}
+	}
+
+	var states map[string]state.ProductStateRecord
+	err := retry.do(ctx, func() error {
+		var err error
+		states, err = h.Store.GetProductStates(ctx, tenantID, keys)
+		return err
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// The batched call already spent the retries: fallbacks try each product once so
+	// an outage fails fast instead of multiplying the backoff by the chunk size.
+	lookup := statesLookup(states)
+	if err != nil {
+		lookup = previousStateLookup(ctx, h.Store, tenantID)
+	}
+
+	results := make([]ingest.ProductProcessResult, 0, len(chunk))
+	add := func(i int, res ingest.ProductProcessResult) {
+		if res.ProductKey == "" {
+			res.ProductKey = ingest.KeylessProductKey(offset + i)
+		}
+		results = append(results, res)
+	}
+
+	for i, l := range chunk {
+		if l.ParseErr != nil {
+			add(i, ingest.ProductProcessResult{
+				Disposition: domain.ProductDispositionRejected,
+				Reason:      "invalid_json_line",
+				Issues: []ingest.ValidationIssue{
+					{Path: "$", Code: "invalid_json", Message: l.ParseErr.Error()},
+				},
+			})
+			out.Summary.Rejected++
+			continue
+		}
+
+		if !dups.Keep(offset + i) {
+			out.Summary.Duplicates++
+			if res, ok := dups.DuplicateResult(offset + i); ok {
+				add(i, res)
+			}
+			continue
+		}
+
+		if l.PrepareErr != nil {
+			add(i, ingest.ErrorResult(l.ProductKey, "processing_failed", l.PrepareErr))
+			out.Summary.Errored++
+			continue
+		}
+
+		res := l.Prepared
+		if l.Valid {
+			prev, _, err := lookup(l.ProductKey)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				add(i, ingest.ErrorResult(l.ProductKey, "processing_failed", err))
+				out.Summary.Errored++
+				continue
+			}
+			res = h.Processor.Decide(res, prev)
+		}
+		dups.Annotate(offset+i, &res)
+
+		add(i, res)
+
+		if !l.Valid {
+			out.Summary.Rejected++
+			continue
+		}
+
+		switch res.Disposition {
+		case domain.ProductDispositionUnchanged:
+			out.Summary.Unchanged++
+		case domain.ProductDispositionEnqueued:
+			out.Summary.Enqueued++
+		}
+		out.Summary.Valid++
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	out.Products = append(out.Products, results...)
+	return nil
 }
 
 func wrapMaybeGzip(body io.ReadCloser, contentEncoding string) (io.ReadCloser, error) {
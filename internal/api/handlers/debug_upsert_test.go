@@ -2,14 +2,22 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/ETAnderson/conductor/internal/api/middleware"
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
 	"github.com/ETAnderson/conductor/internal/domain"
 	"github.com/ETAnderson/conductor/internal/ingest"
 	"github.com/ETAnderson/conductor/internal/state"
+	"github.com/go-sql-driver/mysql"
 )
 
 func TestDebugUpsert_StateWired_FirstEnqueuedSecondUnchanged(t *testing.T) {
@@ -73,3 +81,701 @@ func TestDebugUpsert_StateWired_FirstEnqueuedSecondUnchanged(t *testing.T) {
 		t.Fatalf("expected unchanged=1, got %#v", resp2.Result.Summary)
 	}
 }
+
+func TestDebugBulkUpsert_DuplicateProductKeys(t *testing.T) {
+	store := state.NewMemoryStore()
+
+	h := DebugBulkUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google"},
+	}
+
+	line := testProductLine
+	body := line("sku1", "First") + "\n" + line("sku2", "Other") + "\n" + line("sku1", "Last") + "\n"
+
+	post := func(query string) (*httptest.ResponseRecorder, RunResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk"+query, bytes.NewBufferString(body))
+		req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		var resp RunResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	rec, _ := post("?duplicate_policy=bogus")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown policy, got %d", rec.Code)
+	}
+
+	rec, resp := post("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp.Result.Summary.Duplicates != 2 || resp.Result.Summary.Enqueued != 1 || len(resp.Result.Products) != 2 {
+		t.Fatalf("unexpected reject_all result: %+v", resp.Result)
+	}
+
+	rec, resp = post("?duplicate_policy=last_wins")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp.Result.Summary.Duplicates != 1 || resp.Result.Summary.Enqueued != 1 {
+		t.Fatalf("unexpected last_wins summary: %+v", resp.Result.Summary)
+	}
+
+	ps, ok, _ := store.GetProductState(context.Background(), 1, "sku1")
+	if !ok || ps.Product == nil || ps.Product.Title != "Last" {
+		t.Fatalf("expected last occurrence persisted, got ok=%v %+v", ok, ps.Product)
+	}
+
+	run, _, _ := store.GetRun(context.Background(), 1, resp.RunID)
+	if run.Duplicates != 1 {
+		t.Fatalf("expected run duplicates=1, got %d", run.Duplicates)
+	}
+}
+
+// Like run_products in MySQL, the memory store keeps one row per product_key in a run,
+// so every result of a batch needs a distinct key.
+func TestDebugBulkUpsert_KeylessAndCaseVariantKeysPersist(t *testing.T) {
+	store := state.NewMemoryStore()
+
+	h := DebugBulkUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google"},
+	}
+
+	body := "{not json\n" +
+		testProductLine("SKU1", "Upper") + "\n" +
+		"[1,2]\n" +
+		testProductLine("", "No key") + "\n" +
+		testProductLine("sku1", "Lower") + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk", bytes.NewBufferString(body))
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp RunResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sum := resp.Result.Summary; sum.Rejected != 3 || sum.Enqueued != 2 || sum.Duplicates != 0 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+
+	products, err := store.ListRunProducts(context.Background(), resp.RunID, state.RunProductFilter{}, 10)
+	if err != nil {
+		t.Fatalf("ListRunProducts: %v", err)
+	}
+	var keys []string
+	for _, p := range products {
+		keys = append(keys, p.ProductKey)
+	}
+	want := []string{"$[0]", "$[2]", "$[3]", "SKU1", "sku1"}
+	if fmt.Sprint(keys) != fmt.Sprint(want) {
+		t.Fatalf("expected run product keys %v, got %v", want, keys)
+	}
+}
+
+func testProductLine(key, title string) string {
+	return `{"product_key":"` + key + `","title":"` + title + `","description":"Desc","link":"https://example.com/p/` + key +
+		`","image_link":"https://example.com/p/` + key + `.jpg","condition":"new","availability":"in_stock",` +
+		`"price":{"amount_decimal":"19.99","currency":"USD"},"channel":{"google":{"control":{"state":"active"}}}}`
+}
+
+// flakyStore fails lookups for brokenKey every time, the first run commit once when
+// blip is set, and every run commit when commitErr is set. With lostAck the first
+// commit goes through but reports a dropped connection. commits counts commit calls.
+type flakyStore struct {
+	state.Store
+	brokenKey string
+	blip      bool
+	blipped   bool
+	lostAck   bool
+	commitErr error
+	commits   int
+}
+
+func (s *flakyStore) GetProductState(ctx context.Context, tenantID uint64, productKey string) (state.ProductStateRecord, bool, error) {
+	if productKey == s.brokenKey {
+		return state.ProductStateRecord{}, false, driver.ErrBadConn
+	}
+	return s.Store.GetProductState(ctx, tenantID, productKey)
+}
+
+func (s *flakyStore) GetProductStates(ctx context.Context, tenantID uint64, productKeys []string) (map[string]state.ProductStateRecord, error) {
+	for _, k := range productKeys {
+		if k == s.brokenKey {
+			return nil, driver.ErrBadConn
+		}
+	}
+	return s.Store.GetProductStates(ctx, tenantID, productKeys)
+}
+
+func (s *flakyStore) CommitIngestRun(ctx context.Context, in state.IngestRun) error {
+	s.commits++
+	if s.commitErr != nil {
+		return s.commitErr
+	}
+	if s.blip && !s.blipped {
+		s.blipped = true
+		return &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	}
+	if err := s.Store.CommitIngestRun(ctx, in); err != nil {
+		return err
+	}
+	if s.lostAck && s.commits == 1 {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func TestDebugBulkUpsert_ProductErrorsArePerProduct(t *testing.T) {
+	store := &flakyStore{Store: state.NewMemoryStore(), brokenKey: "sku2", blip: true}
+
+	h := DebugBulkUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google"},
+		RetryBackoff:    time.Millisecond,
+	}
+
+	body := testProductLine("sku1", "A") + "\n" + testProductLine("sku2", "B") + "\n" + testProductLine("sku3", "C") + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk", bytes.NewBufferString(body))
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp RunResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	sum := resp.Result.Summary
+	if sum.Errored != 1 || sum.Enqueued != 2 || sum.Valid != 2 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+	if resp.Status != domain.RunStatusHasChanges {
+		t.Fatalf("expected has_changes, got %s", resp.Status)
+	}
+
+	bad := resp.Result.Products[1]
+	if bad.ProductKey != "sku2" || bad.Disposition != domain.ProductDispositionError || bad.Issues[0].Code != ingest.IssueCodeInternalError {
+		t.Fatalf("unexpected error result: %+v", bad)
+	}
+
+	if _, ok, _ := store.Store.GetProductState(context.Background(), 1, "sku3"); !ok {
+		t.Fatalf("expected transient commit failure to be retried")
+	}
+
+	run, _, _ := store.GetRun(context.Background(), 1, resp.RunID)
+	if run.Errored != 1 {
+		t.Fatalf("expected run errored=1, got %d", run.Errored)
+	}
+}
+
+func TestDebugBulkUpsert_FailedCommitKeepsNoState(t *testing.T) {
+	store := &flakyStore{Store: state.NewMemoryStore(), commitErr: driver.ErrBadConn}
+
+	h := DebugBulkUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google"},
+		RetryBackoff:    time.Millisecond,
+	}
+
+	body := testProductLine("sku1", "A") + "\n"
+	// NDJSON included: nothing is streamed before the run is committed
+	for _, query := range []string{"", "?response=ndjson"} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk"+query, bytes.NewBufferString(body))
+		req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("%q: expected 500, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		if bytes.Contains(rec.Body.Bytes(), []byte(`"type":"product"`)) {
+			t.Fatalf("%q: expected no product lines, got %s", query, rec.Body.String())
+		}
+	}
+
+	// Without a run to push it, the change must still be pending on the next ingest.
+	if _, ok, _ := store.GetProductState(context.Background(), 1, "sku1"); ok {
+		t.Fatalf("expected no product state without a recorded run")
+	}
+	if runs, _ := store.ListRuns(context.Background(), 1, 0); len(runs) != 0 {
+		t.Fatalf("expected no run, got %+v", runs)
+	}
+}
+
+func TestDebugBulkUpsert_CommitRetries(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		store       *flakyStore
+		wantCode    int
+		wantCommits int
+		wantRuns    int
+	}{
+		// The retry hits the run the lost attempt committed and reads it back
+		{name: "lost ack", store: &flakyStore{lostAck: true}, wantCode: http.StatusOK, wantCommits: 2, wantRuns: 1},
+		{name: "permanent error", store: &flakyStore{commitErr: errors.New("Data too long for column")}, wantCode: http.StatusInternalServerError, wantCommits: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.store
+			store.Store = state.NewMemoryStore()
+
+			h := DebugBulkUpsertHandler{
+				Processor:       ingest.NewProcessor(),
+				Store:           store,
+				EnabledChannels: []string{"google"},
+				RetryBackoff:    time.Millisecond,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk", bytes.NewBufferString(testProductLine("sku1", "A")+"\n"))
+			req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rec.Code, rec.Body.String())
+			}
+			if store.commits != tc.wantCommits {
+				t.Fatalf("expected %d commit attempts, got %d", tc.wantCommits, store.commits)
+			}
+			if runs, _ := store.ListRuns(context.Background(), 1, 0); len(runs) != tc.wantRuns {
+				t.Fatalf("expected %d runs, got %+v", tc.wantRuns, runs)
+			}
+		})
+	}
+}
+
+// countingStore counts state round-trips made by the bulk handler.
+type countingStore struct {
+	state.Store
+	lookups, writes int
+}
+
+func (s *countingStore) GetProductState(ctx context.Context, tenantID uint64, productKey string) (state.ProductStateRecord, bool, error) {
+	s.lookups++
+	return s.Store.GetProductState(ctx, tenantID, productKey)
+}
+
+func (s *countingStore) GetProductStates(ctx context.Context, tenantID uint64, productKeys []string) (map[string]state.ProductStateRecord, error) {
+	s.lookups++
+	return s.Store.GetProductStates(ctx, tenantID, productKeys)
+}
+
+func (s *countingStore) CommitIngestRun(ctx context.Context, in state.IngestRun) error {
+	s.writes++
+	return s.Store.CommitIngestRun(ctx, in)
+}
+
+func TestDebugBulkUpsert_BatchesStateCallsPerChunk(t *testing.T) {
+	store := &countingStore{Store: state.NewMemoryStore()}
+
+	h := DebugBulkUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google"},
+		ChunkSize:       4,
+	}
+
+	var body bytes.Buffer
+	for i := 0; i < 10; i++ {
+		body.WriteString(testProductLine(fmt.Sprintf("sku%d", i), "T") + "\n")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk", &body)
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if store.lookups != 3 || store.writes != 1 {
+		t.Fatalf("expected one lookup per chunk and a single commit, got lookups=%d writes=%d", store.lookups, store.writes)
+	}
+
+	versions, _ := store.ListProductVersions(context.Background(), 1, "sku9", 0)
+	if len(versions) != 1 || versions[0].Version != 1 {
+		t.Fatalf("expected sku9 at version 1, got %+v", versions)
+	}
+}
+
+func TestDebugBulkUpsert_ResponseModes(t *testing.T) {
+	store := state.NewMemoryStore()
+
+	h := DebugBulkUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google"},
+		ChunkSize:       2,
+	}
+
+	body := testProductLine("sku1", "A") + "\n" + "{bad json\n" + testProductLine("sku2", "B") + "\n"
+	post := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk"+query, bytes.NewBufferString(body))
+		req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("?response=everything"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown mode, got %d", rec.Code)
+	}
+
+	rec := post("?response=summary")
+	var summary map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &summary)
+	if rec.Code != http.StatusOK || summary["summary"] == nil || summary["result"] != nil {
+		t.Fatalf("unexpected summary response %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get(middleware.RunIDHeader) != summary["run_id"] {
+		t.Fatalf("expected run id header, got %q", rec.Header().Get(middleware.RunIDHeader))
+	}
+
+	rec = post("?response=ndjson")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected ndjson content type, got %q", ct)
+	}
+
+	var lines []BulkStreamLine
+	dec := json.NewDecoder(rec.Body)
+	for dec.More() {
+		var l BulkStreamLine
+		if err := dec.Decode(&l); err != nil {
+			t.Fatalf("decode line: %v", err)
+		}
+		lines = append(lines, l)
+	}
+
+	if len(lines) != 4 {
+		t.Fatalf("expected 3 product lines and a run line, got %+v", lines)
+	}
+	if lines[0].Product.ProductKey != "sku1" || lines[1].Product.Reason != "invalid_json_line" || lines[2].Product.ProductKey != "sku2" {
+		t.Fatalf("expected products in input order, got %+v", lines[:3])
+	}
+	last := lines[3]
+	if last.Type != "run" || last.Run == nil || last.Run.Summary.Received != 3 || last.Run.Summary.Rejected != 1 {
+		t.Fatalf("unexpected closing line: %+v", last)
+	}
+}
+
+func TestDebugUpsert_UpgradesUnversionedStoredHash(t *testing.T) {
+	store := state.NewMemoryStore()
+	ctx := context.Background()
+
+	prod := domain.Product{
+		ProductKey:   "sku1",
+		Title:        "Test",
+		Description:  "Desc",
+		Link:         "https://example.com/p/sku1",
+		ImageLink:    "https://example.com/p/sku1.jpg",
+		Condition:    "new",
+		Availability: "in_stock",
+		Price:        domain.Money{AmountDecimal: "19.99", Currency: "USD"},
+		Channel: domain.ChannelFields{
+			Google: &domain.GoogleFields{Control: domain.ChannelControl{State: domain.ChannelStateActive}},
+		},
+	}
+	v1, err := ingest.Hasher{Version: ingest.HashVersionV1}.HashNormalized(prod)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	current, err := ingest.Hasher{}.HashNormalized(prod)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	// State written before hash versioning has no version.
+	if err := store.UpsertProductState(ctx, 1, state.ProductStateRecord{ProductKey: "sku1", Hash: v1, Product: &prod}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	h := DebugUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		TenantID:        1,
+		EnabledChannels: []string{"google"},
+	}
+
+	body, _ := json.Marshal([]domain.Product{prod})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert", bytes.NewReader(body)))
+
+	var resp RunResponse
+	_ = json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.PushTriggered || resp.Result.Summary.Unchanged != 1 {
+		t.Fatalf("expected unchanged without push, got push=%v summary=%+v", resp.PushTriggered, resp.Result.Summary)
+	}
+
+	got, ok, err := store.GetProductState(ctx, 1, "sku1")
+	if err != nil || !ok {
+		t.Fatalf("get state: ok=%v err=%v", ok, err)
+	}
+	if got.Hash != current || got.HashVersion != ingest.CurrentHashVersion {
+		t.Fatalf("expected hash stored as version %d, got %+v", ingest.CurrentHashVersion, got)
+	}
+}
+
+func TestDebugUpsert_AppliesFeedValidationPolicy(t *testing.T) {
+	store := state.NewMemoryStore()
+	ctx := tenantctx.WithTenantID(context.Background(), 1)
+
+	strict, err := store.CreateFeed(ctx, state.Feed{TenantID: 1, Name: "strict"})
+	if err != nil {
+		t.Fatalf("create feed: %v", err)
+	}
+	lenient, err := store.CreateFeed(ctx, state.Feed{
+		TenantID:   1,
+		Name:       "lenient",
+		Validation: &ingest.ValidationPolicy{AllowZeroPrice: true},
+	})
+	if err != nil {
+		t.Fatalf("create feed: %v", err)
+	}
+	other, err := store.CreateFeed(ctx, state.Feed{TenantID: 2, Name: "other tenant"})
+	if err != nil {
+		t.Fatalf("create feed: %v", err)
+	}
+
+	h := DebugUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google"},
+	}
+
+	body := `[{
+  "product_key": "free-sample",
+  "title": "Free sample",
+  "description": "Desc",
+  "link": "https://example.com/p/free-sample",
+  "image_link": "https://example.com/p/free-sample.jpg",
+  "condition": "new",
+  "availability": "in_stock",
+  "price": { "amount_decimal": "0.00", "currency": "USD" },
+  "channel": { "google": { "control": { "state": "active" } } }
+}]`
+
+	ingestFeed := func(feedID uint64) *httptest.ResponseRecorder {
+		url := fmt.Sprintf("/v1/debug/products:upsert?feed_id=%d", feedID)
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	cases := []struct {
+		feed     state.Feed
+		rejected int
+		enqueued int
+	}{
+		{feed: strict, rejected: 1, enqueued: 0},
+		{feed: lenient, rejected: 0, enqueued: 1},
+	}
+	for _, tc := range cases {
+		rec := ingestFeed(tc.feed.ID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("feed %q: expected 200, got %d: %s", tc.feed.Name, rec.Code, rec.Body.String())
+		}
+
+		var resp RunResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		sum := resp.Result.Summary
+		if sum.Rejected != tc.rejected || sum.Enqueued != tc.enqueued {
+			t.Fatalf("feed %q: expected rejected=%d enqueued=%d, got %#v", tc.feed.Name, tc.rejected, tc.enqueued, sum)
+		}
+
+		run, ok, err := store.GetRun(ctx, 1, resp.RunID)
+		if err != nil || !ok {
+			t.Fatalf("get run: ok=%v err=%v", ok, err)
+		}
+		if run.FeedID == nil || *run.FeedID != tc.feed.ID {
+			t.Fatalf("feed %q: expected run feed %d, got %v", tc.feed.Name, tc.feed.ID, run.FeedID)
+		}
+	}
+
+	if rec := ingestFeed(other.ID); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another tenant's feed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDebugUpsert_PushesUnchangedProductToNewlyAcceptingChannel(t *testing.T) {
+	store := state.NewMemoryStore()
+	ctx := tenantctx.WithTenantID(context.Background(), 1)
+
+	feed, err := store.CreateFeed(ctx, state.Feed{TenantID: 1, Name: "catalog"})
+	if err != nil {
+		t.Fatalf("create feed: %v", err)
+	}
+
+	h := DebugUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google", "meta"},
+	}
+
+	// No brand: meta rejects it under its default profile, google accepts it
+	body := `[{
+  "product_key": "sku1",
+  "title": "Unbranded",
+  "description": "Desc",
+  "link": "https://example.com/p/sku1",
+  "image_link": "https://example.com/p/sku1.jpg",
+  "condition": "new",
+  "availability": "in_stock",
+  "price": { "amount_decimal": "9.99", "currency": "USD" },
+  "channel": { "google": { "control": { "state": "active" } }, "meta": { "control": { "state": "active" } } }
+}]`
+
+	ingestOnce := func() ingest.ProductProcessResult {
+		url := fmt.Sprintf("/v1/debug/products:upsert?feed_id=%d", feed.ID)
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp RunResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Result.Products[0]
+	}
+
+	if got := ingestOnce(); got.Disposition != domain.ProductDispositionEnqueued || got.PushableTo("meta") {
+		t.Fatalf("expected enqueued for google only, got %+v", got)
+	}
+	if got := ingestOnce(); got.Disposition != domain.ProductDispositionUnchanged {
+		t.Fatalf("expected unchanged on re-ingest, got %+v", got)
+	}
+
+	if err := store.UpdateFeedValidation(ctx, 1, feed.ID, &ingest.ValidationPolicy{
+		Profiles: map[string]ingest.ChannelProfile{"meta": {}},
+	}); err != nil {
+		t.Fatalf("update feed validation: %v", err)
+	}
+
+	got := ingestOnce()
+	if got.Disposition != domain.ProductDispositionEnqueued || got.Reason != "channels_changed" || !got.PushableTo("meta") {
+		t.Fatalf("expected the same document enqueued for meta, got %+v", got)
+	}
+	if got := ingestOnce(); got.Disposition != domain.ProductDispositionUnchanged {
+		t.Fatalf("expected unchanged once meta has it, got %+v", got)
+	}
+}
+
+func TestDebugBulkUpsert_AppliesTenantLinkDomains(t *testing.T) {
+	store := state.NewMemoryStore()
+	ctx := context.Background()
+
+	if err := store.UpdateTenantValidation(ctx, 1, &ingest.ValidationPolicy{LinkDomains: []string{"example.com"}}); err != nil {
+		t.Fatalf("tenant 1 policy: %v", err)
+	}
+	if err := store.UpdateTenantValidation(ctx, 2, &ingest.ValidationPolicy{LinkDomains: []string{"shop.test"}}); err != nil {
+		t.Fatalf("tenant 2 policy: %v", err)
+	}
+	// A feed's own policy takes precedence over its tenant's
+	mirror, err := store.CreateFeed(ctx, state.Feed{
+		TenantID:   2,
+		Name:       "mirror",
+		Validation: &ingest.ValidationPolicy{LinkDomains: []string{"example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("create feed: %v", err)
+	}
+
+	h := DebugBulkUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           store,
+		EnabledChannels: []string{"google"},
+	}
+
+	// Links point at example.com
+	body := testProductLine("sku1", "A") + "\n"
+	post := func(tenantID uint64, query string) RunSummaryResponse {
+		req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk?response=summary"+query, bytes.NewBufferString(body))
+		req = req.WithContext(tenantctx.WithTenantID(req.Context(), tenantID))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("tenant %d: expected 200, got %d: %s", tenantID, rec.Code, rec.Body.String())
+		}
+
+		var resp RunSummaryResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	if sum := post(1, "").Summary; sum.Valid != 1 || sum.Rejected != 0 {
+		t.Fatalf("tenant 1: expected the example.com link accepted, got %#v", sum)
+	}
+	if sum := post(2, "").Summary; sum.Valid != 0 || sum.Rejected != 1 {
+		t.Fatalf("tenant 2: expected the example.com link rejected, got %#v", sum)
+	}
+	if sum := post(2, fmt.Sprintf("&feed_id=%d", mirror.ID)).Summary; sum.Valid != 1 || sum.Rejected != 0 {
+		t.Fatalf("tenant 2 feed: expected its own policy to accept, got %#v", sum)
+	}
+}
+
+func TestDebugBulkUpsert_IdempotentReplayMatchesFirstResponse(t *testing.T) {
+	st := state.NewMemoryStore()
+	h := middleware.IdempotencyMiddleware{
+		Store: st,
+		Next: DebugBulkUpsertHandler{
+			Processor:       ingest.NewProcessor(),
+			Store:           st,
+			EnabledChannels: []string{"google"},
+			ChunkSize:       2,
+		},
+	}
+
+	// Input order differs from key order; includes a parse error and a rejected product
+	body := testProductLine("sku3", "C") + "\n{bad json\n" + testProductLine("sku1", "") + "\n" + testProductLine("sku2", "B") + "\n"
+
+	for _, mode := range []string{"full", "summary", "ndjson"} {
+		t.Run(mode, func(t *testing.T) {
+			post := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk?response="+mode, bytes.NewBufferString(body))
+				req.Header.Set(middleware.IdempotencyHeaderKey, "replay-"+mode)
+				req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				return rec
+			}
+
+			first := post()
+			replayed := post()
+
+			if first.Code != http.StatusOK || replayed.Code != http.StatusOK {
+				t.Fatalf("expected 200s, got %d then %d: %s", first.Code, replayed.Code, replayed.Body.String())
+			}
+			if replayed.Header().Get(middleware.IdempotentReplayHeader) != "true" {
+				t.Fatalf("expected the second response replayed")
+			}
+			if ct := replayed.Header().Get("Content-Type"); ct != first.Header().Get("Content-Type") {
+				t.Fatalf("expected content type %q, got %q", first.Header().Get("Content-Type"), ct)
+			}
+			if !bytes.Equal(first.Body.Bytes(), replayed.Body.Bytes()) {
+				t.Fatalf("replay differs from the first response:\nfirst:  %s\nreplay: %s", first.Body.String(), replayed.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/ingest"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+// FeedsHandler manages a tenant's feeds and the validation policy stored with each.
+//
+//	GET /v1/feeds
+//	POST /v1/feeds
+//	GET /v1/feeds/{feed_id}
+//	PUT /v1/feeds/{feed_id}/validation
+type FeedsHandler struct {
+	Store state.Store
+}
+
+type createFeedRequest struct {
+	Name       string                   `json:"name"`
+	Validation *ingest.ValidationPolicy `json:"validation,omitempty"`
+}
+
+func (h FeedsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "misconfigured",
+			"message": "handler dependencies not configured",
+		})
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/feeds"), "/")
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.serveList(w, r)
+		case http.MethodPost:
+			h.serveCreate(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	idPart, action, _ := strings.Cut(rest, "/")
+	feedID, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil || feedID == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_feed_id",
+			"message": "feed_id must be a positive integer",
+		})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.serveGet(w, r, feedID)
+	case action == "validation" && r.Method == http.MethodPut:
+		h.serveUpdateValidation(w, r, feedID)
+	case action == "" || action == "validation":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "unknown feed endpoint",
+		})
+	}
+}
+
+func (h FeedsHandler) serveList(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	items, err := h.Store.ListFeeds(r.Context(), tenantID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "list_feeds_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if items == nil {
+		items = []state.Feed{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+	})
+}
+
+func (h FeedsHandler) serveCreate(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	var req createFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_json",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_name",
+			"message": "name is required",
+		})
+		return
+	}
+
+	f, err := h.Store.CreateFeed(r.Context(), state.Feed{
+		TenantID:   tenantID,
+		Name:       req.Name,
+		Validation: req.Validation,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "create_feed_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, f)
+}
+
+func (h FeedsHandler) serveGet(w http.ResponseWriter, r *http.Request, feedID uint64) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	f, ok, err := h.Store.GetFeed(r.Context(), tenantID, feedID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "get_feed_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "feed not found",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, f)
+}
+
+// serveUpdateValidation replaces the feed's policy; a JSON null restores the default.
+func (h FeedsHandler) serveUpdateValidation(w http.ResponseWriter, r *http.Request, feedID uint64) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	var policy *ingest.ValidationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_json",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	err := h.Store.UpdateFeedValidation(r.Context(), tenantID, feedID, policy)
+	if errors.Is(err, state.ErrFeedNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "feed not found",
+		})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "update_feed_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.serveGet(w, r, feedID)
+}
+
+// resolveFeed reads the optional ?feed_id= of an ingest request and applies the
+// validation policy the request is under to proc: the feed's own policy, else the
+// tenant's, else proc's default. It writes the error response itself and returns
+// false when the feed id is malformed or not one of the tenant's feeds.
+func resolveFeed(w http.ResponseWriter, r *http.Request, store state.Store, tenantID uint64, proc *ingest.Processor) (*uint64, bool) {
+	var feed *state.Feed
+	if v := r.URL.Query().Get("feed_id"); v != "" {
+		feedID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || feedID == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"error":   "invalid_feed_id",
+				"message": "feed_id must be a positive integer",
+			})
+			return nil, false
+		}
+
+		f, ok, err := store.GetFeed(r.Context(), tenantID, feedID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"error":   "get_feed_failed",
+				"message": err.Error(),
+			})
+			return nil, false
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]any{
+				"error":   "feed_not_found",
+				"message": "feed not found",
+			})
+			return nil, false
+		}
+		feed = &f
+	}
+
+	var policy *ingest.ValidationPolicy
+	if feed != nil {
+		policy = feed.Validation
+	}
+	if policy == nil {
+		p, err := store.GetTenantValidation(r.Context(), tenantID)
+		if err != nil && !errors.Is(err, state.ErrTenantNotFound) {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"error":   "get_validation_failed",
+				"message": err.Error(),
+			})
+			return nil, false
+		}
+		policy = p
+	}
+	if policy != nil {
+		proc.Validation = *policy
+	}
+
+	if feed == nil {
+		return nil, true
+	}
+	return &feed.ID, true
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/ingest"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+// previousStateLookup reads canonical state for delta detection.
+func previousStateLookup(ctx context.Context, store state.Store, tenantID uint64) ingest.PreviousStateLookup {
+	return func(productKey string) (ingest.PreviousState, bool, error) {
+		rec, ok, err := store.GetProductState(ctx, tenantID, productKey)
+		if err != nil || !ok {
+			return ingest.PreviousState{}, false, err
+		}
+		return previousState(rec), true, nil
+	}
+}
+
+// statesLookup serves lookups from states prefetched with GetProductStates.
+func statesLookup(states map[string]state.ProductStateRecord) ingest.PreviousStateLookup {
+	return func(productKey string) (ingest.PreviousState, bool, error) {
+		rec, ok := states[productKey]
+		if !ok {
+			return ingest.PreviousState{}, false, nil
+		}
+		return previousState(rec), true, nil
+	}
+}
+
+func previousState(rec state.ProductStateRecord) ingest.PreviousState {
+	return ingest.PreviousState{Hash: rec.Hash, HashVersion: rec.HashVersion, Product: rec.Product, Channels: rec.Channels}
+}
+
+// productStateWrite is what recording res writes: canonical state for accepted
+// products and a version for each accepted change.
+// Rejected products (and anything without a hash) leave canonical state untouched.
+// Unchanged products are rewritten too, which is how hashes still stored under an
+// older hash version get upgraded.
+func productStateWrite(runID string, res ingest.ProductProcessResult) (state.ProductStateWrite, bool) {
+	if res.Hash == "" {
+		return state.ProductStateWrite{}, false
+	}
+
+	switch res.Disposition {
+	case domain.ProductDispositionEnqueued, domain.ProductDispositionUnchanged:
+	default:
+		return state.ProductStateWrite{}, false
+	}
+
+	w := state.ProductStateWrite{
+		State: state.ProductStateRecord{
+			ProductKey:  res.ProductKey,
+			Hash:        res.Hash,
+			HashVersion: res.HashVersion,
+			LastRunID:   runID,
+			Product:     res.Product,
+			Channels:    res.AcceptedChannels(),
+		},
+	}
+
+	// Only accepted changes become a new version
+	if res.Disposition == domain.ProductDispositionEnqueued && res.Product != nil {
+		w.Version = &state.ProductVersion{
+			ProductKey:    res.ProductKey,
+			RunID:         runID,
+			Hash:          res.Hash,
+			HashVersion:   res.HashVersion,
+			ChangedFields: res.ChangedFields,
+			Product:       res.Product,
+		}
+	}
+	return w, true
+}
+
+// productStateWrites collects the state writes for a run's results; they are
+// committed together with the run (see state.Store.CommitIngestRun).
+func productStateWrites(runID string, results []ingest.ProductProcessResult) []state.ProductStateWrite {
+	var out []state.ProductStateWrite
+	for _, res := range results {
+		if w, ok := productStateWrite(runID, res); ok {
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/ingest"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+// ProductsHandler exposes the canonical product state Conductor holds per tenant.
+//
+//	GET /v1/products?limit=&cursor=
+//	GET /v1/products/{product_key}
+//	GET /v1/products/{product_key}/versions
+//	GET /v1/products/{product_key}/diff?from=&to=
+type ProductsHandler struct {
+	Store state.Store
+}
+
+type productStateResponse struct {
+	ProductKey string                                  `json:"product_key"`
+	Hash       string                                  `json:"hash"`
+	LastRunID  string                                  `json:"last_run_id,omitempty"`
+	UpdatedAt  time.Time                               `json:"updated_at"`
+	Channels   map[string]domain.ChannelLifecycleState `json:"channels"`
+	Product    *domain.Product                         `json:"product,omitempty"`
+}
+
+func (h ProductsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "misconfigured",
+			"message": "handler dependencies not configured",
+		})
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// product_key may contain reserved characters, so decode the escaped path ourselves.
+	rest := strings.TrimPrefix(r.URL.EscapedPath(), "/v1/products")
+	rest = strings.TrimPrefix(rest, "/")
+	if rest == "" {
+		h.serveList(w, r)
+		return
+	}
+
+	// Expected: {product_key}[/versions|/diff]
+	escapedKey, action, _ := strings.Cut(rest, "/")
+
+	productKey, err := url.PathUnescape(escapedKey)
+	if err != nil || strings.TrimSpace(productKey) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_product_key",
+			"message": "product_key missing or invalid",
+		})
+		return
+	}
+
+	switch action {
+	case "":
+		h.serveGet(w, r, productKey)
+	case "versions":
+		h.serveVersions(w, r, productKey)
+	case "diff":
+		h.serveDiff(w, r, productKey)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "unknown product endpoint",
+		})
+	}
+}
+
+func (h ProductsHandler) serveGet(w http.ResponseWriter, r *http.Request, productKey string) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	rec, ok, err := h.Store.GetProductState(r.Context(), tenantID, productKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "get_product_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "product not found",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newProductStateResponse(rec))
+}
+
+func (h ProductsHandler) serveList(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			limit = n
+		}
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	cursor := r.URL.Query().Get("cursor")
+
+	recs, err := h.Store.ListProductStates(r.Context(), tenantID, cursor, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "list_products_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	items := make([]productStateResponse, 0, len(recs))
+	for _, rec := range recs {
+		items = append(items, newProductStateResponse(rec))
+	}
+
+	// Keyset pagination: a full page means there may be more after the last key.
+	nextCursor := ""
+	if len(recs) == limit {
+		nextCursor = recs[len(recs)-1].ProductKey
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":       items,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (h ProductsHandler) serveVersions(w http.ResponseWriter, r *http.Request, productKey string) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			limit = n
+		}
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	versions, err := h.Store.ListProductVersions(r.Context(), tenantID, productKey, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "list_product_versions_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// The list view omits documents; fetch a diff to see field values.
+	for i := range versions {
+		versions[i].Product = nil
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"product_key": productKey,
+		"items":       versions,
+	})
+}
+
+// serveDiff compares two versions. "to" defaults to the latest version and
+// "from" defaults to the version before "to".
+func (h ProductsHandler) serveDiff(w http.ResponseWriter, r *http.Request, productKey string) {
+	tenantID := tenantctx.TenantID(r.Context())
+	q := r.URL.Query()
+
+	to, err := optionalPositiveInt(q.Get("to"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_version",
+			"message": "to must be a positive integer",
+		})
+		return
+	}
+	if to == 0 {
+		latest, err := h.Store.ListProductVersions(r.Context(), tenantID, productKey, 1)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"error":   "list_product_versions_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+		if len(latest) == 0 {
+			writeJSON(w, http.StatusNotFound, map[string]any{
+				"error":   "not_found",
+				"message": "product has no versions",
+			})
+			return
+		}
+		to = latest[0].Version
+	}
+
+	from, err := optionalPositiveInt(q.Get("from"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_version",
+			"message": "from must be a positive integer",
+		})
+		return
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_version",
+			"message": "no earlier version to diff against",
+		})
+		return
+	}
+
+	fromV, ok := h.loadVersion(w, r, tenantID, productKey, from)
+	if !ok {
+		return
+	}
+	toV, ok := h.loadVersion(w, r, tenantID, productKey, to)
+	if !ok {
+		return
+	}
+
+	var a, b domain.Product
+	if fromV.Product != nil {
+		a = *fromV.Product
+	}
+	if toV.Product != nil {
+		b = *toV.Product
+	}
+
+	changes, err := ingest.DiffProducts(a, b)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "diff_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"product_key": productKey,
+		"from":        fromV.Version,
+		"to":          toV.Version,
+		"changes":     changes,
+	})
+}
+
+func (h ProductsHandler) loadVersion(w http.ResponseWriter, r *http.Request, tenantID uint64, productKey string, version int) (state.ProductVersion, bool) {
+	v, ok, err := h.Store.GetProductVersion(r.Context(), tenantID, productKey, version)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "get_product_version_failed",
+			"message": err.Error(),
+		})
+		return state.ProductVersion{}, false
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "product version " + strconv.Itoa(version) + " not found",
+		})
+		return state.ProductVersion{}, false
+	}
+	return v, true
+}
+
+func optionalPositiveInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, errors.New("must be a positive integer")
+	}
+	return n, nil
+}
+
+func newProductStateResponse(rec state.ProductStateRecord) productStateResponse {
+	resp := productStateResponse{
+		ProductKey: rec.ProductKey,
+		Hash:       rec.Hash,
+		LastRunID:  rec.LastRunID,
+		UpdatedAt:  rec.UpdatedAt,
+		Channels:   map[string]domain.ChannelLifecycleState{},
+		Product:    rec.Product,
+	}
+	if rec.Product != nil {
+		resp.Channels = rec.Product.ChannelStates()
+	}
+	return resp
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/ingest"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+func productJSON(key string, title string) string {
+	return fmt.Sprintf(`{
+    "product_key": %q,
+    "title": %q,
+    "description": "Desc",
+    "link": "https://example.com/p/%s",
+    "image_link": "https://example.com/p/%s.jpg",
+    "condition": "new",
+    "availability": "in_stock",
+    "price": { "amount_decimal": "19.99", "currency": "USD" },
+    "channel": { "google": { "control": { "state": "active" } } }
+  }`, key, title, key, key)
+}
+
+func upsertProducts(t *testing.T, st state.Store, tenantID uint64, items ...string) RunResponse {
+	t.Helper()
+
+	h := DebugUpsertHandler{
+		Processor:       ingest.NewProcessor(),
+		Store:           st,
+		EnabledChannels: []string{"google"},
+	}
+
+	body := "["
+	for i, it := range items {
+		if i > 0 {
+			body += ","
+		}
+		body += it
+	}
+	body += "]"
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert", bytes.NewBufferString(body))
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), tenantID))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upsert: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp RunResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("upsert: decode failed: %v", err)
+	}
+	return resp
+}
+
+func getProducts(st state.Store, tenantID uint64, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), tenantID))
+	rec := httptest.NewRecorder()
+	ProductsHandler{Store: st}.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestProductsHandler_GetReturnsCanonicalState(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	run := upsertProducts(t, st, 1, productJSON("sku/1", "Blue Shirt"))
+
+	rec := getProducts(st, 1, "/v1/products/sku%2F1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp productStateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if resp.ProductKey != "sku/1" || resp.Hash == "" {
+		t.Fatalf("unexpected product state: %#v", resp)
+	}
+	if resp.LastRunID != run.RunID {
+		t.Fatalf("expected last_run_id=%s, got %s", run.RunID, resp.LastRunID)
+	}
+	if resp.Channels["google"] != domain.ChannelStateActive {
+		t.Fatalf("expected google=active, got %#v", resp.Channels)
+	}
+	if resp.Product == nil || resp.Product.Title != "Blue Shirt" {
+		t.Fatalf("expected stored document, got %#v", resp.Product)
+	}
+
+	// Other tenants cannot see it
+	if rec := getProducts(st, 2, "/v1/products/sku%2F1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for other tenant, got %d", rec.Code)
+	}
+}
+
+func TestProductsHandler_ListPaginates(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	upsertProducts(t, st, 1,
+		productJSON("sku3", "C"),
+		productJSON("sku1", "A"),
+		productJSON("sku2", "B"),
+	)
+
+	var page struct {
+		Items      []productStateResponse `json:"items"`
+		NextCursor string                 `json:"next_cursor"`
+	}
+
+	rec := getProducts(st, 1, "/v1/products?limit=2")
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(page.Items) != 2 || page.Items[0].ProductKey != "sku1" || page.Items[1].ProductKey != "sku2" {
+		t.Fatalf("unexpected first page: %#v", page.Items)
+	}
+	if page.NextCursor != "sku2" {
+		t.Fatalf("expected next_cursor=sku2, got %q", page.NextCursor)
+	}
+
+	rec = getProducts(st, 1, "/v1/products?limit=2&cursor="+page.NextCursor)
+	page.Items, page.NextCursor = nil, ""
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ProductKey != "sku3" || page.NextCursor != "" {
+		t.Fatalf("unexpected second page: %#v next=%q", page.Items, page.NextCursor)
+	}
+}
+
+func TestProductsHandler_VersionsAndDiff(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	upsertProducts(t, st, 1, productJSON("sku1", "Old Title"))
+	upsertProducts(t, st, 1, productJSON("sku1", "Old Title")) // unchanged: no new version
+	second := upsertProducts(t, st, 1, productJSON("sku1", "New Title"))
+
+	if got := second.Result.Products[0].ChangedFields; len(got) != 1 || got[0] != "title" {
+		t.Fatalf("expected changed_fields=[title], got %v", got)
+	}
+
+	var versions struct {
+		Items []state.ProductVersion `json:"items"`
+	}
+	rec := getProducts(st, 1, "/v1/products/sku1/versions")
+	if err := json.Unmarshal(rec.Body.Bytes(), &versions); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(versions.Items) != 2 || versions.Items[0].Version != 2 || versions.Items[0].RunID != second.RunID {
+		t.Fatalf("unexpected versions: %#v", versions.Items)
+	}
+
+	var diff struct {
+		From    int                `json:"from"`
+		To      int                `json:"to"`
+		Changes []ingest.FieldDiff `json:"changes"`
+	}
+	rec = getProducts(st, 1, "/v1/products/sku1/diff")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &diff); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if diff.From != 1 || diff.To != 2 {
+		t.Fatalf("expected diff 1..2, got %d..%d", diff.From, diff.To)
+	}
+	if len(diff.Changes) != 1 || diff.Changes[0].Path != "title" || diff.Changes[0].To != "New Title" {
+		t.Fatalf("unexpected changes: %#v", diff.Changes)
+	}
+
+	if rec := getProducts(st, 1, "/v1/products/sku1/diff?from=1&to=9"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing version, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+const (
+	defaultProductAttempts = 3
+	defaultRetryBackoff    = 50 * time.Millisecond
+)
+
+// retryPolicy bounds how often an ingest store call is retried before it is given up on. Backoff doubles after each failed attempt.
+// Only transient store errors (see state.IsTransient) are retried.
+type retryPolicy struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+func (p retryPolicy) withDefaults() retryPolicy {
+	if p.Attempts <= 0 {
+		p.Attempts = defaultProductAttempts
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = defaultRetryBackoff
+	}
+	return p
+}
+
+// do calls fn until it succeeds, fails with an error that is not transient or the
+// attempts are used up. Errors caused by the request context ending are never retried.
+func (p retryPolicy) do(ctx context.Context, fn func() error) error {
+	p = p.withDefaults()
+
+	backoff := p.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil || !state.IsTransient(err) {
+			return err
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
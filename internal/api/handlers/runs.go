@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+// RunsHandler serves run-scoped endpoints under /v1/runs/{run_id}.
+type RunsHandler struct {
+	Store state.Store
+
+	// StreamPollEvery is how often /stream re-reads run events when the store cannot
+	// push them (see state.RunEventWatcher). Defaults to 1s.
+	StreamPollEvery time.Duration
+
+	// StreamHeartbeat is the idle interval between SSE keep-alive comments. Defaults to 15s.
+	StreamHeartbeat time.Duration
+}
+
+func (h RunsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "misconfigured",
+			"message": "handler dependencies not configured",
+		})
+		return
+	}
+
+	// Expected path: /v1/runs/{run_id}/{action} or /v1/runs/{run_id}:{verb}
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/runs/")
+	runID, action, _ := strings.Cut(rest, "/")
+	runID, verb, _ := strings.Cut(runID, ":")
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_run_id",
+			"message": "run_id missing or invalid",
+		})
+		return
+	}
+
+	if verb != "" {
+		if action != "" || verb != "cancel" {
+			writeJSON(w, http.StatusNotFound, map[string]any{
+				"error":   "not_found",
+				"message": "unknown run endpoint",
+			})
+			return
+		}
+		h.serveCancel(w, r, runID)
+		return
+	}
+
+	switch action {
+	case "issues-summary":
+		h.serveIssuesSummary(w, r, runID)
+	case "events":
+		h.serveEvents(w, r, runID)
+	case "stream":
+		h.serveStream(w, r, runID)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "unknown run endpoint",
+		})
+	}
+}
+
+func (h RunsHandler) serveIssuesSummary(w http.ResponseWriter, r *http.Request, runID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	run, ok := h.loadRun(w, r, runID)
+	if !ok {
+		return
+	}
+
+	samples := 5
+	if v := r.URL.Query().Get("samples"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			samples = n
+		}
+	}
+	if samples < 0 {
+		samples = 0
+	}
+	if samples > 50 {
+		samples = 50
+	}
+
+	items, err := h.Store.SummarizeRunIssues(r.Context(), run.RunID, samples)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "summarize_issues_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"run_id":   run.RunID,
+		"rejected": run.Rejected,
+		"items":    items,
+	})
+}
+
+type runEventResponse struct {
+	state.RunEvent
+
+	// SinceCreatedMS is the time from run creation (ingest) to this event.
+	SinceCreatedMS int64 `json:"since_created_ms"`
+}
+
+func (h RunsHandler) serveEvents(w http.ResponseWriter, r *http.Request, runID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	run, ok := h.loadRun(w, r, runID)
+	if !ok {
+		return
+	}
+
+	events, err := h.Store.ListRunEvents(r.Context(), run.TenantID, run.RunID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "list_run_events_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	items := make([]runEventResponse, 0, len(events))
+	for _, ev := range events {
+		items = append(items, runEventResponse{
+			RunEvent:       ev,
+			SinceCreatedMS: ev.CreatedAt.Sub(run.CreatedAt).Milliseconds(),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"run_id": run.RunID,
+		"status": run.Status,
+		"items":  items,
+	})
+}
+
+// serveCancel stops a run from being pushed. Pending runs are cancelled immediately (200);
+// processing runs are flagged and stop between batches (202).
+func (h RunsHandler) serveCancel(w http.ResponseWriter, r *http.Request, runID string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := tenantctx.TenantID(r.Context())
+
+	run, err := h.Store.CancelRun(r.Context(), tenantID, runID)
+	switch {
+	case errors.Is(err, state.ErrRunNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "run not found",
+		})
+		return
+	case errors.Is(err, state.ErrRunNotCancellable):
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"error":   "run_not_cancellable",
+			"message": "run already finished with status " + run.Status,
+		})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "cancel_run_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	status := http.StatusOK
+	if run.CancelRequested {
+		status = http.StatusAccepted
+	}
+
+	writeJSON(w, status, map[string]any{
+		"run_id":           run.RunID,
+		"status":           run.Status,
+		"cancel_requested": run.CancelRequested,
+	})
+}
+
+// loadRun resolves the run for the request tenant, writing an error response when it cannot.
+func (h RunsHandler) loadRun(w http.ResponseWriter, r *http.Request, runID string) (state.RunRecord, bool) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	run, ok, err := h.Store.GetRun(r.Context(), tenantID, runID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "get_run_failed",
+			"message": err.Error(),
+		})
+		return state.RunRecord{}, false
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "run not found",
+		})
+		return state.RunRecord{}, false
+	}
+
+	return run, true
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+// serveStream emits the run's timeline as server-sent events until the run reaches a
+// terminal status or the client disconnects. Each SSE event carries the run event ID,
+// so clients reconnect with Last-Event-ID and resume without duplicates. The run's own
+// status decides when to stop, so a client reconnecting after the terminal event (or
+// watching a run that predates run events) is not left waiting for one.
+func (h RunsHandler) serveStream(w http.ResponseWriter, r *http.Request, runID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "streaming_unsupported",
+			"message": "response writer does not support flushing",
+		})
+		return
+	}
+
+	run, ok := h.loadRun(w, r, runID)
+	if !ok {
+		return
+	}
+
+	var lastID uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			lastID = n
+		}
+	}
+
+	pollEvery := h.StreamPollEvery
+	if pollEvery <= 0 {
+		pollEvery = time.Second
+	}
+	heartbeat := h.StreamHeartbeat
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+
+	// Watch before the first read so nothing appended in between is missed
+	var notify <-chan struct{}
+	if watcher, ok := h.Store.(state.RunEventWatcher); ok {
+		ch, stop := watcher.WatchRunEvents(run.TenantID, run.RunID)
+		defer stop()
+		notify = ch
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	poll := time.NewTicker(pollEvery)
+	defer poll.Stop()
+	lastWrite := time.Now()
+
+	for {
+		// Read the status before the events: once it is terminal, the list below holds
+		// every event the run will ever have.
+		current, ok, err := h.Store.GetRun(r.Context(), run.TenantID, run.RunID)
+		if err != nil {
+			writeSSE(w, 0, "error", map[string]any{"error": "get_run_failed", "message": err.Error()})
+			flusher.Flush()
+			return
+		}
+		finished := !ok || domain.RunStatus(current.Status).IsTerminal()
+
+		events, err := h.Store.ListRunEvents(r.Context(), run.TenantID, run.RunID)
+		if err != nil {
+			writeSSE(w, 0, "error", map[string]any{"error": "list_run_events_failed", "message": err.Error()})
+			flusher.Flush()
+			return
+		}
+
+		done := false
+		for _, ev := range events {
+			if ev.EventID <= lastID {
+				continue
+			}
+			lastID = ev.EventID
+
+			writeSSE(w, ev.EventID, string(ev.Type), runEventResponse{
+				RunEvent:       ev,
+				SinceCreatedMS: ev.CreatedAt.Sub(run.CreatedAt).Milliseconds(),
+			})
+			lastWrite = time.Now()
+
+			if ev.Type == domain.RunEventStatusChanged && domain.RunStatus(ev.Status).IsTerminal() {
+				done = true
+			}
+		}
+		flusher.Flush()
+
+		if done || finished {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-notify:
+		case <-poll.C:
+			if time.Since(lastWrite) >= heartbeat {
+				_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+				flusher.Flush()
+				lastWrite = time.Now()
+			}
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, id uint64, event string, data any) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	if id > 0 {
+		_, _ = fmt.Fprintf(w, "id: %d\n", id)
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+}
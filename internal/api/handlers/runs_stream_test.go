@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+// pollingOnlyStore hides MemoryStore's RunEventWatcher so the handler has to poll.
+type pollingOnlyStore struct {
+	state.Store
+}
+
+// streamRunEvents connects to /stream and returns the SSE event names in order.
+// advance is called once the first event (run creation) has been received.
+func streamRunEvents(t *testing.T, h RunsHandler, runID string, advance func()) []string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(tenantctx.WithTenantID(r.Context(), 1)))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/runs/"+runID+"/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	var statuses []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		// Status is the only field we assert on; avoid a full decode per line
+		status := "-"
+		if i := strings.Index(line, `"status":"`); i >= 0 {
+			rest := line[i+len(`"status":"`):]
+			status = rest[:strings.Index(rest, `"`)]
+		}
+		statuses = append(statuses, status)
+
+		if len(statuses) == 1 {
+			go advance()
+		}
+	}
+
+	return statuses
+}
+
+func runToCompletion(st state.Store) func() {
+	return func() {
+		ctx := context.Background()
+		_, _ = st.ClaimRuns(ctx, time.Now(), time.Minute, 10)
+		_ = st.AppendRunEvent(ctx, state.RunEvent{
+			RunID:    "run_stream",
+			TenantID: 1,
+			Type:     domain.RunEventBatchExecuted,
+		})
+		_ = st.CompleteRun(ctx, 1, "run_stream", nil)
+	}
+}
+
+func seedStreamRun(t *testing.T, st state.Store) {
+	t.Helper()
+
+	if err := st.InsertRun(context.Background(), state.RunRecord{
+		RunID:         "run_stream",
+		TenantID:      1,
+		Status:        string(domain.RunStatusHasChanges),
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("InsertRun: %v", err)
+	}
+}
+
+func TestRunsHandler_Stream_PushesUntilTerminal(t *testing.T) {
+	st := state.NewMemoryStore()
+	seedStreamRun(t, st)
+
+	// Long poll interval: progress must arrive through the in-process watcher
+	h := RunsHandler{Store: st, StreamPollEvery: time.Hour}
+
+	got := streamRunEvents(t, h, "run_stream", runToCompletion(st))
+
+	want := []string{"has_changes", "processing", "-", "completed"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRunsHandler_Stream_PollsStoresWithoutWatcher(t *testing.T) {
+	mem := state.NewMemoryStore()
+	st := pollingOnlyStore{Store: mem}
+	seedStreamRun(t, st)
+
+	h := RunsHandler{Store: st, StreamPollEvery: 10 * time.Millisecond}
+
+	got := streamRunEvents(t, h, "run_stream", runToCompletion(st))
+
+	if len(got) != 4 || got[len(got)-1] != "completed" {
+		t.Fatalf("expected stream to end on completed, got %v", got)
+	}
+}
+
+func TestRunsHandler_Stream_ReconnectAfterTerminalEnds(t *testing.T) {
+	st := state.NewMemoryStore()
+	seedStreamRun(t, st)
+	runToCompletion(st)()
+
+	events, err := st.ListRunEvents(context.Background(), 1, "run_stream")
+	if err != nil || len(events) == 0 {
+		t.Fatalf("ListRunEvents: %v (%d events)", err, len(events))
+	}
+	last := events[len(events)-1].EventID
+
+	h := RunsHandler{Store: st, StreamPollEvery: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/runs/run_stream/stream", nil)
+	req = req.WithContext(tenantctx.WithTenantID(ctx, 1))
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(last, 10))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	if ctx.Err() != nil {
+		t.Fatalf("expected stream of a finished run to end, it ran until the deadline")
+	}
+	if strings.Contains(rec.Body.String(), "data: ") {
+		t.Fatalf("expected no replayed events, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/ingest"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+func seedRun(t *testing.T, st state.Store, tenantID uint64, runID string, products []ingest.ProductProcessResult) {
+	t.Helper()
+
+	ctx := context.Background()
+	if err := st.InsertRun(ctx, state.RunRecord{
+		RunID:     runID,
+		TenantID:  tenantID,
+		Status:    string(domain.RunStatusCompleted),
+		Received:  len(products),
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("InsertRun: %v", err)
+	}
+	if err := st.InsertRunProducts(ctx, runID, products); err != nil {
+		t.Fatalf("InsertRunProducts: %v", err)
+	}
+}
+
+func serveRuns(st state.Store, tenantID uint64, method string, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), tenantID))
+	rec := httptest.NewRecorder()
+	RunsHandler{Store: st}.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRunsHandler_IssuesSummary(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	missingImage := ingest.ValidationIssue{Path: "image_link", Code: "required", Message: "field is required"}
+	badCurrency := ingest.ValidationIssue{Path: "price.currency", Code: "invalid_currency"}
+
+	seedRun(t, st, 1, "run_issues", []ingest.ProductProcessResult{
+		{ProductKey: "sku3", Disposition: domain.ProductDispositionRejected, Issues: []ingest.ValidationIssue{missingImage}},
+		{ProductKey: "sku1", Disposition: domain.ProductDispositionRejected, Issues: []ingest.ValidationIssue{missingImage, badCurrency}},
+		{ProductKey: "sku2", Disposition: domain.ProductDispositionRejected, Issues: []ingest.ValidationIssue{missingImage}},
+		{ProductKey: "sku4", Disposition: domain.ProductDispositionEnqueued},
+	})
+
+	rec := serveRuns(st, 1, http.MethodGet, "/v1/runs/run_issues/issues-summary?samples=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Items []state.RunIssueSummary `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if len(resp.Items) != 2 {
+		t.Fatalf("expected 2 groups, got %#v", resp.Items)
+	}
+
+	first := resp.Items[0]
+	if first.Path != "image_link" || first.Code != "required" || first.Count != 3 {
+		t.Fatalf("unexpected first group: %#v", first)
+	}
+	if len(first.SampleProductKeys) != 2 || first.SampleProductKeys[0] != "sku1" || first.SampleProductKeys[1] != "sku2" {
+		t.Fatalf("unexpected samples: %#v", first.SampleProductKeys)
+	}
+
+	second := resp.Items[1]
+	if second.Code != "invalid_currency" || second.Count != 1 {
+		t.Fatalf("unexpected second group: %#v", second)
+	}
+}
+
+func TestRunsHandler_IssuesSummary_IsTenantScoped(t *testing.T) {
+	st := state.NewMemoryStore()
+	seedRun(t, st, 1, "run_other_tenant", nil)
+
+	rec := serveRuns(st, 2, http.MethodGet, "/v1/runs/run_other_tenant/issues-summary")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRunsHandler_Cancel(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+
+	_ = st.InsertRun(ctx, state.RunRecord{
+		RunID:         "run_pending",
+		TenantID:      1,
+		Status:        string(domain.RunStatusHasChanges),
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	})
+	seedRun(t, st, 1, "run_done", nil)
+
+	rec := serveRuns(st, 1, http.MethodGet, "/v1/runs/run_pending:cancel")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+
+	rec = serveRuns(st, 2, http.MethodPost, "/v1/runs/run_pending:cancel")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for other tenant, got %d", rec.Code)
+	}
+
+	rec = serveRuns(st, 1, http.MethodPost, "/v1/runs/run_pending:cancel")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if resp.Status != string(domain.RunStatusCancelled) {
+		t.Fatalf("expected cancelled, got %q", resp.Status)
+	}
+
+	rec = serveRuns(st, 1, http.MethodPost, "/v1/runs/run_done:cancel")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for completed run, got %d", rec.Code)
+	}
+}
+
+func TestRunsHandler_Events(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+
+	_ = st.InsertRun(ctx, state.RunRecord{
+		RunID:         "run_events",
+		TenantID:      1,
+		Status:        string(domain.RunStatusHasChanges),
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC().Add(-2 * time.Second),
+	})
+	_ = st.AppendRunEvent(ctx, state.RunEvent{
+		RunID:    "run_events",
+		TenantID: 1,
+		Type:     domain.RunEventChannelMilestone,
+		Channel:  "google",
+		Details:  map[string]any{"milestone": "live"},
+	})
+
+	rec := serveRuns(st, 2, http.MethodGet, "/v1/runs/run_events/events")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for other tenant, got %d", rec.Code)
+	}
+
+	rec = serveRuns(st, 1, http.MethodGet, "/v1/runs/run_events/events")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Items []struct {
+			Type           string `json:"type"`
+			Status         string `json:"status"`
+			Channel        string `json:"channel"`
+			SinceCreatedMS int64  `json:"since_created_ms"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if len(resp.Items) != 2 {
+		t.Fatalf("expected 2 events, got %#v", resp.Items)
+	}
+	if resp.Items[0].Type != "status_changed" || resp.Items[0].Status != "has_changes" {
+		t.Fatalf("unexpected first event: %#v", resp.Items[0])
+	}
+	if resp.Items[1].Channel != "google" || resp.Items[1].SinceCreatedMS < 2000 {
+		t.Fatalf("unexpected milestone event: %#v", resp.Items[1])
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/ingest"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+// TenantValidationHandler manages the tenant's validation policy, which applies to
+// ingest requests whose feed has no policy of its own (link domains, https, image caps).
+//
+//	GET /v1/tenant/validation
+//	PUT /v1/tenant/validation
+type TenantValidationHandler struct {
+	Store state.Store
+}
+
+func (h TenantValidationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "misconfigured",
+			"message": "handler dependencies not configured",
+		})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.serveGet(w, r)
+	case http.MethodPut:
+		h.servePut(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h TenantValidationHandler) serveGet(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	policy, err := h.Store.GetTenantValidation(r.Context(), tenantID)
+	if errors.Is(err, state.ErrTenantNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "tenant not found",
+		})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "get_validation_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if policy == nil {
+		policy = &ingest.ValidationPolicy{}
+	}
+
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// servePut replaces the tenant's policy; a JSON null restores the default.
+func (h TenantValidationHandler) servePut(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	var policy *ingest.ValidationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_json",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	err := h.Store.UpdateTenantValidation(r.Context(), tenantID, policy)
+	if errors.Is(err, state.ErrTenantNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "tenant not found",
+		})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "update_validation_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.serveGet(w, r)
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/state"
+	"github.com/ETAnderson/conductor/internal/webhooks"
+)
+
+// WebhooksHandler manages a tenant's webhook endpoints and their delivery log.
+//
+//	GET    /v1/webhooks
+//	POST   /v1/webhooks
+//	DELETE /v1/webhooks/{endpoint_id}
+//	GET    /v1/webhooks/{endpoint_id}/deliveries?limit=
+type WebhooksHandler struct {
+	Store state.Store
+
+	// AllowInsecureURLs accepts http:// endpoints and loopback or private hosts (dev
+	// and tests only; see webhooks.ValidateEndpointURL).
+	AllowInsecureURLs bool
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+func (h WebhooksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "misconfigured",
+			"message": "handler dependencies not configured",
+		})
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/webhooks"), "/")
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.serveList(w, r)
+		case http.MethodPost:
+			h.serveCreate(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	idPart, action, _ := strings.Cut(rest, "/")
+	endpointID, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil || endpointID == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_endpoint_id",
+			"message": "endpoint_id must be a positive integer",
+		})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+		h.serveDelete(w, r, endpointID)
+	case action == "deliveries" && r.Method == http.MethodGet:
+		h.serveDeliveries(w, r, endpointID)
+	case action == "" || action == "deliveries":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "unknown webhook endpoint",
+		})
+	}
+}
+
+func (h WebhooksHandler) serveList(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	items, err := h.Store.ListWebhookEndpoints(r.Context(), tenantID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "list_webhooks_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if items == nil {
+		items = []state.WebhookEndpoint{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+	})
+}
+
+func (h WebhooksHandler) serveCreate(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_json",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := webhooks.ValidateEndpointURL(req.URL, h.AllowInsecureURLs); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_url",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if len(req.Events) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":   "invalid_events",
+			"message": "at least one event is required",
+		})
+		return
+	}
+	for _, ev := range req.Events {
+		if !webhooks.IsEvent(ev) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"error":   "invalid_events",
+				"message": "unknown event " + strconv.Quote(ev),
+				"allowed": webhooks.Events(),
+			})
+			return
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		secret, err = newWebhookSecret()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"error":   "secret_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	ep, err := h.Store.CreateWebhookEndpoint(r.Context(), state.WebhookEndpoint{
+		TenantID: tenantID,
+		URL:      req.URL,
+		Secret:   secret,
+		Events:   req.Events,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "create_webhook_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// The secret is only ever returned here
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"id":         ep.ID,
+		"url":        ep.URL,
+		"events":     ep.Events,
+		"secret":     ep.Secret,
+		"created_at": ep.CreatedAt,
+	})
+}
+
+func (h WebhooksHandler) serveDelete(w http.ResponseWriter, r *http.Request, endpointID uint64) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	err := h.Store.DeleteWebhookEndpoint(r.Context(), tenantID, endpointID)
+	if errors.Is(err, state.ErrWebhookEndpointNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":   "not_found",
+			"message": "webhook endpoint not found",
+		})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "delete_webhook_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h WebhooksHandler) serveDeliveries(w http.ResponseWriter, r *http.Request, endpointID uint64) {
+	tenantID := tenantctx.TenantID(r.Context())
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			limit = n
+		}
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	items, err := h.Store.ListWebhookDeliveries(r.Context(), tenantID, endpointID, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":   "list_deliveries_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if items == nil {
+		items = []state.WebhookDelivery{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"endpoint_id": endpointID,
+		"items":       items,
+	})
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
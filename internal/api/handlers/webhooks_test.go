@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/state"
+	"github.com/ETAnderson/conductor/internal/webhooks"
+)
+
+func serveWebhooks(st state.Store, tenantID uint64, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), tenantID))
+	rec := httptest.NewRecorder()
+	WebhooksHandler{Store: st}.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebhooksHandler_CreateListDeleteAndDeliveries(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	rec := serveWebhooks(st, 1, http.MethodPost, "/v1/webhooks", `{"url":"https://example.com/hook","events":["run.nope"]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown event, got %d", rec.Code)
+	}
+
+	rec = serveWebhooks(st, 1, http.MethodPost, "/v1/webhooks", `{"url":"https://example.com/hook","events":["run.completed"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var created struct {
+		ID     uint64 `json:"id"`
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if created.ID == 0 || created.Secret == "" {
+		t.Fatalf("expected id and generated secret, got %+v", created)
+	}
+
+	rec = serveWebhooks(st, 1, http.MethodGet, "/v1/webhooks", "")
+	if rec.Code != http.StatusOK || bytes.Contains(rec.Body.Bytes(), []byte(created.Secret)) {
+		t.Fatalf("expected list without secret, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	_, _ = st.EnqueueWebhookEvent(context.Background(), 1, webhooks.EventRunCompleted, "run_1", []byte(`{"event":"run.completed"}`))
+
+	path := "/v1/webhooks/" + strconv.FormatUint(created.ID, 10)
+
+	rec = serveWebhooks(st, 1, http.MethodGet, path+"/deliveries", "")
+	var log struct {
+		Items []state.WebhookDelivery `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &log); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(log.Items) != 1 || log.Items[0].RunID != "run_1" || log.Items[0].Status != state.WebhookDeliveryPending {
+		t.Fatalf("unexpected delivery log: %+v", log.Items)
+	}
+
+	rec = serveWebhooks(st, 2, http.MethodDelete, path, "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for other tenant, got %d", rec.Code)
+	}
+
+	rec = serveWebhooks(st, 1, http.MethodDelete, path, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+}
+
+func TestWebhooksHandler_RejectsInsecureURLs(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	for _, u := range []string{"http://example.com/hook", "https://127.0.0.1/hook", "https://169.254.169.254/hook"} {
+		rec := serveWebhooks(st, 1, http.MethodPost, "/v1/webhooks", `{"url":"`+u+`","events":["run.completed"]}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", u, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Dev deployments opt in to local receivers
+	req := httptest.NewRequest(http.MethodPost, "/v1/webhooks", bytes.NewBufferString(`{"url":"http://127.0.0.1:9000/hook","events":["run.completed"]}`))
+	req = req.WithContext(tenantctx.WithTenantID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	WebhooksHandler{Store: st, AllowInsecureURLs: true}.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 with insecure URLs allowed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
-	"io"
+	"encoding/json"
 	"net/http"
-	"net/http/httptest"
 	"strings"
 	"time"
 
 	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/ingest"
 	"github.com/ETAnderson/conductor/internal/state"
 )
 
@@ -60,57 +60,167 @@ func (m IdempotencyMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	}
 
 	if ok {
-		// Return cached response (body only)
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-		status := rec.StatusCode
-		if status == 0 {
-			status = http.StatusOK
+		if rec.RunID != "" {
+			if m.replayRun(w, r, tenantID, rec) {
+				return
+			}
+			// The referenced run was never persisted (the handler failed after
+			// streaming began), so there is nothing to replay: process again.
+		} else {
+			// Return cached response (body only)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+			status := rec.StatusCode
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			w.WriteHeader(status)
+			_, _ = w.Write(rec.BodyJSON)
+			return
 		}
-
-		w.WriteHeader(status)
-		_, _ = w.Write(rec.BodyJSON)
-		return
 	}
 
-	// Ensure downstream can read the body (we may have already consumed it if we add future logic)
-	if r.Body != nil {
-		reqBody, _ := io.ReadAll(r.Body)
-		_ = r.Body.Close()
-		r.Body = io.NopCloser(bytes.NewReader(reqBody))
-	}
-
-	// Record downstream response
-	rr := httptest.NewRecorder()
-	m.Next.ServeHTTP(rr, r)
-
-	// Copy recorded response to the real writer
-	for k, vals := range rr.Header() {
-		for _, v := range vals {
-			w.Header().Add(k, v)
-		}
-	}
+	// Pass the response through as it is written (so streamed responses stay
+	// streamed) while keeping a copy of the body for the cache. Handlers that
+	// identify their run via RunIDHeader on a successful response are cached by
+	// reference and their body is not copied at all.
+	cw := &captureWriter{ResponseWriter: w}
+	m.Next.ServeHTTP(cw, r)
 
-	status := rr.Code
+	status := cw.status
 	if status == 0 {
 		status = http.StatusOK
 	}
 
-	w.WriteHeader(status)
-	_, _ = w.Write(rr.Body.Bytes())
-
 	// Cache body (and status) only
 	respRec := state.IdempotencyRecord{
 		StatusCode: status,
-		BodyJSON:   rr.Body.Bytes(),
+		BodyJSON:   cw.body.Bytes(),
+		RunID:      cw.runID,
 		CreatedAt:  time.Now().UTC(),
 		ExpiresAt:  time.Now().UTC().Add(24 * time.Hour),
 	}
+	if respRec.RunID != "" {
+		respRec.BodyJSON = nil
+	}
 
 	// If caching fails, do not fail the request; response has already been written.
 	_ = m.Store.PutIdempotency(r.Context(), tenantID, endpoint, keyHash, respRec)
 }
 
+// RunIDHeader is set by handlers whose response describes a single run. A
+// successful response carrying it is cached as a reference to that run.
+const RunIDHeader = "X-Conductor-Run-Id"
+
+// IdempotentReplayHeader marks responses served from the idempotency cache by run reference.
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+// RunReplayer is implemented by handlers whose responses are cached by run reference.
+// ReplayRun writes the response r would have received, rebuilt from the stored run,
+// so a replay has the same shape as the original response. Handlers without it get
+// runReplay.
+type RunReplayer interface {
+	ReplayRun(w http.ResponseWriter, r *http.Request, run state.RunRecord)
+}
+
+// runReplay is the body replayed for a request cached by run reference: the run's
+// current status and counts (per-product results stay available from the run).
+type runReplay struct {
+	RunID         string                   `json:"run_id"`
+	Status        string                   `json:"status"`
+	PushTriggered bool                     `json:"push_triggered"`
+	Warnings      ingest.UnknownKeyWarning `json:"warnings,omitempty"`
+	Summary       ingest.ProcessSummary    `json:"summary"`
+	Replayed      bool                     `json:"replayed"`
+}
+
+// replayRun writes the replay for rec and reports whether the run was found.
+func (m IdempotencyMiddleware) replayRun(w http.ResponseWriter, r *http.Request, tenantID uint64, rec state.IdempotencyRecord) bool {
+	run, ok, err := m.Store.GetRun(r.Context(), tenantID, rec.RunID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"idempotency_lookup_failed"}`))
+		return true
+	}
+	if !ok {
+		return false
+	}
+
+	if rr, ok := m.Next.(RunReplayer); ok {
+		w.Header().Set(IdempotentReplayHeader, "true")
+		rr.ReplayRun(w, r, run)
+		return true
+	}
+
+	body, err := json.Marshal(runReplay{
+		RunID:         run.RunID,
+		Status:        run.Status,
+		PushTriggered: run.PushTriggered,
+		Warnings:      run.Warnings,
+		Summary:       run.Summary(),
+		Replayed:      true,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+
+	status := rec.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set(RunIDHeader, run.RunID)
+	w.Header().Set(IdempotentReplayHeader, "true")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+	return true
+}
+
+// captureWriter passes a response through to the client while recording what the
+// idempotency cache needs.
+type captureWriter struct {
+	http.ResponseWriter
+
+	status int
+	runID  string
+	body   bytes.Buffer
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if c.status != 0 {
+		return
+	}
+	c.status = status
+	if status < 300 {
+		c.runID = c.Header().Get(RunIDHeader)
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.runID == "" {
+		c.body.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush lets streaming handlers push partial output through the middleware.
+func (c *captureWriter) Flush() {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func sha256Hex(s string) string {
 	sum := sha256.Sum256([]byte(s))
 	return hex.EncodeToString(sum[:])
@@ -2,9 +2,12 @@ package middleware
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ETAnderson/conductor/internal/api/tenantctx"
 	"github.com/ETAnderson/conductor/internal/state"
@@ -45,3 +48,68 @@ func TestIdempotencyMiddleware_CachesResponseViaStateStore(t *testing.T) {
 		t.Fatalf("expected cached response match")
 	}
 }
+
+func TestIdempotencyMiddleware_CachesRunReferenceInsteadOfBody(t *testing.T) {
+	store := state.NewMemoryStore()
+	ctx := tenantctx.WithTenantID(context.Background(), 1)
+
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		runID := "run_" + itoa(calls)
+		if calls > 1 {
+			_ = store.InsertRun(r.Context(), state.RunRecord{
+				RunID: runID, TenantID: 1, Status: "completed", Received: 2, Rejected: 2, CreatedAt: time.Now().UTC(),
+			})
+		}
+		w.Header().Set(RunIDHeader, runID)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"run_id":"` + runID + `","result":{"products":["...large..."]}}`))
+	})
+
+	mw := IdempotencyMiddleware{Store: store, Next: next}
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/debug/products:upsert-bulk", bytes.NewBufferString(`{}`))
+		req = req.WithContext(ctx)
+		req.Header.Set(IdempotencyHeaderKey, "bulk-1")
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// run_1 was never persisted, so the cached reference cannot be replayed
+	post()
+	post()
+	if calls != 2 {
+		t.Fatalf("expected a dangling run reference to be processed again, got %d calls", calls)
+	}
+
+	cached, ok, _ := store.GetIdempotency(ctx, 1, "/v1/debug/products:upsert-bulk", sha256Hex("bulk-1"))
+	if !ok || cached.RunID != "run_2" || len(cached.BodyJSON) != 0 {
+		t.Fatalf("expected run reference without body, got %+v", cached)
+	}
+
+	rec := post()
+	if calls != 2 {
+		t.Fatalf("expected replay from cache, got %d calls", calls)
+	}
+	if rec.Header().Get(IdempotentReplayHeader) != "true" {
+		t.Fatalf("expected replay header")
+	}
+
+	var body struct {
+		RunID    string `json:"run_id"`
+		Status   string `json:"status"`
+		Replayed bool   `json:"replayed"`
+		Summary  struct {
+			Rejected int `json:"rejected"`
+		} `json:"summary"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.RunID != "run_2" || body.Status != "completed" || !body.Replayed || body.Summary.Rejected != 2 {
+		t.Fatalf("unexpected replay body: %s", rec.Body.String())
+	}
+}
@@ -12,6 +12,10 @@ type Config struct {
 
 	// Optional: run migrations at startup (dev convenience)
 	RunMigrations bool `env:"RUN_MIGRATIONS" default:"false"`
+
+	// Accept http:// webhook endpoints and deliver to loopback/private addresses
+	// (defaults to true only when ENV=dev)
+	WebhooksAllowInsecure bool `env:"WEBHOOKS_ALLOW_INSECURE"`
 }
 
 func Load() Config {
@@ -22,6 +26,11 @@ func Load() Config {
 		MySQLDSN:      getenv("DB_DSN", ""),
 		RunMigrations: getenv("RUN_MIGRATIONS", "false") == "true",
 	}
+	insecureDefault := "false"
+	if cfg.Env == "dev" {
+		insecureDefault = "true"
+	}
+	cfg.WebhooksAllowInsecure = getenv("WEBHOOKS_ALLOW_INSECURE", insecureDefault) == "true"
 	return cfg
 }
 
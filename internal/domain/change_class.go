@@ -0,0 +1,21 @@
+package domain
+
+// ChangeClass groups changed fields by how channels want to receive them.
+type ChangeClass string
+
+const (
+	// ChangeClassLifecycle: per-channel control.state changed (publish/archive/delete).
+	ChangeClassLifecycle ChangeClass = "lifecycle"
+	// ChangeClassPriceInventory: price, sale price or availability changed.
+	// Urgent, and often pushable through lighter inventory-only APIs.
+	ChangeClassPriceInventory ChangeClass = "price_inventory"
+	// ChangeClassMedia: primary or additional images changed.
+	ChangeClassMedia ChangeClass = "media"
+	// ChangeClassContent: any other descriptive field changed.
+	ChangeClassContent ChangeClass = "content"
+)
+
+// Urgent reports whether changes of this class should be pushed ahead of others.
+func (c ChangeClass) Urgent() bool {
+	return c == ChangeClassLifecycle || c == ChangeClassPriceInventory
+}
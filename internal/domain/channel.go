@@ -11,3 +11,42 @@ const (
 type ChannelControl struct {
 	State ChannelLifecycleState `json:"state"`
 }
+
+// ChannelStates returns the lifecycle state for each channel block present on the product.
+func (p Product) ChannelStates() map[string]ChannelLifecycleState {
+	out := make(map[string]ChannelLifecycleState, 3)
+	if p.Channel.Google != nil {
+		out["google"] = p.Channel.Google.Control.State
+	}
+	if p.Channel.Meta != nil {
+		out["meta"] = p.Channel.Meta.Control.State
+	}
+	if p.Channel.Yotpo != nil {
+		out["yotpo"] = p.Channel.Yotpo.Control.State
+	}
+	return out
+}
+
+// ChannelAttributes are the product fields a channel spells in its own vocabulary.
+// Fields a channel does not use are left empty.
+type ChannelAttributes struct {
+	Condition    string `json:"condition,omitempty"`
+	Availability string `json:"availability,omitempty"`
+	InStock      *bool  `json:"in_stock,omitempty"`
+}
+
+// ChannelAttributes maps p's condition and availability through the per-channel mappers
+// (see GoogleCondition, MetaAvailability, YotpoInStock). Unknown channels get canonical values.
+func (p Product) ChannelAttributes(channel string) ChannelAttributes {
+	switch channel {
+	case "google":
+		return ChannelAttributes{Condition: GoogleCondition(p.Condition), Availability: GoogleAvailability(p.Availability)}
+	case "meta":
+		return ChannelAttributes{Condition: MetaCondition(p.Condition), Availability: MetaAvailability(p.Availability)}
+	case "yotpo":
+		inStock := YotpoInStock(p.Availability)
+		return ChannelAttributes{InStock: &inStock}
+	default:
+		return ChannelAttributes{Condition: string(p.Condition), Availability: string(p.Availability)}
+	}
+}
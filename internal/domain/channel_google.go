@@ -3,3 +3,13 @@ package domain
 type GoogleFields struct {
 	Control ChannelControl `json:"control"`
 }
+
+// GoogleCondition is c in Google Merchant Center's vocabulary.
+func GoogleCondition(c Condition) string {
+	return string(c)
+}
+
+// GoogleAvailability is a in Google Merchant Center's vocabulary.
+func GoogleAvailability(a Availability) string {
+	return string(a)
+}
@@ -3,3 +3,23 @@ package domain
 type MetaFields struct {
 	Control ChannelControl `json:"control"`
 }
+
+// MetaCondition is c in Meta catalog vocabulary.
+func MetaCondition(c Condition) string {
+	return string(c)
+}
+
+// MetaAvailability is a in Meta catalog vocabulary, which uses spaces and calls
+// backorders "available for order".
+func MetaAvailability(a Availability) string {
+	switch a {
+	case AvailabilityInStock:
+		return "in stock"
+	case AvailabilityOutOfStock:
+		return "out of stock"
+	case AvailabilityBackorder:
+		return "available for order"
+	default:
+		return string(a)
+	}
+}
@@ -3,3 +3,8 @@ package domain
 type YotpoFields struct {
 	Control ChannelControl `json:"control"`
 }
+
+// YotpoInStock is a as Yotpo's in_stock flag: only products available now count.
+func YotpoInStock(a Availability) bool {
+	return a == AvailabilityInStock
+}
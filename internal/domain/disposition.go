@@ -6,4 +6,12 @@ const (
 	ProductDispositionRejected  ProductDisposition = "rejected"
 	ProductDispositionUnchanged ProductDisposition = "unchanged"
 	ProductDispositionEnqueued  ProductDisposition = "enqueued"
+
+	// ProductDispositionDuplicate marks a product_key that appeared more than once in a
+	// batch and was not processed (see ingest.DuplicatePolicy).
+	ProductDispositionDuplicate ProductDisposition = "duplicate"
+
+	// ProductDispositionError marks a product that could not be processed because of
+	// an infrastructure error (e.g. a failed state lookup); canonical state is unchanged.
+	ProductDispositionError ProductDisposition = "error"
 )
@@ -21,8 +21,8 @@ type Product struct {
 	GTIN  string `json:"gtin,omitempty"`
 	MPN   string `json:"mpn,omitempty"`
 
-	Condition    string `json:"condition"`
-	Availability string `json:"availability"`
+	Condition    Condition    `json:"condition"`
+	Availability Availability `json:"availability"`
 
 	Price     Money  `json:"price"`
 	SalePrice *Money `json:"sale_price,omitempty"`
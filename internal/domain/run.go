@@ -6,4 +6,69 @@ const (
 	RunStatusCompleted        RunStatus = "completed"
 	RunStatusNoChangeDetected RunStatus = "no_change_detected"
 	RunStatusHasChanges       RunStatus = "has_changes"
+
+	// RunStatusProcessing is a run claimed by a worker and being executed.
+	RunStatusProcessing RunStatus = "processing"
+	RunStatusFailed     RunStatus = "failed"
+
+	// RunStatusSuperseded marks a pending run whose changes were coalesced into a newer
+	// run for the same tenant/feed (see RunRecord.SupersededBy).
+	RunStatusSuperseded RunStatus = "superseded"
+
+	RunStatusCancelled RunStatus = "cancelled"
+
+	// RunStatusPartialSuccess is a run that finished while some of its products
+	// errored (see RunRecord.Errored); the rest were processed normally.
+	RunStatusPartialSuccess RunStatus = "partial_success"
 )
+
+// runTransitions is the run lifecycle:
+//
+//	has_changes -> processing -> completed | partial_success | failed | cancelled
+//	has_changes -> superseded | cancelled
+//
+// Ingestion creates runs as has_changes, no_change_detected, completed or
+// partial_success (product errors and nothing to push).
+// Every status without outgoing transitions is terminal.
+var runTransitions = map[RunStatus][]RunStatus{
+	RunStatusHasChanges: {RunStatusProcessing, RunStatusSuperseded, RunStatusCancelled},
+	RunStatusProcessing: {RunStatusCompleted, RunStatusPartialSuccess, RunStatusFailed, RunStatusCancelled},
+}
+
+// RunStatuses lists every known run status.
+func RunStatuses() []RunStatus {
+	return []RunStatus{
+		RunStatusHasChanges,
+		RunStatusNoChangeDetected,
+		RunStatusProcessing,
+		RunStatusCompleted,
+		RunStatusPartialSuccess,
+		RunStatusFailed,
+		RunStatusSuperseded,
+		RunStatusCancelled,
+	}
+}
+
+func (s RunStatus) Valid() bool {
+	for _, known := range RunStatuses() {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving from s to next.
+func (s RunStatus) CanTransitionTo(next RunStatus) bool {
+	for _, allowed := range runTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether no further transitions are allowed from s.
+func (s RunStatus) IsTerminal() bool {
+	return len(runTransitions[s]) == 0
+}
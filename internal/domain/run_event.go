@@ -0,0 +1,23 @@
+package domain
+
+// RunEventType classifies entries in a run's event timeline.
+type RunEventType string
+
+const (
+	// RunEventStatusChanged records a lifecycle transition (including creation); the
+	// event's Status is the status entered.
+	RunEventStatusChanged RunEventType = "status_changed"
+
+	RunEventCancelRequested RunEventType = "cancel_requested"
+
+	// RunEventClaimExpired records a processing run claimed again because its claim
+	// lease ran out before the run finished.
+	RunEventClaimExpired RunEventType = "claim_expired"
+
+	// RunEventBatchExecuted records one executor batch handed to channel orchestration.
+	RunEventBatchExecuted RunEventType = "batch_executed"
+
+	// RunEventChannelMilestone records channel progress (e.g. "submitted", "live")
+	// for the event's Channel.
+	RunEventChannelMilestone RunEventType = "channel_milestone"
+)
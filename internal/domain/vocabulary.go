@@ -0,0 +1,115 @@
+package domain
+
+import "strings"
+
+// Condition is a product's canonical condition. Channels spell it differently; see the
+// per-channel mappers (e.g. GoogleCondition).
+type Condition string
+
+const (
+	ConditionNew         Condition = "new"
+	ConditionRefurbished Condition = "refurbished"
+	ConditionUsed        Condition = "used"
+)
+
+// Availability is a product's canonical stock status.
+type Availability string
+
+const (
+	AvailabilityInStock    Availability = "in_stock"
+	AvailabilityOutOfStock Availability = "out_of_stock"
+	AvailabilityPreorder   Availability = "preorder"
+	AvailabilityBackorder  Availability = "backorder"
+)
+
+// Accepted spellings, keyed by vocabularyKey.
+var (
+	conditionAliases = map[string]Condition{
+		"new":           ConditionNew,
+		"brand_new":     ConditionNew,
+		"refurbished":   ConditionRefurbished,
+		"refurb":        ConditionRefurbished,
+		"renewed":       ConditionRefurbished,
+		"reconditioned": ConditionRefurbished,
+		"used":          ConditionUsed,
+		"pre_owned":     ConditionUsed,
+		"preowned":      ConditionUsed,
+		"second_hand":   ConditionUsed,
+		"secondhand":    ConditionUsed,
+	}
+
+	availabilityAliases = map[string]Availability{
+		"in_stock":            AvailabilityInStock,
+		"instock":             AvailabilityInStock,
+		"available":           AvailabilityInStock,
+		"out_of_stock":        AvailabilityOutOfStock,
+		"outofstock":          AvailabilityOutOfStock,
+		"sold_out":            AvailabilityOutOfStock,
+		"soldout":             AvailabilityOutOfStock,
+		"unavailable":         AvailabilityOutOfStock,
+		"preorder":            AvailabilityPreorder,
+		"pre_order":           AvailabilityPreorder,
+		"backorder":           AvailabilityBackorder,
+		"back_order":          AvailabilityBackorder,
+		"available_for_order": AvailabilityBackorder,
+	}
+)
+
+// ParseCondition maps a canonical value or accepted alias ("New", "pre-owned") to its
+// Condition.
+func ParseCondition(s string) (Condition, bool) {
+	if c, ok := conditionAliases[s]; ok {
+		return c, true
+	}
+	c, ok := conditionAliases[vocabularyKey(s)]
+	return c, ok
+}
+
+// ParseAvailability maps a canonical value or accepted alias ("In Stock", "instock")
+// to its Availability.
+func ParseAvailability(s string) (Availability, bool) {
+	if a, ok := availabilityAliases[s]; ok {
+		return a, true
+	}
+	a, ok := availabilityAliases[vocabularyKey(s)]
+	return a, ok
+}
+
+// Valid reports whether c is a canonical Condition.
+func (c Condition) Valid() bool {
+	switch c {
+	case ConditionNew, ConditionRefurbished, ConditionUsed:
+		return true
+	}
+	return false
+}
+
+// Valid reports whether a is a canonical Availability.
+func (a Availability) Valid() bool {
+	switch a {
+	case AvailabilityInStock, AvailabilityOutOfStock, AvailabilityPreorder, AvailabilityBackorder:
+		return true
+	}
+	return false
+}
+
+// vocabularyKey lower-cases s and turns runs of spaces, hyphens and underscores into a
+// single underscore, so "In Stock", "in-stock" and "in_stock" look the same.
+func vocabularyKey(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	sep := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if r == ' ' || r == '-' || r == '_' {
+			sep = true
+			continue
+		}
+		if sep && b.Len() > 0 {
+			b.WriteByte('_')
+		}
+		sep = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
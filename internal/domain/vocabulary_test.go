@@ -0,0 +1,70 @@
+package domain
+
+import "testing"
+
+func TestParseVocabulary(t *testing.T) {
+	conditions := map[string]Condition{
+		"new":         ConditionNew,
+		" Brand New ": ConditionNew,
+		"Pre-Owned":   ConditionUsed,
+		"REFURB":      ConditionRefurbished,
+	}
+	for in, want := range conditions {
+		if got, ok := ParseCondition(in); !ok || got != want {
+			t.Fatalf("ParseCondition(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+
+	availabilities := map[string]Availability{
+		"in_stock":            AvailabilityInStock,
+		"In Stock":            AvailabilityInStock,
+		"instock":             AvailabilityInStock,
+		"in--stock":           AvailabilityInStock,
+		"Sold Out":            AvailabilityOutOfStock,
+		"pre-order":           AvailabilityPreorder,
+		"available for order": AvailabilityBackorder,
+	}
+	for in, want := range availabilities {
+		if got, ok := ParseAvailability(in); !ok || got != want {
+			t.Fatalf("ParseAvailability(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+
+	for _, bad := range []string{"", "mint", "in stock now"} {
+		if _, ok := ParseCondition(bad); ok {
+			t.Fatalf("expected condition %q to be rejected", bad)
+		}
+		if _, ok := ParseAvailability(bad); ok {
+			t.Fatalf("expected availability %q to be rejected", bad)
+		}
+	}
+}
+
+func TestChannelVocabularyMappers(t *testing.T) {
+	cases := []struct {
+		in     Availability
+		google string
+		meta   string
+		yotpo  bool
+	}{
+		{AvailabilityInStock, "in_stock", "in stock", true},
+		{AvailabilityOutOfStock, "out_of_stock", "out of stock", false},
+		{AvailabilityPreorder, "preorder", "preorder", false},
+		{AvailabilityBackorder, "backorder", "available for order", false},
+	}
+	for _, tc := range cases {
+		if got := GoogleAvailability(tc.in); got != tc.google {
+			t.Fatalf("google %s: got %q", tc.in, got)
+		}
+		if got := MetaAvailability(tc.in); got != tc.meta {
+			t.Fatalf("meta %s: got %q", tc.in, got)
+		}
+		if got := YotpoInStock(tc.in); got != tc.yotpo {
+			t.Fatalf("yotpo %s: got %v", tc.in, got)
+		}
+	}
+
+	if GoogleCondition(ConditionRefurbished) != "refurbished" || MetaCondition(ConditionUsed) != "used" {
+		t.Fatalf("unexpected condition mapping")
+	}
+}
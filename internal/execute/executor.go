@@ -4,12 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/ETAnderson/conductor/internal/domain"
 	"github.com/ETAnderson/conductor/internal/ingest"
 	"github.com/ETAnderson/conductor/internal/state"
 )
 
+// ChannelProduct is an enqueued product as handed to one channel: the run result plus
+// its fields mapped into that channel's vocabulary (see domain.Product.ChannelAttributes).
+type ChannelProduct struct {
+	ingest.ProductProcessResult
+	Attributes domain.ChannelAttributes
+}
+
 type Executor struct {
 	Store state.Store
 
@@ -17,17 +25,30 @@ type Executor struct {
 	// If <= 0, defaults to 100000 (safe for now; optimize later).
 	ProductLimit int
 
+	// BatchSize controls how many enqueued products are handed to OnExecute per call.
+	// Cancellation is checked between batches. If <= 0, defaults to 500.
+	BatchSize int
+
+	// Channels are the channels products are pushed to. Each batch is handed to
+	// OnExecute once per channel with only the products that channel accepted (see
+	// ingest.ProductProcessResult.PushableTo). If empty, each batch is handed over once,
+	// unfiltered, with channel "".
+	Channels []string
+
 	// OnExecute is a test seam + future channel orchestration hook.
-	// It receives the run record plus ONLY the enqueued products for this run.
+	// It is called per batch and channel with the run record plus ONLY enqueued products
+	// pushable to that channel, urgent changes first (see
+	// ingest.ProductProcessResult.Urgent). Each product carries the attributes mapped for
+	// that channel; products without a loaded document carry none.
 	// If nil, execution is a no-op (but still validates tenant/run ownership).
-	OnExecute func(ctx context.Context, run state.RunRecord, enqueued []ingest.ProductProcessResult) error
+	OnExecute func(ctx context.Context, run state.RunRecord, channel string, enqueued []ChannelProduct) error
 }
 
 var ErrRunNotFound = errors.New("run not found")
 
 // Execute implements worker.RunExecutor.
-// It validates run ownership, loads run products, filters to enqueued items,
-// and invokes the orchestration hook (OnExecute).
+// It validates run ownership, loads enqueued products for the run (and any runs
+// it superseded), and invokes the orchestration hook (OnExecute).
 func (e Executor) Execute(ctx context.Context, runID string, tenantID uint64) error {
 	if e.Store == nil {
 		return errors.New("store is nil")
@@ -52,23 +73,168 @@ func (e Executor) Execute(ctx context.Context, runID string, tenantID uint64) er
 		return ErrRunNotFound
 	}
 
-	products, err := e.Store.ListRunProducts(ctx, runID, limit)
+	// Runs coalesced into this one are replayed oldest first, so the newest hash
+	// per product_key wins and each product is pushed once.
+	superseded, err := e.Store.ListSupersededRuns(ctx, tenantID, runID)
 	if err != nil {
-		return fmt.Errorf("list run products failed: %w", err)
+		return fmt.Errorf("list superseded runs failed: %w", err)
 	}
 
-	enqueued := make([]ingest.ProductProcessResult, 0, len(products))
-	for _, p := range products {
-		if p.Disposition == domain.ProductDispositionEnqueued {
+	runIDs := make([]string, 0, len(superseded)+1)
+	for _, r := range superseded {
+		runIDs = append(runIDs, r.RunID)
+	}
+	runIDs = append(runIDs, runID)
+
+	enqueued := make([]ingest.ProductProcessResult, 0, 64)
+	index := make(map[string]int)
+
+	for _, id := range runIDs {
+		products, err := e.Store.ListRunProducts(ctx, id, state.RunProductFilter{
+			Disposition: string(domain.ProductDispositionEnqueued),
+		}, limit)
+		if err != nil {
+			return fmt.Errorf("list run products failed: %w", err)
+		}
+
+		for _, p := range products {
+			if p.Disposition != domain.ProductDispositionEnqueued {
+				continue
+			}
+			if i, ok := index[p.ProductKey]; ok {
+				enqueued[i] = p
+				continue
+			}
+			index[p.ProductKey] = len(enqueued)
 			enqueued = append(enqueued, p)
 		}
 	}
 
-	if e.OnExecute != nil {
-		if err := e.OnExecute(ctx, run, enqueued); err != nil {
+	// Urgent (lifecycle, price/inventory) changes go first; otherwise keep run order.
+	sort.SliceStable(enqueued, func(i, j int) bool {
+		return enqueued[i].Urgent() && !enqueued[j].Urgent()
+	})
+
+	if run.CancelRequested {
+		return state.ErrRunCancelled
+	}
+
+	if e.OnExecute == nil {
+		return nil
+	}
+
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	if len(enqueued) == 0 {
+		return e.executeBatch(ctx, run, enqueued)
+	}
+
+	for start := 0; start < len(enqueued); start += batchSize {
+		if start > 0 {
+			if err := e.checkCancelled(ctx, tenantID, runID); err != nil {
+				return err
+			}
+		}
+
+		end := start + batchSize
+		if end > len(enqueued) {
+			end = len(enqueued)
+		}
+
+		if err := e.executeBatch(ctx, run, enqueued[start:end]); err != nil {
+			return err
+		}
+
+		err := e.Store.AppendRunEvent(ctx, state.RunEvent{
+			RunID:    runID,
+			TenantID: tenantID,
+			Type:     domain.RunEventBatchExecuted,
+			Details: map[string]any{
+				"batch":    start / batchSize,
+				"products": end - start,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("append run event failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// executeBatch hands batch to OnExecute for each channel, without the products that
+// channel rejected and with the rest mapped into its vocabulary. A channel left with
+// nothing to push in this batch is skipped, unless the whole run has nothing to push.
+func (e Executor) executeBatch(ctx context.Context, run state.RunRecord, batch []ingest.ProductProcessResult) error {
+	if len(e.Channels) == 0 {
+		return e.OnExecute(ctx, run, "", channelProducts(batch, ""))
+	}
+
+	for _, ch := range e.Channels {
+		pushable := make([]ingest.ProductProcessResult, 0, len(batch))
+		for _, p := range batch {
+			if p.PushableTo(ch) {
+				pushable = append(pushable, p)
+			}
+		}
+		if len(pushable) == 0 && len(batch) > 0 {
+			continue
+		}
+
+		if err := e.OnExecute(ctx, run, ch, channelProducts(pushable, ch)); err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+func channelProducts(batch []ingest.ProductProcessResult, channel string) []ChannelProduct {
+	out := make([]ChannelProduct, 0, len(batch))
+	for _, p := range batch {
+		cp := ChannelProduct{ProductProcessResult: p}
+		if p.Product != nil {
+			cp.Attributes = p.Product.ChannelAttributes(channel)
+		}
+		out = append(out, cp)
+	}
+	return out
+}
+
+// RecordChannelMilestone adds a channel milestone (e.g. "submitted", "live") to the run's
+// timeline. Channel integrations call it from OnExecute or from later status polling.
+func (e Executor) RecordChannelMilestone(ctx context.Context, run state.RunRecord, channel string, milestone string, details map[string]any) error {
+	if channel == "" || milestone == "" {
+		return errors.New("channel and milestone are required")
+	}
+
+	d := map[string]any{"milestone": milestone}
+	for k, v := range details {
+		d[k] = v
+	}
 
+	return e.Store.AppendRunEvent(ctx, state.RunEvent{
+		RunID:    run.RunID,
+		TenantID: run.TenantID,
+		Type:     domain.RunEventChannelMilestone,
+		Channel:  channel,
+		Details:  d,
+	})
+}
+
+// checkCancelled re-reads the run so cancellation requested mid-run is honoured.
+func (e Executor) checkCancelled(ctx context.Context, tenantID uint64, runID string) error {
+	run, ok, err := e.Store.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return fmt.Errorf("get run failed: %w", err)
+	}
+	if !ok {
+		return ErrRunNotFound
+	}
+	if run.CancelRequested {
+		return state.ErrRunCancelled
+	}
 	return nil
 }
@@ -47,13 +47,13 @@ func TestExecutor_Execute_LoadsRunAndFiltersEnqueued(t *testing.T) {
 	})
 
 	var gotRun state.RunRecord
-	var gotEnqueued []ingest.ProductProcessResult
+	var gotEnqueued []ChannelProduct
 
 	ex := Executor{
 		Store: st,
-		OnExecute: func(ctx context.Context, run state.RunRecord, enq []ingest.ProductProcessResult) error {
+		OnExecute: func(ctx context.Context, run state.RunRecord, channel string, enq []ChannelProduct) error {
 			gotRun = run
-			gotEnqueued = append([]ingest.ProductProcessResult(nil), enq...)
+			gotEnqueued = append([]ChannelProduct(nil), enq...)
 			return nil
 		},
 	}
@@ -116,7 +116,7 @@ func TestExecutor_Execute_PropagatesHookError(t *testing.T) {
 
 	ex := Executor{
 		Store: st,
-		OnExecute: func(ctx context.Context, run state.RunRecord, enq []ingest.ProductProcessResult) error {
+		OnExecute: func(ctx context.Context, run state.RunRecord, channel string, enq []ChannelProduct) error {
 			return want
 		},
 	}
@@ -126,3 +126,251 @@ func TestExecutor_Execute_PropagatesHookError(t *testing.T) {
 		t.Fatalf("expected %v got %v", want, err)
 	}
 }
+
+func TestExecutor_Execute_OrdersUrgentChangesFirst(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	runID := "run_exec_urgent_1"
+	tenantID := uint64(1)
+
+	_ = st.InsertRun(context.Background(), state.RunRecord{
+		RunID:         runID,
+		TenantID:      tenantID,
+		Status:        "processing",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	})
+
+	_ = st.InsertRunProducts(context.Background(), runID, []ingest.ProductProcessResult{
+		{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued, ChangeClasses: []domain.ChangeClass{domain.ChangeClassContent}},
+		{ProductKey: "sku2", Disposition: domain.ProductDispositionEnqueued, ChangeClasses: []domain.ChangeClass{domain.ChangeClassPriceInventory}},
+		{ProductKey: "sku3", Disposition: domain.ProductDispositionEnqueued, Reason: "new_product"},
+	})
+
+	var got []string
+	ex := Executor{
+		Store: st,
+		OnExecute: func(ctx context.Context, run state.RunRecord, channel string, enq []ChannelProduct) error {
+			for _, p := range enq {
+				got = append(got, p.ProductKey)
+			}
+			return nil
+		},
+	}
+
+	if err := ex.Execute(context.Background(), runID, tenantID); err != nil {
+		t.Fatalf("Execute returned err: %v", err)
+	}
+
+	if len(got) != 3 || got[0] != "sku2" || got[1] != "sku1" || got[2] != "sku3" {
+		t.Fatalf("expected [sku2 sku1 sku3], got %v", got)
+	}
+}
+
+func TestExecutor_Execute_MergesSupersededRunsLatestWins(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+	tenantID := uint64(1)
+	now := time.Now().UTC()
+
+	for i, id := range []string{"run_old", "run_mid", "run_new"} {
+		_ = st.InsertRun(ctx, state.RunRecord{
+			RunID:         id,
+			TenantID:      tenantID,
+			Status:        "has_changes",
+			PushTriggered: true,
+			CreatedAt:     now.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	_ = st.InsertRunProducts(ctx, "run_old", []ingest.ProductProcessResult{
+		{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued, Hash: "h1"},
+		{ProductKey: "sku2", Disposition: domain.ProductDispositionEnqueued, Hash: "a1"},
+	})
+	_ = st.InsertRunProducts(ctx, "run_mid", []ingest.ProductProcessResult{
+		{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued, Hash: "h2"},
+	})
+	_ = st.InsertRunProducts(ctx, "run_new", []ingest.ProductProcessResult{
+		{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued, Hash: "h3"},
+		{ProductKey: "sku2", Disposition: domain.ProductDispositionUnchanged, Hash: "a1"},
+	})
+
+	claims, err := st.ClaimRuns(ctx, time.Now(), time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimRuns: %v", err)
+	}
+	if len(claims) != 1 || claims[0].RunID != "run_new" {
+		t.Fatalf("expected only run_new claimed, got %+v", claims)
+	}
+
+	for _, id := range []string{"run_old", "run_mid"} {
+		rec, _, _ := st.GetRun(ctx, tenantID, id)
+		if rec.Status != string(domain.RunStatusSuperseded) || rec.SupersededBy != "run_new" {
+			t.Fatalf("expected %s superseded by run_new, got status=%s by=%s", id, rec.Status, rec.SupersededBy)
+		}
+	}
+
+	got := map[string]string{}
+	ex := Executor{
+		Store: st,
+		OnExecute: func(ctx context.Context, run state.RunRecord, channel string, enq []ChannelProduct) error {
+			for _, p := range enq {
+				if _, dup := got[p.ProductKey]; dup {
+					t.Fatalf("product %s pushed twice", p.ProductKey)
+				}
+				got[p.ProductKey] = p.Hash
+			}
+			return nil
+		},
+	}
+
+	if err := ex.Execute(ctx, "run_new", tenantID); err != nil {
+		t.Fatalf("Execute returned err: %v", err)
+	}
+
+	if len(got) != 2 || got["sku1"] != "h3" || got["sku2"] != "a1" {
+		t.Fatalf("expected sku1=h3 sku2=a1, got %v", got)
+	}
+}
+
+func TestExecutor_Execute_StopsBetweenBatchesWhenCancelled(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+
+	runID := "run_exec_cancel_1"
+	tenantID := uint64(1)
+
+	_ = st.InsertRun(ctx, state.RunRecord{
+		RunID:         runID,
+		TenantID:      tenantID,
+		Status:        "processing",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	})
+
+	_ = st.InsertRunProducts(ctx, runID, []ingest.ProductProcessResult{
+		{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued},
+		{ProductKey: "sku2", Disposition: domain.ProductDispositionEnqueued},
+		{ProductKey: "sku3", Disposition: domain.ProductDispositionEnqueued},
+	})
+
+	batches := 0
+	ex := Executor{
+		Store:     st,
+		BatchSize: 2,
+		OnExecute: func(ctx context.Context, run state.RunRecord, channel string, enq []ChannelProduct) error {
+			batches++
+			_, err := st.CancelRun(ctx, tenantID, runID)
+			return err
+		},
+	}
+
+	err := ex.Execute(ctx, runID, tenantID)
+	if !errors.Is(err, state.ErrRunCancelled) {
+		t.Fatalf("expected ErrRunCancelled, got %v", err)
+	}
+	if batches != 1 {
+		t.Fatalf("expected 1 batch before cancellation, got %d", batches)
+	}
+}
+
+func TestExecutor_Execute_PushesEachChannelOnlyWhatItAccepted(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+
+	_ = st.InsertRun(ctx, state.RunRecord{
+		RunID:         "run_channels",
+		TenantID:      1,
+		Status:        "processing",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	})
+
+	valid := ingest.ChannelResult{Status: ingest.ChannelStatusValid}
+	rejected := ingest.ChannelResult{Status: ingest.ChannelStatusRejected}
+	_ = st.InsertRunProducts(ctx, "run_channels", []ingest.ProductProcessResult{
+		{ProductKey: "both", Disposition: domain.ProductDispositionEnqueued, Channels: map[string]ingest.ChannelResult{"google": valid, "meta": valid}},
+		{ProductKey: "google_only", Disposition: domain.ProductDispositionEnqueued, Channels: map[string]ingest.ChannelResult{"google": valid, "meta": rejected}},
+		// Recorded before per-channel validation: pushable everywhere
+		{ProductKey: "legacy", Disposition: domain.ProductDispositionEnqueued},
+	})
+
+	got := make(map[string][]string)
+	ex := Executor{
+		Store:    st,
+		Channels: []string{"google", "meta"},
+		OnExecute: func(ctx context.Context, run state.RunRecord, channel string, enq []ChannelProduct) error {
+			for _, p := range enq {
+				got[channel] = append(got[channel], p.ProductKey)
+			}
+			return nil
+		},
+	}
+
+	if err := ex.Execute(ctx, "run_channels", 1); err != nil {
+		t.Fatalf("Execute returned err: %v", err)
+	}
+
+	if len(got["google"]) != 3 {
+		t.Fatalf("expected every product pushed to google, got %v", got["google"])
+	}
+	if len(got["meta"]) != 2 || got["meta"][0] != "both" || got["meta"][1] != "legacy" {
+		t.Fatalf("expected meta to skip the product it rejected, got %v", got["meta"])
+	}
+}
+
+func TestExecutor_Execute_MapsAttributesPerChannel(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+
+	_ = st.InsertRun(ctx, state.RunRecord{
+		RunID:         "run_vocab",
+		TenantID:      1,
+		Status:        "processing",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	})
+
+	_ = st.InsertRunProducts(ctx, "run_vocab", []ingest.ProductProcessResult{
+		{
+			ProductKey:  "sku1",
+			Disposition: domain.ProductDispositionEnqueued,
+			Product:     &domain.Product{ProductKey: "sku1", Condition: domain.ConditionRefurbished, Availability: domain.AvailabilityBackorder},
+		},
+		{
+			ProductKey:  "sku2",
+			Disposition: domain.ProductDispositionEnqueued,
+			Product:     &domain.Product{ProductKey: "sku2", Condition: domain.ConditionNew, Availability: domain.AvailabilityInStock},
+		},
+	})
+
+	got := make(map[string][]domain.ChannelAttributes)
+	ex := Executor{
+		Store:    st,
+		Channels: []string{"google", "meta", "yotpo"},
+		OnExecute: func(ctx context.Context, run state.RunRecord, channel string, enq []ChannelProduct) error {
+			for _, p := range enq {
+				got[channel] = append(got[channel], p.Attributes)
+			}
+			return nil
+		},
+	}
+
+	if err := ex.Execute(ctx, "run_vocab", 1); err != nil {
+		t.Fatalf("Execute returned err: %v", err)
+	}
+
+	if g := got["google"]; len(g) != 2 || g[0].Availability != "backorder" || g[0].Condition != "refurbished" || g[1].Availability != "in_stock" {
+		t.Fatalf("unexpected google attributes: %+v", g)
+	}
+	if m := got["meta"]; len(m) != 2 || m[0].Availability != "available for order" || m[1].Availability != "in stock" || m[1].Condition != "new" {
+		t.Fatalf("unexpected meta attributes: %+v", m)
+	}
+	y := got["yotpo"]
+	if len(y) != 2 || y[0].InStock == nil || *y[0].InStock || y[1].InStock == nil || !*y[1].InStock {
+		t.Fatalf("unexpected yotpo attributes: %+v", y)
+	}
+	if y[0].Availability != "" || y[0].Condition != "" {
+		t.Fatalf("expected yotpo to carry only in_stock, got %+v", y[0])
+	}
+}
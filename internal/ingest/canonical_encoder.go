@@ -0,0 +1,319 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"hash"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+// canonicalEncoder writes json.Marshal(normalizeForHash(p)) straight into a sha256
+// hash: fields in the fixed (sorted) key order encoding/json uses for maps, strings
+// escaped exactly as encoding/json escapes them. Stored hashes depend on these bytes
+// never changing; the fuzz tests compare against the reference form.
+//
+// Encoders are pooled, so hashing a product only allocates for unusual attribute
+// values (which fall back to encoding/json) and the hex result.
+type canonicalEncoder struct {
+	h   hash.Hash
+	buf []byte
+	sum [sha256.Size]byte
+
+	// scratch for sorting map keys and unsorted image links
+	keys  []string
+	links []string
+}
+
+const canonicalFlushAt = 4096
+
+var encoderPool = sync.Pool{
+	New: func() any {
+		return &canonicalEncoder{h: sha256.New(), buf: make([]byte, 0, 2*canonicalFlushAt)}
+	},
+}
+
+func (e *canonicalEncoder) reset() {
+	e.h.Reset()
+	e.buf = e.buf[:0]
+}
+
+func (e *canonicalEncoder) flush() {
+	if len(e.buf) > 0 {
+		e.h.Write(e.buf)
+		e.buf = e.buf[:0]
+	}
+}
+
+func (e *canonicalEncoder) maybeFlush() {
+	if len(e.buf) >= canonicalFlushAt {
+		e.flush()
+	}
+}
+
+func (e *canonicalEncoder) raw(s string) {
+	e.buf = append(e.buf, s...)
+}
+
+// field writes `"name":"value"` preceded by sep (',' or '{').
+func (e *canonicalEncoder) field(sep byte, name, value string) {
+	e.buf = append(e.buf, sep, '"')
+	e.buf = append(e.buf, name...)
+	e.buf = append(e.buf, '"', ':')
+	e.str(value)
+}
+
+func (e *canonicalEncoder) encodeProduct(p domain.Product) error {
+	// Keys in sorted order, matching encoding/json's map output.
+	e.raw(`{"additional_image_links":[`)
+	links := p.AdditionalImageLinks
+	if !slices.IsSorted(links) {
+		e.links = append(e.links[:0], links...)
+		slices.Sort(e.links)
+		links = e.links
+	}
+	for i, l := range links {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		e.str(l)
+	}
+	clear(e.links)
+	e.links = e.links[:0]
+
+	e.raw(`],"attributes":`)
+	if err := e.attributes(p.Attributes); err != nil {
+		return err
+	}
+
+	e.field(',', "availability", string(p.Availability))
+	e.field(',', "brand", p.Brand)
+
+	e.raw(`,"channel":{`)
+	sep := byte(0)
+	if p.Channel.Google != nil {
+		e.channel(&sep, "google", p.Channel.Google.Control.State)
+	}
+	if p.Channel.Meta != nil {
+		e.channel(&sep, "meta", p.Channel.Meta.Control.State)
+	}
+	if p.Channel.Yotpo != nil {
+		e.channel(&sep, "yotpo", p.Channel.Yotpo.Control.State)
+	}
+	e.buf = append(e.buf, '}')
+
+	e.field(',', "condition", string(p.Condition))
+	e.field(',', "description", p.Description)
+	e.field(',', "group_key", p.GroupKey)
+	e.field(',', "gtin", p.GTIN)
+	e.field(',', "image_link", p.ImageLink)
+	e.field(',', "link", p.Link)
+	e.field(',', "mpn", p.MPN)
+
+	e.raw(`,"options":`)
+	e.options(p.Options)
+
+	e.raw(`,"price":`)
+	e.money(p.Price)
+
+	e.field(',', "product_key", p.ProductKey)
+
+	e.raw(`,"sale_price":`)
+	if p.SalePrice == nil {
+		e.raw("null")
+	} else {
+		e.money(*p.SalePrice)
+	}
+
+	e.field(',', "title", p.Title)
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+func (e *canonicalEncoder) channel(sep *byte, name string, state domain.ChannelLifecycleState) {
+	if *sep != 0 {
+		e.buf = append(e.buf, *sep)
+	}
+	*sep = ','
+
+	e.str(name)
+	e.raw(`:{"control":{"state":`)
+	e.str(string(state))
+	e.raw(`}}`)
+}
+
+func (e *canonicalEncoder) money(m domain.Money) {
+	e.field('{', "amount_decimal", m.AmountDecimal)
+	e.field(',', "currency", m.Currency)
+	e.buf = append(e.buf, '}')
+}
+
+// options encodes sortedStringMap: [{"k":...,"v":...}] in key order.
+func (e *canonicalEncoder) options(m map[string]string) {
+	keys := appendSortedKeys(e.keys[:0], m)
+	e.keys = keys
+
+	e.buf = append(e.buf, '[')
+	for i, k := range keys {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		e.field('{', "k", k)
+		e.field(',', "v", m[k])
+		e.buf = append(e.buf, '}')
+	}
+	e.buf = append(e.buf, ']')
+	e.releaseKeys()
+}
+
+// attributes encodes sortedAnyMap: [{"k":...,"v":...}] in key order.
+func (e *canonicalEncoder) attributes(m map[string]any) error {
+	keys := appendSortedKeys(e.keys[:0], m)
+	e.keys = keys
+
+	e.buf = append(e.buf, '[')
+	for i, k := range keys {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		e.field('{', "k", k)
+		e.raw(`,"v":`)
+		if err := e.value(m[k]); err != nil {
+			e.releaseKeys()
+			return err
+		}
+		e.buf = append(e.buf, '}')
+	}
+	e.buf = append(e.buf, ']')
+	e.releaseKeys()
+	return nil
+}
+
+func appendSortedKeys[V any](dst []string, m map[string]V) []string {
+	for k := range m {
+		dst = append(dst, k)
+	}
+	slices.Sort(dst)
+	return dst
+}
+
+func (e *canonicalEncoder) releaseKeys() {
+	clear(e.keys)
+	e.keys = e.keys[:0]
+}
+
+// value encodes an attribute value. The types JSON decoding produces are written
+// directly; anything else goes through encoding/json.
+func (e *canonicalEncoder) value(v any) error {
+	switch x := v.(type) {
+	case nil:
+		e.raw("null")
+	case string:
+		e.str(x)
+	case bool:
+		e.buf = strconv.AppendBool(e.buf, x)
+	case int:
+		e.buf = strconv.AppendInt(e.buf, int64(x), 10)
+	case int64:
+		e.buf = strconv.AppendInt(e.buf, x, 10)
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return e.marshal(v)
+		}
+		e.float(x)
+	default:
+		return e.marshal(v)
+	}
+	return nil
+}
+
+func (e *canonicalEncoder) marshal(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.buf = append(e.buf, b...)
+	return nil
+}
+
+// float formats like encoding/json: ES6 number-to-string, i.e. %f unless the
+// exponent is out of range, with exponents not padded to two digits.
+func (e *canonicalEncoder) float(f float64) {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+
+	e.buf = strconv.AppendFloat(e.buf, f, format, -1, 64)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(e.buf)
+		if n >= 4 && e.buf[n-4] == 'e' && e.buf[n-3] == '-' && e.buf[n-2] == '0' {
+			e.buf[n-2] = e.buf[n-1]
+			e.buf = e.buf[:n-1]
+		}
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// str writes s as a JSON string escaped exactly like encoding/json with HTML
+// escaping on: <, > and & become \u003c etc., invalid UTF-8 becomes U+FFFD and
+// U+2028/U+2029 are escaped.
+func (e *canonicalEncoder) str(s string) {
+	b := append(e.buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '\\', '"':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	e.buf = append(b, '"')
+	e.maybeFlush()
+}
@@ -0,0 +1,175 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+// referenceHash is the original HashNormalized: sha256 of json.Marshal(normalizeForHash(p)).
+func referenceHash(t testing.TB, p domain.Product) (string, error) {
+	t.Helper()
+
+	b, err := json.Marshal(normalizeForHash(p))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// assertMatchesReference checks every hash version against the reference encoding of
+// the product it hashes: v1 the product as is, later versions the product normalized
+// by their rules.
+func assertMatchesReference(t testing.TB, p domain.Product) {
+	t.Helper()
+
+	inputs := map[int]domain.Product{
+		HashVersionV1: p,
+		HashVersionV2: normalizeProduct(p, HashVersionV2),
+		HashVersionV3: NormalizeProduct(p),
+	}
+	for version, in := range inputs {
+		want, wantErr := referenceHash(t, in)
+		got, err := Hasher{Version: version}.HashNormalized(p)
+		if (err != nil) != (wantErr != nil) {
+			t.Fatalf("v%d: error mismatch: reference %v, canonical %v", version, wantErr, err)
+		}
+		if got != want {
+			t.Fatalf("v%d: hash mismatch for %+v:\n reference %s\n canonical %s", version, p, want, got)
+		}
+	}
+}
+
+func TestCanonicalEncoder_MatchesReference(t *testing.T) {
+	sale := domain.Money{AmountDecimal: "9.99", Currency: "USD"}
+
+	cases := map[string]func(p *domain.Product){
+		"base":    func(p *domain.Product) {},
+		"minimal": func(p *domain.Product) { *p = domain.Product{ProductKey: "k"} },
+		"sale_price": func(p *domain.Product) {
+			p.SalePrice = &sale
+		},
+		"all_channels": func(p *domain.Product) {
+			p.Channel.Meta = &domain.MetaFields{Control: domain.ChannelControl{State: domain.ChannelStateInactive}}
+			p.Channel.Yotpo = &domain.YotpoFields{Control: domain.ChannelControl{State: domain.ChannelStateDelete}}
+		},
+		"meta_only": func(p *domain.Product) {
+			p.Channel = domain.ChannelFields{Meta: &domain.MetaFields{}}
+		},
+		"unsorted_links": func(p *domain.Product) {
+			p.AdditionalImageLinks = []string{"z", "a", "m", "a"}
+		},
+		"escaping": func(p *domain.Product) {
+			p.Title = "Tom & Jerry <b>\"quoted\"</b> \\ \n\r\t\b\f\x00\x1f\x7f"
+			p.Description = "line\u2028sep\u2029 caf\u00e9 \U0001F600 bad\xff\xfe utf8"
+		},
+		"attribute_values": func(p *domain.Product) {
+			p.Attributes = map[string]any{
+				"nil":    nil,
+				"bool":   true,
+				"int":    -42,
+				"int64":  int64(1) << 60,
+				"float":  12.5,
+				"tiny":   1e-7,
+				"huge":   1e21,
+				"negexp": -2.5e-10,
+				"whole":  float64(3),
+				"list":   []any{"a", 1.5, map[string]any{"z": 1, "a": "<"}},
+				"obj":    map[string]any{"b": false, "a": nil},
+				"uint8":  uint8(7),
+				"number": json.Number("10.50"),
+			}
+		},
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := baseProductForHash()
+			mutate(&p)
+			assertMatchesReference(t, p)
+		})
+	}
+}
+
+func TestCanonicalEncoder_UnsupportedAttributeValueErrors(t *testing.T) {
+	p := baseProductForHash()
+	p.Attributes = map[string]any{"bad": math.NaN()}
+	assertMatchesReference(t, p)
+
+	p.Attributes = map[string]any{"bad": func() {}}
+	if _, err := (Hasher{}).HashNormalized(p); err == nil {
+		t.Fatalf("expected error for unsupported attribute value")
+	}
+}
+
+func TestCanonicalEncoder_DoesNotAllocatePerField(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are unreliable under the race detector")
+	}
+
+	p := baseProductForHash()
+	h := Hasher{}
+
+	// Only the hex result should allocate once the encoder pool is warm.
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = h.HashNormalized(p)
+	})
+	if allocs > 1 {
+		t.Fatalf("expected at most 1 allocation per hash, got %.1f", allocs)
+	}
+}
+
+func FuzzHashNormalized_MatchesReference(f *testing.F) {
+	f.Add("sku1", "Title", "https://example.com/a", "size", "M", "material", "cotton", 12.5, true)
+	f.Add("", "<&>\u2028", "\xff", "", "", "k", "\x00", 1e-7, false)
+	f.Add("k\"", "a\\b", "z", "b", "a", "", "", -0.0, true)
+
+	f.Fuzz(func(t *testing.T, key, title, link, optK, optV, attrK, attrV string, num float64, withSale bool) {
+		p := domain.Product{
+			ProductKey:           key,
+			Title:                title,
+			Link:                 link,
+			AdditionalImageLinks: []string{link, title, key},
+			Options:              map[string]string{optK: optV, optV: optK},
+			Attributes:           map[string]any{attrK: attrV, attrV: num, "n": nil},
+			Price:                domain.Money{AmountDecimal: optV, Currency: attrV},
+			Channel: domain.ChannelFields{
+				Google: &domain.GoogleFields{Control: domain.ChannelControl{State: domain.ChannelLifecycleState(optK)}},
+			},
+		}
+		if withSale {
+			p.SalePrice = &domain.Money{AmountDecimal: title, Currency: key}
+			p.Channel.Yotpo = &domain.YotpoFields{}
+		}
+
+		assertMatchesReference(t, p)
+	})
+}
+
+func BenchmarkHashNormalized(b *testing.B) {
+	p := baseProductForHash()
+	h := Hasher{}
+
+	b.Run("canonical", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := h.HashNormalized(p); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reference", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := referenceHash(b, p); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package ingest
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+// ChannelProfile is what a channel checks on top of base validation. Length limits
+// count characters; zero means no limit.
+type ChannelProfile struct {
+	MaxTitleLength       int `json:"max_title_length,omitempty"`
+	MaxDescriptionLength int `json:"max_description_length,omitempty"`
+	MaxBrandLength       int `json:"max_brand_length,omitempty"`
+
+	// RequireBrand rejects products without a brand.
+	RequireBrand bool `json:"require_brand,omitempty"`
+
+	// RequireIdentifiers is the identifier-exists rule: brand plus a GTIN or MPN.
+	RequireIdentifiers bool `json:"require_identifiers,omitempty"`
+
+	MaxAdditionalImages int `json:"max_additional_images,omitempty"`
+
+	// RequireHTTPSImages rejects http:// image_link and additional_image_links.
+	RequireHTTPSImages bool `json:"require_https_images,omitempty"`
+}
+
+// DefaultChannelProfiles are the documented limits of each channel's product API.
+var DefaultChannelProfiles = map[string]ChannelProfile{
+	"google": {
+		MaxTitleLength:       150,
+		MaxDescriptionLength: 5000,
+		MaxBrandLength:       70,
+		MaxAdditionalImages:  10,
+	},
+	"meta": {
+		MaxTitleLength:       200,
+		MaxDescriptionLength: 9999,
+		MaxBrandLength:       100,
+		RequireBrand:         true,
+		MaxAdditionalImages:  20,
+	},
+	"yotpo": {},
+}
+
+// Channel profile issue codes (identifier and image codes are shared with base
+// validation).
+const (
+	IssueCodeTitleTooLong       = "title_too_long"
+	IssueCodeDescriptionTooLong = "description_too_long"
+	IssueCodeBrandTooLong       = "brand_too_long"
+	IssueCodeBrandRequired      = "brand_required"
+)
+
+// ChannelStatus is a product's validation outcome for one enabled channel.
+type ChannelStatus string
+
+const (
+	ChannelStatusValid    ChannelStatus = "valid"
+	ChannelStatusRejected ChannelStatus = "rejected"
+)
+
+// ChannelResult is a product's validation outcome for one enabled channel. Only
+// channels with ChannelStatusValid should receive the product.
+type ChannelResult struct {
+	Status ChannelStatus     `json:"status"`
+	Issues []ValidationIssue `json:"issues,omitempty"`
+}
+
+// ValidateChannels validates p separately for each enabled channel: the channel block
+// and control state, then the channel's profile under the validation policy.
+func ValidateChannels(p domain.Product, enabledChannels []string, policy ValidationPolicy) map[string]ValidationResult {
+	channels := normalizeChannels(enabledChannels)
+	out := make(map[string]ValidationResult, len(channels))
+
+	for _, ch := range channels {
+		var res ValidationResult
+		validateChannelControl(&res, p, ch)
+		if prof, ok := policy.ChannelProfile(ch); ok {
+			validateChannelProfile(&res, p, ch, prof)
+		}
+		out[ch] = res
+	}
+
+	return out
+}
+
+func validateChannelProfile(res *ValidationResult, p domain.Product, ch string, prof ChannelProfile) {
+	maxLength(res, "title", p.Title, prof.MaxTitleLength, IssueCodeTitleTooLong, ch)
+	maxLength(res, "description", p.Description, prof.MaxDescriptionLength, IssueCodeDescriptionTooLong, ch)
+	maxLength(res, "brand", p.Brand, prof.MaxBrandLength, IssueCodeBrandTooLong, ch)
+
+	brand := strings.TrimSpace(p.Brand)
+	if (prof.RequireBrand || prof.RequireIdentifiers) && brand == "" {
+		code := IssueCodeBrandRequired
+		if prof.RequireIdentifiers {
+			code = IssueCodeIdentifierBrandMissing
+		}
+		addIssue(res, "brand", code, "brand is required by "+ch)
+	}
+	if prof.RequireIdentifiers && strings.TrimSpace(p.GTIN) == "" && strings.TrimSpace(p.MPN) == "" {
+		addIssue(res, "gtin", IssueCodeIdentifierCodeMissing, "gtin or mpn is required by "+ch)
+	}
+
+	if prof.MaxAdditionalImages > 0 && len(p.AdditionalImageLinks) > prof.MaxAdditionalImages {
+		addIssue(res, "additional_image_links", IssueCodeTooManyAdditionalImages,
+			ch+" accepts at most "+strconv.Itoa(prof.MaxAdditionalImages)+" additional images")
+	}
+
+	if prof.RequireHTTPSImages {
+		requireHTTPS(res, "image_link", p.ImageLink, ch)
+		for i, l := range p.AdditionalImageLinks {
+			requireHTTPS(res, "additional_image_links["+strconv.Itoa(i)+"]", l, ch)
+		}
+	}
+}
+
+func maxLength(res *ValidationResult, path, v string, limit int, code, ch string) {
+	if limit > 0 && utf8.RuneCountInString(v) > limit {
+		addIssue(res, path, code, path+" must be at most "+strconv.Itoa(limit)+" characters for "+ch)
+	}
+}
+
+// requireHTTPS flags http:// links; malformed links are reported by base validation.
+func requireHTTPS(res *ValidationResult, path, raw, ch string) {
+	if u, err := url.Parse(raw); err == nil && strings.EqualFold(u.Scheme, "http") {
+		addIssue(res, path, IssueCodeInsecureURL, ch+" requires https image links")
+	}
+}
@@ -0,0 +1,90 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+func TestValidateChannels_Profiles(t *testing.T) {
+	p := validBaseProduct()
+	p.Channel.Meta = &domain.MetaFields{Control: domain.ChannelControl{State: domain.ChannelStateActive}}
+	p.Title = strings.Repeat("é", 151)
+
+	res := ValidateChannels(p, []string{"google", "meta"}, ValidationPolicy{})
+
+	google := res["google"].Issues
+	if len(google) != 1 || google[0].Path != "title" || google[0].Code != IssueCodeTitleTooLong {
+		t.Fatalf("expected google title_too_long, got %#v", google)
+	}
+
+	meta := res["meta"].Issues
+	if len(meta) != 1 || meta[0].Path != "brand" || meta[0].Code != IssueCodeBrandRequired {
+		t.Fatalf("expected meta brand_required, got %#v", meta)
+	}
+}
+
+func TestValidateChannels_PolicyProfiles(t *testing.T) {
+	p := validBaseProduct()
+	p.ImageLink = "http://cdn.example.net/a.jpg"
+	p.Description = strings.Repeat("d", 51)
+
+	policy := ValidationPolicy{Profiles: map[string]ChannelProfile{
+		"google": {MaxDescriptionLength: 50, RequireHTTPSImages: true},
+	}}
+
+	issues := ValidateChannels(p, []string{"google"}, policy)["google"].Issues
+	if len(issues) != 2 ||
+		issues[0].Code != IssueCodeDescriptionTooLong ||
+		issues[1].Path != "image_link" || issues[1].Code != IssueCodeInsecureURL {
+		t.Fatalf("expected the feed profile to replace the default, got %#v", issues)
+	}
+
+	if res := ValidateChannels(p, []string{"notarealchannel"}, policy); res["notarealchannel"].Issues[0].Code != "unknown_channel" {
+		t.Fatalf("expected unknown_channel, got %#v", res)
+	}
+}
+
+func TestProcessor_RecordsPerChannelRejections(t *testing.T) {
+	proc := NewProcessor()
+
+	// Valid for google; meta rejects it for the missing brand.
+	p := validProductForProcessor("sku1")
+	p.Channel.Meta = &domain.MetaFields{Control: domain.ChannelControl{State: domain.ChannelStateActive}}
+
+	out, err := proc.ProcessProducts([]domain.Product{p}, []string{"google", "meta"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Summary.Valid != 1 || out.Summary.Rejected != 0 {
+		t.Fatalf("expected the product to stay valid, got %+v", out.Summary)
+	}
+
+	res := out.Products[0]
+	if res.Disposition != domain.ProductDispositionEnqueued {
+		t.Fatalf("expected enqueued, got %s", res.Disposition)
+	}
+	if res.Channels["google"].Status != ChannelStatusValid || !res.PushableTo("google") {
+		t.Fatalf("expected google valid, got %+v", res.Channels)
+	}
+	meta := res.Channels["meta"]
+	if meta.Status != ChannelStatusRejected || len(meta.Issues) != 1 || meta.Issues[0].Code != IssueCodeBrandRequired || res.PushableTo("meta") {
+		t.Fatalf("expected meta rejected for brand, got %+v", meta)
+	}
+	if len(res.Issues) != 1 || res.Issues[0].Path != "channel.meta.brand" || res.Issues[0].Code != IssueCodeBrandRequired {
+		t.Fatalf("expected the meta rejection in issues under channel.meta, got %+v", res.Issues)
+	}
+
+	// Rejected by every enabled channel: the product is rejected.
+	out, err = proc.ProcessProducts([]domain.Product{p}, []string{"meta"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := out.Products[0]; got.Disposition != domain.ProductDispositionRejected || got.Reason != "channel_validation_failed" {
+		t.Fatalf("expected channel_validation_failed, got %s/%s", got.Disposition, got.Reason)
+	}
+	if got := out.Products[0].Issues; len(got) != 1 || got[0].Path != "channel.meta.brand" {
+		t.Fatalf("expected issues under channel.meta, got %+v", got)
+	}
+}
@@ -1,10 +1,17 @@
 package ingest
 
-import "github.com/ETAnderson/conductor/internal/domain"
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
 
 type DeltaDecision struct {
-	Disposition domain.ProductDisposition `json:"disposition"`
-	Reason      string                    `json:"reason"`
+	Disposition   domain.ProductDisposition `json:"disposition"`
+	Reason        string                    `json:"reason"`
+	ChangedFields []string                  `json:"changed_fields,omitempty"`
+	ChangeClasses []domain.ChangeClass      `json:"change_classes,omitempty"`
 }
 
 func ComputeDisposition(previousHash string, currentHash string) DeltaDecision {
@@ -30,3 +37,73 @@ func ComputeDisposition(previousHash string, currentHash string) DeltaDecision {
 		Reason:      "content_changed",
 	}
 }
+
+// ComputeProductDelta is ComputeDisposition plus the top-level fields that changed,
+// which can only be derived when the previous document is known.
+func ComputeProductDelta(prev PreviousState, cur domain.Product, currentHash string) DeltaDecision {
+	d := ComputeDisposition(prev.Hash, currentHash)
+
+	if d.Reason == "content_changed" && prev.Product != nil {
+		d.ChangedFields = changedTopLevelFields(*prev.Product, cur)
+		d.ChangeClasses = ClassifyChangedFields(d.ChangedFields)
+	}
+
+	return d
+}
+
+// changedTopLevelFields compares the hash envelopes field by field, so only
+// differences that affect the hash are reported (e.g. image order is ignored).
+func changedTopLevelFields(prev domain.Product, cur domain.Product) []string {
+	a, _ := normalizeForHash(prev).(map[string]any)
+	b, _ := normalizeForHash(cur).(map[string]any)
+
+	changed := make([]string, 0, 4)
+	for k, av := range a {
+		ab, errA := json.Marshal(av)
+		bb, errB := json.Marshal(b[k])
+		if errA != nil || errB != nil || string(ab) != string(bb) {
+			changed = append(changed, k)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
+
+// changeClassOrder is the priority order classes are reported in (most urgent first).
+var changeClassOrder = []domain.ChangeClass{
+	domain.ChangeClassLifecycle,
+	domain.ChangeClassPriceInventory,
+	domain.ChangeClassMedia,
+	domain.ChangeClassContent,
+}
+
+// ClassifyChangedFields maps top-level changed fields to change classes,
+// most urgent first. Unlisted fields count as content.
+func ClassifyChangedFields(fields []string) []domain.ChangeClass {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	hit := make(map[domain.ChangeClass]bool, len(changeClassOrder))
+	for _, f := range fields {
+		switch f {
+		case "channel":
+			hit[domain.ChangeClassLifecycle] = true
+		case "price", "sale_price", "availability":
+			hit[domain.ChangeClassPriceInventory] = true
+		case "image_link", "additional_image_links":
+			hit[domain.ChangeClassMedia] = true
+		default:
+			hit[domain.ChangeClassContent] = true
+		}
+	}
+
+	out := make([]domain.ChangeClass, 0, len(hit))
+	for _, c := range changeClassOrder {
+		if hit[c] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -38,3 +38,83 @@ func TestComputeDisposition_Changed(t *testing.T) {
 		t.Fatalf("expected content_changed, got %s", d.Reason)
 	}
 }
+
+func TestComputeProductDelta_RecordsChangedTopLevelFields(t *testing.T) {
+	prev := baseProductForHash()
+	cur := baseProductForHash()
+	cur.Title = "New Title"
+	cur.Price.AmountDecimal = "9.99"
+	// Reordered images do not count as a change
+	cur.AdditionalImageLinks = []string{"https://example.com/b.jpg", "https://example.com/a.jpg"}
+
+	d := ComputeProductDelta(PreviousState{Hash: "old", Product: &prev}, cur, "new")
+
+	if d.Reason != "content_changed" {
+		t.Fatalf("expected content_changed, got %s", d.Reason)
+	}
+	if len(d.ChangedFields) != 2 || d.ChangedFields[0] != "price" || d.ChangedFields[1] != "title" {
+		t.Fatalf("expected [price title], got %v", d.ChangedFields)
+	}
+}
+
+func TestComputeProductDelta_NoFieldsWithoutPreviousDocument(t *testing.T) {
+	d := ComputeProductDelta(PreviousState{Hash: "old"}, baseProductForHash(), "new")
+
+	if d.Disposition != domain.ProductDispositionEnqueued {
+		t.Fatalf("expected enqueued, got %s", d.Disposition)
+	}
+	if len(d.ChangedFields) != 0 {
+		t.Fatalf("expected no changed fields, got %v", d.ChangedFields)
+	}
+}
+
+func TestClassifyChangedFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		want   []domain.ChangeClass
+	}{
+		{"none", nil, nil},
+		{"price only", []string{"price"}, []domain.ChangeClass{domain.ChangeClassPriceInventory}},
+		{"availability and sale price", []string{"availability", "sale_price"}, []domain.ChangeClass{domain.ChangeClassPriceInventory}},
+		{"content", []string{"description", "title"}, []domain.ChangeClass{domain.ChangeClassContent}},
+		{"media", []string{"additional_image_links"}, []domain.ChangeClass{domain.ChangeClassMedia}},
+		{
+			"mixed is ordered by urgency",
+			[]string{"title", "image_link", "price", "channel"},
+			[]domain.ChangeClass{
+				domain.ChangeClassLifecycle,
+				domain.ChangeClassPriceInventory,
+				domain.ChangeClassMedia,
+				domain.ChangeClassContent,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyChangedFields(tt.fields)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeProductDelta_PriceChangeIsPriceInventoryOnly(t *testing.T) {
+	prev := baseProductForHash()
+	cur := baseProductForHash()
+	cur.Price.AmountDecimal = "9.99"
+
+	d := ComputeProductDelta(PreviousState{Hash: "old", Product: &prev}, cur, "new")
+	res := ProductProcessResult{ChangeClasses: d.ChangeClasses}
+
+	if !res.PriceInventoryOnly() || !res.Urgent() {
+		t.Fatalf("expected urgent price_inventory-only change, got %v", d.ChangeClasses)
+	}
+}
@@ -0,0 +1,123 @@
+package ingest
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+const (
+	FieldDiffAdded   = "added"
+	FieldDiffRemoved = "removed"
+	FieldDiffChanged = "changed"
+)
+
+// FieldDiff is a single leaf-level difference between two product documents.
+// Paths use dots for objects and [i] for arrays (e.g. "additional_image_links[1]").
+type FieldDiff struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
+
+// DiffProducts returns the field-level differences between two product documents,
+// ordered by path.
+func DiffProducts(from domain.Product, to domain.Product) ([]FieldDiff, error) {
+	a, err := toGenericJSON(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toGenericJSON(to)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]FieldDiff, 0, 8)
+	diffValues("", a, b, &out)
+	return out, nil
+}
+
+func toGenericJSON(p domain.Product) (any, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func diffValues(path string, a any, b any, out *[]FieldDiff) {
+	switch av := a.(type) {
+	case map[string]any:
+		if bv, ok := b.(map[string]any); ok {
+			diffObjects(path, av, bv, out)
+			return
+		}
+	case []any:
+		if bv, ok := b.([]any); ok {
+			diffArrays(path, av, bv, out)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*out = append(*out, FieldDiff{Path: path, Op: FieldDiffChanged, From: a, To: b})
+	}
+}
+
+func diffObjects(path string, a map[string]any, b map[string]any, out *[]FieldDiff) {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child := k
+		if path != "" {
+			child = path + "." + k
+		}
+
+		av, inA := a[k]
+		bv, inB := b[k]
+		switch {
+		case !inA:
+			*out = append(*out, FieldDiff{Path: child, Op: FieldDiffAdded, To: bv})
+		case !inB:
+			*out = append(*out, FieldDiff{Path: child, Op: FieldDiffRemoved, From: av})
+		default:
+			diffValues(child, av, bv, out)
+		}
+	}
+}
+
+func diffArrays(path string, a []any, b []any, out *[]FieldDiff) {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+
+	for i := 0; i < n; i++ {
+		child := path + "[" + strconv.Itoa(i) + "]"
+		switch {
+		case i >= len(a):
+			*out = append(*out, FieldDiff{Path: child, Op: FieldDiffAdded, To: b[i]})
+		case i >= len(b):
+			*out = append(*out, FieldDiff{Path: child, Op: FieldDiffRemoved, From: a[i]})
+		default:
+			diffValues(child, a[i], b[i], out)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+func TestDiffProducts_ReportsLeafChanges(t *testing.T) {
+	a := baseProductForHash()
+	b := baseProductForHash()
+
+	b.Price.AmountDecimal = "17.99"
+	b.AdditionalImageLinks = append(b.AdditionalImageLinks, "https://example.com/c.jpg")
+	b.Brand = "Acme"
+	b.Channel.Google.Control.State = domain.ChannelStateInactive
+
+	diffs, err := DiffProducts(a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []FieldDiff{
+		{Path: "additional_image_links[2]", Op: FieldDiffAdded, To: "https://example.com/c.jpg"},
+		{Path: "brand", Op: FieldDiffAdded, To: "Acme"},
+		{Path: "channel.google.control.state", Op: FieldDiffChanged, From: "active", To: "inactive"},
+		{Path: "price.amount_decimal", Op: FieldDiffChanged, From: "19.99", To: "17.99"},
+	}
+
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %d diffs, got %#v", len(want), diffs)
+	}
+	for i := range want {
+		if diffs[i] != want[i] {
+			t.Fatalf("diff %d: expected %#v, got %#v", i, want[i], diffs[i])
+		}
+	}
+}
+
+func TestDiffProducts_IdenticalIsEmpty(t *testing.T) {
+	diffs, err := DiffProducts(baseProductForHash(), baseProductForHash())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("expected no diffs, got %#v", diffs)
+	}
+}
@@ -0,0 +1,138 @@
+package ingest
+
+import (
+	"fmt"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+// DuplicatePolicy decides what happens when one batch contains the same product_key
+// more than once.
+type DuplicatePolicy string
+
+const (
+	// DuplicatePolicyRejectAll processes none of the occurrences; the key is reported
+	// once with disposition "duplicate". This is the default.
+	DuplicatePolicyRejectAll DuplicatePolicy = "reject_all"
+
+	// DuplicatePolicyLastWins processes only the last occurrence.
+	DuplicatePolicyLastWins DuplicatePolicy = "last_wins"
+
+	// DuplicatePolicyFirstWins processes only the first occurrence.
+	DuplicatePolicyFirstWins DuplicatePolicy = "first_wins"
+)
+
+const IssueCodeDuplicateProductKey = "duplicate_product_key"
+
+// ParseDuplicatePolicy accepts the policy names above; "" selects the default.
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch p := DuplicatePolicy(s); p {
+	case "":
+		return DuplicatePolicyRejectAll, nil
+	case DuplicatePolicyRejectAll, DuplicatePolicyLastWins, DuplicatePolicyFirstWins:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate policy %q", s)
+	}
+}
+
+// DuplicateResolution is the outcome of applying a DuplicatePolicy to a batch.
+// Positions are indexes into the keys passed to ResolveDuplicates.
+type DuplicateResolution struct {
+	policy DuplicatePolicy
+	keys   []string
+	counts map[string]int
+	keep   []bool
+	first  map[string]int
+}
+
+// ResolveDuplicates decides which batch positions are processed. Keys are compared
+// byte for byte, as the product_key columns do. Empty keys are never treated as
+// duplicates: base validation rejects them on their own and each result is recorded
+// under its KeylessProductKey.
+func ResolveDuplicates(keys []string, policy DuplicatePolicy) DuplicateResolution {
+	if policy == "" {
+		policy = DuplicatePolicyRejectAll
+	}
+
+	d := DuplicateResolution{
+		policy: policy,
+		keys:   keys,
+		counts: make(map[string]int),
+		keep:   make([]bool, len(keys)),
+		first:  make(map[string]int),
+	}
+
+	last := make(map[string]int)
+	for i, k := range keys {
+		if k == "" {
+			continue
+		}
+		d.counts[k]++
+		if _, ok := d.first[k]; !ok {
+			d.first[k] = i
+		}
+		last[k] = i
+	}
+
+	for i, k := range keys {
+		if k == "" || d.counts[k] == 1 {
+			d.keep[i] = true
+			continue
+		}
+
+		switch policy {
+		case DuplicatePolicyLastWins:
+			d.keep[i] = last[k] == i
+		case DuplicatePolicyFirstWins:
+			d.keep[i] = d.first[k] == i
+		}
+	}
+
+	return d
+}
+
+// Keep reports whether position i should be processed.
+func (d DuplicateResolution) Keep(i int) bool {
+	return d.keep[i]
+}
+
+// DuplicateResult returns the result recorded for a dropped position. Under reject_all
+// the key is reported once, at its first occurrence; otherwise the winning occurrence
+// carries the report (see Annotate) and ok is false.
+func (d DuplicateResolution) DuplicateResult(i int) (ProductProcessResult, bool) {
+	k := d.keys[i]
+	if d.keep[i] || d.policy != DuplicatePolicyRejectAll || d.first[k] != i {
+		return ProductProcessResult{}, false
+	}
+
+	return ProductProcessResult{
+		ProductKey:  k,
+		Disposition: domain.ProductDispositionDuplicate,
+		Reason:      IssueCodeDuplicateProductKey,
+		Issues:      []ValidationIssue{d.issue(k)},
+	}, true
+}
+
+// Annotate adds the duplicate issue to the result of a winning occurrence.
+func (d DuplicateResolution) Annotate(i int, res *ProductProcessResult) {
+	k := d.keys[i]
+	if k == "" || d.counts[k] < 2 {
+		return
+	}
+	res.Issues = append(res.Issues, d.issue(k))
+}
+
+func (d DuplicateResolution) issue(k string) ValidationIssue {
+	msg := fmt.Sprintf("product_key appears %d times in batch", d.counts[k])
+	switch d.policy {
+	case DuplicatePolicyLastWins:
+		msg += "; last occurrence kept"
+	case DuplicatePolicyFirstWins:
+		msg += "; first occurrence kept"
+	default:
+		msg += "; all occurrences rejected"
+	}
+
+	return ValidationIssue{Path: "product_key", Code: IssueCodeDuplicateProductKey, Message: msg}
+}
@@ -3,27 +3,107 @@ package ingest
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"sort"
 
 	"github.com/ETAnderson/conductor/internal/domain"
 )
 
-type Hasher struct{}
+// Hash versions. Any change to the canonical encoding changes every stored hash, so
+// it ships as a new version next to the old one: product_state records the version
+// each hash was computed with, and the processor compares against the stored hash
+// using that version (see Processor.Decide) until the state has been rewritten.
+//
+// v1 hashes the product as received; v2 hashes the normalized product, so values that
+// only differ in formatting (e.g. "19.90" and "19.9") hash the same; v3 also maps
+// condition and availability aliases to their canonical values.
+const (
+	HashVersionV1 = 1
+	HashVersionV2 = 2
+	HashVersionV3 = 3
+
+	CurrentHashVersion = HashVersionV3
+)
+
+// hashEncoder writes a hash version's canonical encoding of p.
+type hashEncoder func(e *canonicalEncoder, p domain.Product) error
+
+// hashEncoders maps each supported hash version to its canonical encoding.
+var hashEncoders = map[int]hashEncoder{
+	HashVersionV1: (*canonicalEncoder).encodeProduct,
+	HashVersionV2: func(e *canonicalEncoder, p domain.Product) error {
+		return e.encodeProduct(normalizeProduct(p, HashVersionV2))
+	},
+	HashVersionV3: func(e *canonicalEncoder, p domain.Product) error {
+		return e.encodeProduct(normalizeProduct(p, HashVersionV3))
+	},
+}
+
+var ErrUnsupportedHashVersion = errors.New("unsupported hash version")
+
+// Hasher computes normalized product hashes. Version selects the hash version new
+// hashes are computed with; zero means CurrentHashVersion.
+type Hasher struct {
+	Version int
+
+	// encoders replaces hashEncoders as the supported versions when set (tests).
+	encoders map[int]hashEncoder
+}
+
+// CurrentVersion is the hash version HashNormalized uses.
+func (h Hasher) CurrentVersion() int {
+	if h.Version <= 0 {
+		return CurrentHashVersion
+	}
+	return h.Version
+}
 
+// HashNormalized hashes p with the Hasher's version.
 func (h Hasher) HashNormalized(p domain.Product) (string, error) {
-	n := normalizeForHash(p)
+	return h.HashWithVersion(p, h.CurrentVersion())
+}
+
+// HashWithVersion is the sha256 of the version's canonical encoding of p. For v1 that
+// is json.Marshal(normalizeForHash(p)), computed by streaming the encoding (see
+// canonical_encoder.go) instead of building it. Zero means HashVersionV1, which is
+// what hashes stored before versioning used.
+func (h Hasher) HashWithVersion(p domain.Product, version int) (string, error) {
+	version = normalizeHashVersion(version)
+	encoders := h.encoders
+	if encoders == nil {
+		encoders = hashEncoders
+	}
+	encode, ok := encoders[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnsupportedHashVersion, version)
+	}
 
-	b, err := json.Marshal(n)
-	if err != nil {
+	e := encoderPool.Get().(*canonicalEncoder)
+	defer encoderPool.Put(e)
+
+	e.reset()
+	if err := encode(e, p); err != nil {
 		return "", err
 	}
+	e.flush()
+
+	var out [2 * sha256.Size]byte
+	hex.Encode(out[:], e.h.Sum(e.sum[:0]))
+	return string(out[:]), nil
+}
 
-	sum := sha256.Sum256(b)
-	return hex.EncodeToString(sum[:]), nil
+// normalizeHashVersion maps the zero version of pre-versioning hashes to HashVersionV1.
+func normalizeHashVersion(v int) int {
+	if v <= 0 {
+		return HashVersionV1
+	}
+	return v
 }
 
 // normalizeForHash builds a deterministic representation of the product for hashing.
+// It is the reference form of the canonical encoding: HashNormalized must hash exactly
+// the bytes json.Marshal produces for it.
 // - sorts map keys
 // - sorts additional image links
 // - preserves only canonical fields (no DB/run metadata)
@@ -1,6 +1,7 @@
 package ingest
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/ETAnderson/conductor/internal/domain"
@@ -135,3 +136,27 @@ func TestHashNormalized_ChangesWhenCoreFieldChanges(t *testing.T) {
 		t.Fatalf("expected different hashes when title changes")
 	}
 }
+
+// Stored product_state hashes were computed with this encoding; it must never drift.
+func TestHashNormalized_GoldenVector(t *testing.T) {
+	const want = "e7362a4de9456eafae3f4153a3415fd8cd1311cf0f4a62d3d71f6fa720838646"
+
+	// v1 hashes are stored; they must not change while v1 is supported. The product is
+	// already normalized, so the current version hashes it the same.
+	for _, h := range []Hasher{{}, {Version: HashVersionV1}} {
+		got, err := h.HashNormalized(baseProductForHash())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("version %d: expected %s, got %s", h.Version, want, got)
+		}
+	}
+}
+
+func TestHashWithVersion_RejectsUnsupportedVersion(t *testing.T) {
+	_, err := Hasher{}.HashWithVersion(baseProductForHash(), 99)
+	if !errors.Is(err, ErrUnsupportedHashVersion) {
+		t.Fatalf("expected ErrUnsupportedHashVersion, got %v", err)
+	}
+}
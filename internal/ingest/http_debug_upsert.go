@@ -58,7 +58,7 @@ func (h DebugUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	out, err := h.Processor.ProcessProducts(parsed.Products, h.EnabledChannels, h.Store.Get)
+	out, err := h.Processor.ProcessProducts(parsed.Products, h.EnabledChannels, h.Store.Lookup)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"error":   "processing_failed",
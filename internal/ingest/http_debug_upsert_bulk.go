@@ -66,7 +66,7 @@ func (h DebugBulkUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		if err != nil {
 			// Treat line-level JSON parse errors as rejected product
 			out.Products = append(out.Products, ProductProcessResult{
-				ProductKey:  "",
+				ProductKey:  KeylessProductKey(out.Summary.Received - 1),
 				Disposition: domain.ProductDispositionRejected,
 				Reason:      "invalid_json_line",
 				Issues: []ValidationIssue{
@@ -81,7 +81,7 @@ func (h DebugBulkUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 			unknown[k] = struct{}{}
 		}
 
-		res, valid, err := h.Processor.ProcessProduct(prod, h.EnabledChannels, h.Store.Get)
+		res, valid, err := h.Processor.ProcessProduct(prod, h.EnabledChannels, h.Store.Lookup)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{
 				"error":   "processing_failed",
@@ -89,6 +89,9 @@ func (h DebugBulkUpsertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 			})
 			return
 		}
+		if res.ProductKey == "" {
+			res.ProductKey = KeylessProductKey(out.Summary.Received - 1)
+		}
 
 		out.Products = append(out.Products, res)
 
@@ -0,0 +1,125 @@
+package ingest
+
+import "strings"
+
+// Identifier issue codes.
+const (
+	IssueCodeInvalidGTINFormat      = "invalid_gtin_format"
+	IssueCodeInvalidGTINLength      = "invalid_gtin_length"
+	IssueCodeInvalidGTINCheckDigit  = "invalid_gtin_check_digit"
+	IssueCodeRestrictedGTINPrefix   = "restricted_gtin_prefix"
+	IssueCodeISBN10NotGTIN          = "isbn10_not_gtin"
+	IssueCodeIdentifierBrandMissing = "identifier_brand_missing"
+	IssueCodeIdentifierCodeMissing  = "identifier_gtin_or_mpn_missing"
+)
+
+// validateGTIN checks a non-empty GTIN: GTIN-8, -12 (UPC), -13 (EAN, ISBN-13) or -14
+// with a valid GS1 check digit, outside the prefixes reserved for in-store and coupon
+// codes. A 10-character value with a valid ISBN-10 check digit gets its own code,
+// since channels want the ISBN-13 (978 + first nine digits) instead.
+func validateGTIN(res *ValidationResult, gtin string) {
+	if gtin == "" {
+		return
+	}
+
+	if len(gtin) == 10 && isISBN10(gtin) {
+		addIssue(res, "gtin", IssueCodeISBN10NotGTIN, "ISBN-10 is not a GTIN; submit the 13-digit ISBN (978 prefix)")
+		return
+	}
+
+	for i := 0; i < len(gtin); i++ {
+		if gtin[i] < '0' || gtin[i] > '9' {
+			addIssue(res, "gtin", IssueCodeInvalidGTINFormat, "gtin must contain digits only")
+			return
+		}
+	}
+
+	switch len(gtin) {
+	case 8, 12, 13, 14:
+	default:
+		addIssue(res, "gtin", IssueCodeInvalidGTINLength, "gtin must have 8, 12, 13 or 14 digits")
+		return
+	}
+
+	if gtinCheckDigit(gtin[:len(gtin)-1]) != gtin[len(gtin)-1] {
+		addIssue(res, "gtin", IssueCodeInvalidGTINCheckDigit, "gtin check digit does not match")
+		return
+	}
+
+	if restrictedGTIN(gtin) {
+		addIssue(res, "gtin", IssueCodeRestrictedGTINPrefix, "gtin is in a restricted range (in-store, variable-measure or coupon codes) and is not globally unique")
+	}
+}
+
+// gtinCheckDigit is the GS1 mod-10 check digit for the digits before it: weights
+// 3,1,3,... from the right.
+func gtinCheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < len(body); i++ {
+		d := int(body[len(body)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// restrictedGTIN reports GTINs that are not globally unique trade item numbers. Except
+// for GTIN-8, which has its own prefix space, they are compared in GTIN-13 form.
+func restrictedGTIN(gtin string) bool {
+	if strings.Trim(gtin, "0") == "" {
+		return true
+	}
+
+	if len(gtin) == 8 {
+		// 0 and 2: restricted circulation (RCN-8)
+		return gtin[0] == '0' || gtin[0] == '2'
+	}
+
+	var g13 string
+	switch len(gtin) {
+	case 12:
+		g13 = "0" + gtin
+	case 13:
+		g13 = gtin
+	case 14:
+		g13 = gtin[1:] // drop the packaging indicator
+	}
+
+	switch {
+	case strings.HasPrefix(g13, "02"), strings.HasPrefix(g13, "04"), strings.HasPrefix(g13, "05"):
+		// UPC number systems 2 (variable measure), 4 (in-store) and 5 (coupons)
+		return true
+	case g13[0] == '2':
+		// 20-29: restricted circulation
+		return true
+	case g13[:3] >= "980" && g13[:3] <= "984":
+		// 980 refund receipts, 981-984 coupons
+		return true
+	case strings.HasPrefix(g13, "99"):
+		// coupons
+		return true
+	}
+	return false
+}
+
+// isISBN10 reports whether s is nine digits plus a valid mod-11 check character
+// (0-9 or X).
+func isISBN10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		c := s[i]
+		var d int
+		switch {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case i == 9 && (c == 'X' || c == 'x'):
+			d = 10
+		default:
+			return false
+		}
+		sum += d * (10 - i)
+	}
+	return sum%11 == 0
+}
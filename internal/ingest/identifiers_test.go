@@ -0,0 +1,97 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+func TestValidateProductBase_GTIN(t *testing.T) {
+	cases := []struct {
+		gtin     string
+		wantCode string
+	}{
+		{"", ""},
+		{"96385074", ""},       // GTIN-8
+		{"036000291452", ""},   // GTIN-12 (UPC-A)
+		{"4006381333931", ""},  // GTIN-13 (EAN)
+		{"9780306406157", ""},  // ISBN-13
+		{"10036000291459", ""}, // GTIN-14
+		{"0012345678905", ""},  // UPC padded to 13 digits
+
+		{"4006381333932", IssueCodeInvalidGTINCheckDigit},
+		{"036000291453", IssueCodeInvalidGTINCheckDigit},
+		{"4006-38133393", IssueCodeInvalidGTINFormat},
+		{"400638133393", IssueCodeInvalidGTINCheckDigit},
+		{"400638133", IssueCodeInvalidGTINLength},
+		{"0306406152", IssueCodeISBN10NotGTIN},
+		{"030640615X", IssueCodeInvalidGTINFormat}, // bad ISBN-10 check character
+		{"2000000000008", IssueCodeRestrictedGTINPrefix},
+		{"500000000005", IssueCodeRestrictedGTINPrefix}, // UPC coupon,
+		{"04000006", IssueCodeRestrictedGTINPrefix},
+		{"9810000000006", IssueCodeRestrictedGTINPrefix},
+		{"9900000000011", IssueCodeRestrictedGTINPrefix},
+		{"0000000000000", IssueCodeRestrictedGTINPrefix},
+	}
+
+	for _, tc := range cases {
+		p := validBaseProduct()
+		p.GTIN = tc.gtin
+
+		res := ValidateProductBase(p, ValidationPolicy{})
+		if tc.wantCode == "" {
+			if !res.IsValid() {
+				t.Fatalf("%q: expected valid, got %#v", tc.gtin, res.Issues)
+			}
+			continue
+		}
+		if len(res.Issues) != 1 || res.Issues[0].Path != "gtin" || res.Issues[0].Code != tc.wantCode {
+			t.Fatalf("%q: expected %s on gtin, got %#v", tc.gtin, tc.wantCode, res.Issues)
+		}
+	}
+}
+
+func TestValidateChannels_IdentifierRule(t *testing.T) {
+	policy := ValidationPolicy{IdentifierChannels: []string{"google"}}
+
+	p := validBaseProduct()
+	if res := ValidateChannels(p, []string{"google"}, ValidationPolicy{}); !res["google"].IsValid() {
+		t.Fatalf("expected no rule without the policy, got %#v", res["google"].Issues)
+	}
+
+	res := ValidateChannels(p, []string{"google", "Google"}, policy)
+	if len(res) != 1 {
+		t.Fatalf("expected one result per channel, got %#v", res)
+	}
+	issues := res["google"].Issues
+	if len(issues) != 2 ||
+		issues[0].Code != IssueCodeIdentifierBrandMissing ||
+		issues[1].Code != IssueCodeIdentifierCodeMissing {
+		t.Fatalf("expected brand and gtin/mpn issues once, got %#v", issues)
+	}
+
+	for _, mutate := range []func(*domain.Product){
+		func(p *domain.Product) { p.GTIN = "4006381333931" },
+		func(p *domain.Product) { p.MPN = "MPN-1" },
+	} {
+		p := validBaseProduct()
+		p.Brand = "Acme"
+		mutate(&p)
+		if res := ValidateChannels(p, []string{"google"}, policy); !res["google"].IsValid() {
+			t.Fatalf("expected brand plus gtin or mpn to pass, got %#v", res["google"].Issues)
+		}
+	}
+}
+
+func TestProcessor_IdentifierRuleRejectsForChannel(t *testing.T) {
+	proc := NewProcessor()
+	proc.Validation.IdentifierChannels = []string{"google"}
+
+	res, valid, err := proc.Prepare(validProductForProcessor("sku1"), []string{"google"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if valid || res.Reason != "channel_validation_failed" {
+		t.Fatalf("expected channel_validation_failed, got valid=%v %+v", valid, res)
+	}
+}
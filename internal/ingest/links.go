@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+// maxURLLength is the longest link channels accept (Google's limit; Meta allows more).
+const maxURLLength = 2000
+
+// Link issue codes.
+const (
+	IssueCodeInvalidURL              = "invalid_url"
+	IssueCodeRelativeURL             = "relative_url"
+	IssueCodeUnsupportedURLScheme    = "unsupported_url_scheme"
+	IssueCodeURLTooLong              = "url_too_long"
+	IssueCodeURLContainsWhitespace   = "url_contains_whitespace"
+	IssueCodeInsecureURL             = "insecure_url"
+	IssueCodeLinkDomainNotAllowed    = "link_domain_not_allowed"
+	IssueCodeTooManyAdditionalImages = "too_many_additional_images"
+)
+
+// validateLinks checks link, image_link and additional_image_links. Empty required
+// links are left to the required-field checks.
+func validateLinks(res *ValidationResult, p domain.Product, policy ValidationPolicy) {
+	if u, ok := validateURL(res, "link", p.Link, policy); ok && len(policy.LinkDomains) > 0 && !policy.allowsLinkHost(u.Hostname()) {
+		addIssue(res, "link", IssueCodeLinkDomainNotAllowed, "link host "+strconv.Quote(u.Hostname())+" is not one of the allowed link domains")
+	}
+
+	validateURL(res, "image_link", p.ImageLink, policy)
+	for i, l := range p.AdditionalImageLinks {
+		validateURL(res, "additional_image_links["+strconv.Itoa(i)+"]", l, policy)
+	}
+}
+
+// validateURL reports at most one issue for a non-empty raw URL and returns the parsed
+// URL when it is valid.
+func validateURL(res *ValidationResult, path, raw string, policy ValidationPolicy) (*url.URL, bool) {
+	if raw == "" {
+		return nil, false
+	}
+
+	if len(raw) > maxURLLength {
+		addIssue(res, path, IssueCodeURLTooLong, "url must be at most "+strconv.Itoa(maxURLLength)+" characters")
+		return nil, false
+	}
+	if strings.IndexFunc(raw, unicode.IsSpace) >= 0 {
+		addIssue(res, path, IssueCodeURLContainsWhitespace, "url must not contain whitespace (percent-encode it)")
+		return nil, false
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		addIssue(res, path, IssueCodeInvalidURL, "url could not be parsed")
+		return nil, false
+	}
+	if !u.IsAbs() || u.Host == "" {
+		addIssue(res, path, IssueCodeRelativeURL, "url must be absolute (e.g. \"https://example.com/p/sku1\")")
+		return nil, false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+	case "http":
+		if policy.RequireHTTPS {
+			addIssue(res, path, IssueCodeInsecureURL, "url must use https")
+			return nil, false
+		}
+	default:
+		addIssue(res, path, IssueCodeUnsupportedURLScheme, "url scheme must be http or https")
+		return nil, false
+	}
+
+	return u, true
+}
@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+func TestValidateProductBase_Links(t *testing.T) {
+	cases := []struct {
+		name     string
+		mutate   func(p *domain.Product)
+		policy   ValidationPolicy
+		wantPath string
+		wantCode string
+	}{
+		{"valid", func(p *domain.Product) {}, ValidationPolicy{}, "", ""},
+		{"http allowed by default", func(p *domain.Product) { p.ImageLink = "http://cdn.example.net/a.jpg" }, ValidationPolicy{}, "", ""},
+		{"subdomain allowed", func(p *domain.Product) { p.Link = "https://shop.example.com/p/1" }, ValidationPolicy{LinkDomains: []string{"example.com"}}, "", ""},
+		{"images ignore link domains", func(p *domain.Product) { p.ImageLink = "https://cdn.example.net/a.jpg" }, ValidationPolicy{LinkDomains: []string{"Example.com"}}, "", ""},
+
+		{"relative", func(p *domain.Product) { p.Link = "/p/sku1" }, ValidationPolicy{}, "link", IssueCodeRelativeURL},
+		{"no scheme", func(p *domain.Product) { p.ImageLink = "example.com/a.jpg" }, ValidationPolicy{}, "image_link", IssueCodeRelativeURL},
+		{"scheme", func(p *domain.Product) { p.Link = "ftp://example.com/p/sku1" }, ValidationPolicy{}, "link", IssueCodeUnsupportedURLScheme},
+		{"whitespace", func(p *domain.Product) { p.Link = "https://example.com/p/sku 1" }, ValidationPolicy{}, "link", IssueCodeURLContainsWhitespace},
+		{"unparseable", func(p *domain.Product) { p.Link = "https://example.com/%zz" }, ValidationPolicy{}, "link", IssueCodeInvalidURL},
+		{"too long", func(p *domain.Product) { p.Link = "https://example.com/" + strings.Repeat("a", maxURLLength) }, ValidationPolicy{}, "link", IssueCodeURLTooLong},
+		{"https required", func(p *domain.Product) { p.ImageLink = "http://example.com/a.jpg" }, ValidationPolicy{RequireHTTPS: true}, "image_link", IssueCodeInsecureURL},
+		{"additional image", func(p *domain.Product) { p.AdditionalImageLinks = []string{"https://example.com/a.jpg", "b.jpg"} }, ValidationPolicy{}, "additional_image_links[1]", IssueCodeRelativeURL},
+		{"foreign domain", func(p *domain.Product) { p.Link = "https://notexample.com/p/1" }, ValidationPolicy{LinkDomains: []string{"example.com"}}, "link", IssueCodeLinkDomainNotAllowed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := validBaseProduct()
+			tc.mutate(&p)
+
+			res := ValidateProductBase(p, tc.policy)
+			if tc.wantCode == "" {
+				if !res.IsValid() {
+					t.Fatalf("expected valid, got %#v", res.Issues)
+				}
+				return
+			}
+
+			if len(res.Issues) != 1 {
+				t.Fatalf("expected exactly one issue, got %#v", res.Issues)
+			}
+			if got := res.Issues[0]; got.Path != tc.wantPath || got.Code != tc.wantCode {
+				t.Fatalf("expected %s at %s, got %s at %s", tc.wantCode, tc.wantPath, got.Code, got.Path)
+			}
+		})
+	}
+}
+
+func TestValidateChannels_ImageCap(t *testing.T) {
+	p := validBaseProduct()
+	p.AdditionalImageLinks = []string{"https://example.com/a.jpg", "https://example.com/b.jpg", "https://example.com/c.jpg"}
+
+	if res := ValidateChannels(p, []string{"google"}, ValidationPolicy{}); !res["google"].IsValid() {
+		t.Fatalf("expected default cap to allow 3 images, got %#v", res["google"].Issues)
+	}
+
+	policy := ValidationPolicy{MaxAdditionalImages: map[string]int{"google": 2}}
+	res := ValidateChannels(p, []string{"google"}, policy)
+	if issues := res["google"].Issues; len(issues) != 1 || issues[0].Code != IssueCodeTooManyAdditionalImages {
+		t.Fatalf("expected too_many_additional_images, got %#v", issues)
+	}
+
+	p.AdditionalImageLinks = p.AdditionalImageLinks[:2]
+	if res := ValidateChannels(p, []string{"google"}, policy); !res["google"].IsValid() {
+		t.Fatalf("expected images at the cap to pass, got %#v", res["google"].Issues)
+	}
+}
@@ -21,6 +21,11 @@ func (s *MemoryHashStore) Get(productKey string) (string, bool, error) {
 	return h, ok, nil
 }
 
+func (s *MemoryHashStore) Lookup(productKey string) (PreviousState, bool, error) {
+	h, ok, err := s.Get(productKey)
+	return PreviousState{Hash: h}, ok, err
+}
+
 func (s *MemoryHashStore) Set(productKey string, hash string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,147 @@
+package ingest
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+//go:embed iso4217.csv
+var iso4217CSV string
+
+// currencyMinorUnits maps ISO 4217 codes to the number of decimal places amounts in
+// that currency may have (e.g. USD 2, JPY 0, KWD 3).
+var currencyMinorUnits = loadCurrencies(iso4217CSV)
+
+func loadCurrencies(data string) map[string]int {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comment = '#'
+
+	records, err := r.ReadAll()
+	if err != nil {
+		panic("ingest: bad iso4217.csv: " + err.Error())
+	}
+
+	out := make(map[string]int, len(records))
+	for _, rec := range records[1:] { // header
+		minor, err := strconv.Atoi(rec[1])
+		if err != nil {
+			panic("ingest: bad iso4217.csv minor units for " + rec[0])
+		}
+		out[rec[0]] = minor
+	}
+	return out
+}
+
+// CurrencyMinorUnits reports the decimal places allowed for an ISO 4217 currency.
+func CurrencyMinorUnits(code string) (int, bool) {
+	n, ok := currencyMinorUnits[code]
+	return n, ok
+}
+
+// maxAmountIntegerDigits bounds amounts below 10^12 in any currency; anything larger
+// is a feed bug (e.g. a price in minor units sent as major units), not a price.
+const maxAmountIntegerDigits = 12
+
+// Money issue codes.
+const (
+	IssueCodeInvalidDecimal            = "invalid_decimal"
+	IssueCodeInvalidCurrency           = "invalid_currency"
+	IssueCodeUnknownCurrency           = "unknown_currency"
+	IssueCodeCurrencyPrecision         = "currency_precision_exceeded"
+	IssueCodeNegativeAmount            = "negative_amount"
+	IssueCodeZeroAmount                = "zero_amount"
+	IssueCodeAmountTooLarge            = "amount_too_large"
+	IssueCodeSalePriceCurrencyMismatch = "sale_price_currency_mismatch"
+	IssueCodeSalePriceNotBelowPrice    = "sale_price_not_below_price"
+)
+
+// validateMoneyFields checks price and, when present, sale_price against each other.
+// Empty price fields are left to the required-field checks.
+func validateMoneyFields(res *ValidationResult, p domain.Product, policy ValidationPolicy) {
+	price, priceOK := validateMoney(res, "price", p.Price, policy)
+	if p.SalePrice == nil {
+		return
+	}
+
+	sale, saleOK := validateMoney(res, "sale_price", *p.SalePrice, policy)
+	if p.SalePrice.Currency != "" && p.Price.Currency != "" && p.SalePrice.Currency != p.Price.Currency {
+		addIssue(res, "sale_price.currency", IssueCodeSalePriceCurrencyMismatch, "sale_price currency must match the price currency")
+		return
+	}
+	if priceOK && saleOK && sale.Cmp(price) >= 0 {
+		addIssue(res, "sale_price.amount_decimal", IssueCodeSalePriceNotBelowPrice, "sale_price must be less than price")
+	}
+}
+
+// validateMoney reports the issues with m under path and returns its amount when both
+// the amount and the currency are valid.
+func validateMoney(res *ValidationResult, path string, m domain.Money, policy ValidationPolicy) (*big.Rat, bool) {
+	ok := true
+
+	currency := m.Currency
+	switch {
+	case currency == "":
+		ok = false
+	case len(currency) != 3:
+		addIssue(res, path+".currency", IssueCodeInvalidCurrency, "currency must be a 3-letter ISO code (e.g. \"USD\")")
+		ok = false
+	default:
+		if _, known := currencyMinorUnits[currency]; !known {
+			addIssue(res, path+".currency", IssueCodeUnknownCurrency, "currency "+strconv.Quote(currency)+" is not an ISO 4217 currency")
+			ok = false
+		}
+	}
+
+	amount := m.AmountDecimal
+	if amount == "" {
+		return nil, false
+	}
+
+	amountPath := path + ".amount_decimal"
+	negative := strings.HasPrefix(amount, "-")
+	digits := strings.TrimPrefix(amount, "-")
+	if !looksLikeDecimal(digits) {
+		addIssue(res, amountPath, IssueCodeInvalidDecimal, "amount_decimal must look like a decimal number (e.g. \"19.99\")")
+		return nil, false
+	}
+
+	intPart, frac, _ := strings.Cut(digits, ".")
+	intPart = strings.TrimLeft(intPart, "0")
+	frac = strings.TrimRight(frac, "0")
+	zero := intPart == "" && frac == ""
+
+	switch {
+	case zero:
+		if !policy.AllowZeroPrice {
+			addIssue(res, amountPath, IssueCodeZeroAmount, "amount must be greater than zero")
+			ok = false
+		}
+	case negative:
+		addIssue(res, amountPath, IssueCodeNegativeAmount, "amount must not be negative")
+		ok = false
+	case len(intPart) > maxAmountIntegerDigits:
+		addIssue(res, amountPath, IssueCodeAmountTooLarge, "amount is implausibly large")
+		ok = false
+	}
+
+	if minor, known := currencyMinorUnits[currency]; known && len(frac) > minor {
+		addIssue(res, amountPath, IssueCodeCurrencyPrecision,
+			currency+" amounts allow at most "+strconv.Itoa(minor)+" decimal places")
+		ok = false
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	r, parsed := new(big.Rat).SetString(digits)
+	if !parsed {
+		return nil, false
+	}
+	return r, true
+}
@@ -0,0 +1,74 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+func TestValidateProductBase_Money(t *testing.T) {
+	sale := func(amount, currency string) *domain.Money {
+		return &domain.Money{AmountDecimal: amount, Currency: currency}
+	}
+
+	cases := []struct {
+		name     string
+		mutate   func(p *domain.Product)
+		policy   ValidationPolicy
+		wantPath string
+		wantCode string
+	}{
+		{"valid", func(p *domain.Product) {}, ValidationPolicy{}, "", ""},
+		{"valid sale price", func(p *domain.Product) { p.SalePrice = sale("9.99", "USD") }, ValidationPolicy{}, "", ""},
+		{"zero decimals currency", func(p *domain.Product) { p.Price = domain.Money{AmountDecimal: "1500", Currency: "JPY"} }, ValidationPolicy{}, "", ""},
+		{"three decimals currency", func(p *domain.Product) { p.Price = domain.Money{AmountDecimal: "1.125", Currency: "KWD"} }, ValidationPolicy{}, "", ""},
+		{"zero allowed by policy", func(p *domain.Product) { p.Price.AmountDecimal = "0" }, ValidationPolicy{AllowZeroPrice: true}, "", ""},
+
+		{"unknown currency", func(p *domain.Product) { p.Price.Currency = "ABC" }, ValidationPolicy{}, "price.currency", IssueCodeUnknownCurrency},
+		{"too many decimals", func(p *domain.Product) { p.Price = domain.Money{AmountDecimal: "1500.5", Currency: "JPY"} }, ValidationPolicy{}, "price.amount_decimal", IssueCodeCurrencyPrecision},
+		{"zero", func(p *domain.Product) { p.Price.AmountDecimal = "0.00" }, ValidationPolicy{}, "price.amount_decimal", IssueCodeZeroAmount},
+		{"negative", func(p *domain.Product) { p.Price.AmountDecimal = "-5" }, ValidationPolicy{AllowZeroPrice: true}, "price.amount_decimal", IssueCodeNegativeAmount},
+		{"too large", func(p *domain.Product) { p.Price.AmountDecimal = "1000000000000" }, ValidationPolicy{}, "price.amount_decimal", IssueCodeAmountTooLarge},
+		{"sale currency mismatch", func(p *domain.Product) { p.SalePrice = sale("9.99", "EUR") }, ValidationPolicy{}, "sale_price.currency", IssueCodeSalePriceCurrencyMismatch},
+		{"sale equal to price", func(p *domain.Product) { p.SalePrice = sale("19.990", "USD") }, ValidationPolicy{}, "sale_price.amount_decimal", IssueCodeSalePriceNotBelowPrice},
+		{"sale above price", func(p *domain.Product) { p.SalePrice = sale("100", "USD") }, ValidationPolicy{}, "sale_price.amount_decimal", IssueCodeSalePriceNotBelowPrice},
+		{"sale invalid", func(p *domain.Product) { p.SalePrice = sale("cheap", "USD") }, ValidationPolicy{}, "sale_price.amount_decimal", IssueCodeInvalidDecimal},
+		{"sale zero", func(p *domain.Product) { p.SalePrice = sale("0", "USD") }, ValidationPolicy{}, "sale_price.amount_decimal", IssueCodeZeroAmount},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := validBaseProduct()
+			tc.mutate(&p)
+
+			res := ValidateProductBase(p, tc.policy)
+			if tc.wantCode == "" {
+				if !res.IsValid() {
+					t.Fatalf("expected valid, got %#v", res.Issues)
+				}
+				return
+			}
+
+			if len(res.Issues) != 1 {
+				t.Fatalf("expected exactly one issue, got %#v", res.Issues)
+			}
+			if got := res.Issues[0]; got.Path != tc.wantPath || got.Code != tc.wantCode {
+				t.Fatalf("expected %s at %s, got %s at %s", tc.wantCode, tc.wantPath, got.Code, got.Path)
+			}
+		})
+	}
+}
+
+func TestCurrencyMinorUnits(t *testing.T) {
+	for code, want := range map[string]int{"USD": 2, "JPY": 0, "KWD": 3} {
+		if got, ok := CurrencyMinorUnits(code); !ok || got != want {
+			t.Fatalf("%s: expected %d, got %d (known=%v)", code, want, got, ok)
+		}
+	}
+	// Precious metals and funds are not valid product prices
+	for _, code := range []string{"XAU", "CLF", "USN"} {
+		if _, ok := CurrencyMinorUnits(code); ok {
+			t.Fatalf("expected %s to be rejected as a price currency", code)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package ingest
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeProduct returns p with semantically equal values in one canonical form,
+// so they hash the same and channels receive the same document:
+// - decimals without redundant zeros ("019.90" -> "19.9")
+// - upper-case currency codes
+// - text trimmed and in Unicode NFC
+// - condition and availability aliases mapped to canonical values ("In Stock" -> "in_stock")
+// - additional_image_links without duplicates (first occurrence kept)
+//
+// product_key and group_key are identities and are left exactly as received.
+//
+// Values that are not valid to begin with (e.g. a malformed decimal) are only trimmed
+// and left for validation to reject. NormalizeProduct is idempotent and does not
+// modify p's slices or maps.
+func NormalizeProduct(p domain.Product) domain.Product {
+	return normalizeProduct(p, CurrentHashVersion)
+}
+
+// normalizeProduct normalizes p by the rules of the given hash version, which must
+// stay fixed once the version has shipped: v2 only lower-cases condition and
+// availability, v3 also maps their aliases.
+func normalizeProduct(p domain.Product, version int) domain.Product {
+	p.Title = normalizeText(p.Title)
+	p.Description = normalizeText(p.Description)
+	p.Brand = normalizeText(p.Brand)
+
+	p.Link = strings.TrimSpace(p.Link)
+	p.ImageLink = strings.TrimSpace(p.ImageLink)
+	p.AdditionalImageLinks = normalizeLinks(p.AdditionalImageLinks)
+
+	p.GTIN = strings.TrimSpace(p.GTIN)
+	p.MPN = strings.TrimSpace(p.MPN)
+
+	p.Condition = domain.Condition(normalizeEnum(string(p.Condition)))
+	p.Availability = domain.Availability(normalizeEnum(string(p.Availability)))
+	if version >= HashVersionV3 {
+		if c, ok := domain.ParseCondition(string(p.Condition)); ok {
+			p.Condition = c
+		}
+		if a, ok := domain.ParseAvailability(string(p.Availability)); ok {
+			p.Availability = a
+		}
+	}
+
+	p.Price = normalizeMoney(p.Price)
+	if p.SalePrice != nil {
+		if sale := normalizeMoney(*p.SalePrice); sale != *p.SalePrice {
+			p.SalePrice = &sale
+		}
+	}
+
+	return p
+}
+
+func normalizeText(s string) string {
+	s = strings.TrimSpace(s)
+	// ASCII is always NFC; checking it first keeps the common case allocation-free.
+	if isASCII(s) || norm.NFC.IsNormalString(s) {
+		return s
+	}
+	return norm.NFC.String(s)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeEnum(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func normalizeMoney(m domain.Money) domain.Money {
+	m.AmountDecimal = canonicalDecimal(m.AmountDecimal)
+	m.Currency = strings.ToUpper(strings.TrimSpace(m.Currency))
+	return m
+}
+
+// canonicalDecimal strips leading zeros from the integer part and trailing zeros
+// from the fraction ("0.50" -> "0.5", "20.00" -> "20", ".5" -> "0.5"). Anything that
+// does not look like a decimal comes back trimmed but otherwise unchanged.
+func canonicalDecimal(s string) string {
+	s = strings.TrimSpace(s)
+	if !looksLikeDecimal(s) {
+		return s
+	}
+
+	rawInt, rawFrac, hasDot := strings.Cut(s, ".")
+	intPart := strings.TrimLeft(rawInt, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	frac := strings.TrimRight(rawFrac, "0")
+
+	switch {
+	case frac == "":
+		return intPart
+	case intPart == rawInt && frac == rawFrac && hasDot:
+		return s
+	default:
+		return intPart + "." + frac
+	}
+}
+
+// normalizeLinks trims links and drops empty and repeated ones. The input slice is
+// returned as is when nothing changes.
+func normalizeLinks(links []string) []string {
+	clean := true
+	for i, l := range links {
+		if l == "" || l != strings.TrimSpace(l) || slices.Contains(links[:i], l) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return links
+	}
+
+	out := make([]string, 0, len(links))
+	for _, l := range links {
+		l = strings.TrimSpace(l)
+		if l != "" && !slices.Contains(out, l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package ingest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+func TestNormalizeProduct(t *testing.T) {
+	in := domain.Product{
+		ProductKey:           " sku1 ",
+		Title:                "  Cafe\u0301 mug\t",
+		Description:          "Desc ",
+		Brand:                " Acme",
+		Link:                 " https://example.com/p/sku1",
+		ImageLink:            "https://example.com/p/sku1.jpg ",
+		AdditionalImageLinks: []string{"https://example.com/a.jpg", " https://example.com/b.jpg", "https://example.com/a.jpg", ""},
+		GTIN:                 " 0012345678905 ",
+		Condition:            " New",
+		Availability:         "IN_STOCK",
+		Price:                domain.Money{AmountDecimal: " 019.90", Currency: "usd "},
+		SalePrice:            &domain.Money{AmountDecimal: ".50", Currency: "Usd"},
+	}
+
+	got := NormalizeProduct(in)
+
+	want := domain.Product{
+		ProductKey:           " sku1 ",
+		Title:                "Caf\u00e9 mug",
+		Description:          "Desc",
+		Brand:                "Acme",
+		Link:                 "https://example.com/p/sku1",
+		ImageLink:            "https://example.com/p/sku1.jpg",
+		AdditionalImageLinks: []string{"https://example.com/a.jpg", "https://example.com/b.jpg"},
+		GTIN:                 "0012345678905",
+		Condition:            "new",
+		Availability:         "in_stock",
+		Price:                domain.Money{AmountDecimal: "19.9", Currency: "USD"},
+		SalePrice:            &domain.Money{AmountDecimal: "0.5", Currency: "USD"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected normalization:\n got  %+v\n want %+v", got, want)
+	}
+
+	if again := NormalizeProduct(got); !reflect.DeepEqual(again, got) {
+		t.Fatalf("expected normalization to be idempotent, got %+v", again)
+	}
+	if in.AdditionalImageLinks[1] != " https://example.com/b.jpg" || in.SalePrice.Currency != "Usd" {
+		t.Fatalf("expected input to be left untouched, got %+v", in)
+	}
+}
+
+func TestCanonicalDecimal(t *testing.T) {
+	cases := map[string]string{
+		"19.99":  "19.99",
+		"19.90":  "19.9",
+		"20.00":  "20",
+		"20.":    "20",
+		"0020":   "20",
+		"0.50":   "0.5",
+		".5":     "0.5",
+		"0":      "0",
+		"000.00": "0",
+		" 7 ":    "7",
+		"19.9.9": "19.9.9",
+		"abc":    "abc",
+	}
+	for in, want := range cases {
+		if got := canonicalDecimal(in); got != want {
+			t.Fatalf("canonicalDecimal(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeProduct_VocabularyAliases(t *testing.T) {
+	p := validBaseProduct()
+	p.Condition = "Brand New"
+	p.Availability = "In Stock"
+
+	got := NormalizeProduct(p)
+	if got.Condition != domain.ConditionNew || got.Availability != domain.AvailabilityInStock {
+		t.Fatalf("expected canonical values, got %q/%q", got.Condition, got.Availability)
+	}
+
+	// v2 hashes were computed before aliases were mapped and must keep their rules.
+	v2 := normalizeProduct(p, HashVersionV2)
+	if v2.Condition != "brand new" || v2.Availability != "in stock" {
+		t.Fatalf("expected v2 to only lower-case, got %q/%q", v2.Condition, v2.Availability)
+	}
+
+	p.Availability = "Maybe"
+	if got := NormalizeProduct(p); got.Availability != "maybe" {
+		t.Fatalf("expected unknown value lower-cased, got %q", got.Availability)
+	}
+	res := ValidateProductBase(NormalizeProduct(p), ValidationPolicy{})
+	if len(res.Issues) != 1 || res.Issues[0].Code != "invalid_availability" {
+		t.Fatalf("expected invalid_availability, got %#v", res.Issues)
+	}
+}
@@ -0,0 +1,173 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+const defaultMaxLineBytes = 10 * 1024 * 1024
+
+// BulkPipeline parses, validates and hashes an NDJSON product stream in parallel:
+//
+//	reader -> parse workers -> prepare workers -> ordered collector
+//
+// Each stage is bounded by Workers, so memory in flight stays proportional to the
+// worker count rather than the file. Output is in input order and unknown keys are
+// merged as a set, so the result does not depend on scheduling.
+type BulkPipeline struct {
+	Processor       Processor
+	EnabledChannels []string
+
+	// Workers per stage; defaults to GOMAXPROCS.
+	Workers int
+
+	// MaxLineBytes caps a single NDJSON line (default 10MB).
+	MaxLineBytes int
+
+	// DropProducts releases each item's parsed Product once it is prepared, keeping
+	// only its ProductKey; valid items still carry the normalized document in
+	// Prepared.Product. Callers that only need the outcome set it to halve the
+	// documents held per item.
+	DropProducts bool
+}
+
+// BulkItem is one non-empty NDJSON line after the pipeline: either a parse error or
+// the Processor.Prepare outcome for the parsed product.
+type BulkItem struct {
+	Product    domain.Product
+	ProductKey string
+	ParseErr   error
+
+	Prepared   ProductProcessResult
+	Valid      bool
+	PrepareErr error
+}
+
+type BulkOutput struct {
+	Items       []BulkItem
+	UnknownKeys map[string]struct{}
+}
+
+type bulkJob struct {
+	seq  int
+	line []byte
+
+	item    BulkItem
+	unknown map[string]struct{}
+}
+
+// Run drains r through the pipeline. It fails only when reading fails or ctx ends;
+// per-line problems are reported on the items.
+func (p BulkPipeline) Run(ctx context.Context, r io.Reader) (BulkOutput, error) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	maxLine := p.MaxLineBytes
+	if maxLine <= 0 {
+		maxLine = defaultMaxLineBytes
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan bulkJob, workers*2)
+	parsed := make(chan bulkJob, workers*2)
+	prepared := make(chan bulkJob, workers*2)
+
+	// Reader: the scanner reuses its buffer, so each line is copied before handoff.
+	var readErr error
+	go func() {
+		defer close(lines)
+
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, min(64*1024, maxLine)), maxLine)
+
+		seq := 0
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			select {
+			case lines <- bulkJob{seq: seq, line: append([]byte(nil), line...)}:
+				seq++
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr = sc.Err()
+	}()
+
+	stage(ctx, workers, lines, parsed, func(j *bulkJob) {
+		prod, unk, err := ParseProductObjectAllowUnknown(j.line)
+		j.line = nil
+		j.item.Product = prod
+		j.item.ProductKey = prod.ProductKey
+		j.item.ParseErr = err
+		j.unknown = unk
+	})
+
+	stage(ctx, workers, parsed, prepared, func(j *bulkJob) {
+		if j.item.ParseErr != nil {
+			return
+		}
+		j.item.Prepared, j.item.Valid, j.item.PrepareErr = p.Processor.Prepare(j.item.Product, p.EnabledChannels)
+		if p.DropProducts {
+			j.item.Product = domain.Product{}
+		}
+	})
+
+	// Collector: workers finish out of order, so items land by sequence number.
+	out := BulkOutput{UnknownKeys: make(map[string]struct{})}
+	for j := range prepared {
+		for len(out.Items) <= j.seq {
+			out.Items = append(out.Items, BulkItem{})
+		}
+		out.Items[j.seq] = j.item
+
+		for k := range j.unknown {
+			out.UnknownKeys[k] = struct{}{}
+		}
+	}
+
+	// prepared closes only after the reader is done, so readErr is settled here.
+	if err := ctx.Err(); err != nil {
+		return BulkOutput{}, err
+	}
+	if readErr != nil {
+		return BulkOutput{}, readErr
+	}
+	return out, nil
+}
+
+// stage runs fn over in with n workers and closes out once they are all done.
+func stage(ctx context.Context, n int, in <-chan bulkJob, out chan<- bulkJob, fn func(*bulkJob)) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range in {
+				fn(&j)
+				select {
+				case out <- j:
+				case <-ctx.Done():
+					// Keep draining so upstream stages can exit.
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+}
@@ -0,0 +1,39 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// go test ./internal/ingest -run '^$' -bench BulkPipeline -benchtime 3x
+func BenchmarkBulkPipeline(b *testing.B) {
+	workerCounts := []int{1}
+	if procs := runtime.GOMAXPROCS(0); procs > 1 {
+		workerCounts = append(workerCounts, procs)
+	}
+
+	for _, n := range []int{10_000, 50_000, 200_000} {
+		data := ndjsonProducts(n)
+
+		for _, workers := range workerCounts {
+			b.Run(fmt.Sprintf("products=%d/workers=%d", n, workers), func(b *testing.B) {
+				pipe := BulkPipeline{
+					Processor:       NewProcessor(),
+					EnabledChannels: []string{"google"},
+					Workers:         workers,
+				}
+
+				b.SetBytes(int64(len(data)))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := pipe.Run(context.Background(), bytes.NewReader(data)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
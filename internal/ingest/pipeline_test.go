@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ndjsonProducts builds n valid product lines; every 7th carries an extra key and
+// every 11th is malformed, so ordering and aggregation are exercised.
+func ndjsonProducts(n int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < n; i++ {
+		switch {
+		case i%11 == 10:
+			b.WriteString(`{"product_key":` + "\n")
+			continue
+		case i%7 == 6:
+			fmt.Fprintf(&b, `{"product_key":"sku%d","extra_%d":1,`, i, i%3)
+		default:
+			fmt.Fprintf(&b, `{"product_key":"sku%d",`, i)
+		}
+		fmt.Fprintf(&b, `"title":"Product %d","description":"Desc","link":"https://example.com/p/%d",`+
+			`"image_link":"https://example.com/p/%d.jpg","condition":"new","availability":"in_stock",`+
+			`"price":{"amount_decimal":"%d.99","currency":"USD"},"channel":{"google":{"control":{"state":"active"}}}}`+"\n",
+			i, i, i, i%100)
+	}
+	return b.Bytes()
+}
+
+func TestBulkPipeline_MatchesSerialProcessing(t *testing.T) {
+	data := ndjsonProducts(500)
+	proc := NewProcessor()
+
+	pipe := BulkPipeline{Processor: proc, EnabledChannels: []string{"google"}, Workers: 8}
+	out, err := pipe.Run(context.Background(), bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(out.Items) != len(lines) {
+		t.Fatalf("expected %d items, got %d", len(lines), len(out.Items))
+	}
+
+	unknown := make(map[string]struct{})
+	for i, line := range lines {
+		prod, unk, err := ParseProductObjectAllowUnknown([]byte(line))
+		got := out.Items[i]
+		if (err != nil) != (got.ParseErr != nil) {
+			t.Fatalf("line %d: parse error mismatch: %v vs %v", i, err, got.ParseErr)
+		}
+		if err != nil {
+			continue
+		}
+		for k := range unk {
+			unknown[k] = struct{}{}
+		}
+
+		want, valid, _ := proc.Prepare(prod, []string{"google"})
+		if got.Valid != valid || got.Prepared.ProductKey != want.ProductKey || got.Prepared.Hash != want.Hash {
+			t.Fatalf("line %d: expected %+v, got %+v", i, want, got.Prepared)
+		}
+	}
+
+	if !reflect.DeepEqual(out.UnknownKeys, unknown) {
+		t.Fatalf("expected unknown keys %v, got %v", unknown, out.UnknownKeys)
+	}
+}
+
+func TestBulkPipeline_DropProductsKeepsKeys(t *testing.T) {
+	pipe := BulkPipeline{Processor: NewProcessor(), EnabledChannels: []string{"google"}, DropProducts: true}
+	out, err := pipe.Run(context.Background(), bytes.NewReader(ndjsonProducts(3)))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	for i, got := range out.Items {
+		if got.ProductKey != fmt.Sprintf("sku%d", i) || got.Product.ProductKey != "" {
+			t.Fatalf("item %d: expected key only, got key %q and product %+v", i, got.ProductKey, got.Product)
+		}
+		if !got.Valid || got.Prepared.Product == nil {
+			t.Fatalf("item %d: expected the normalized document kept on Prepared", i)
+		}
+	}
+}
+
+func TestBulkPipeline_ReportsReadErrors(t *testing.T) {
+	pipe := BulkPipeline{Processor: NewProcessor(), MaxLineBytes: 16}
+
+	_, err := pipe.Run(context.Background(), strings.NewReader(`{"product_key":"a very long line"}`+"\n"))
+	if err == nil {
+		t.Fatalf("expected error for oversized line")
+	}
+}
+
+func TestBulkPipeline_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	pipe := BulkPipeline{Processor: NewProcessor(), Workers: 2}
+	if _, err := pipe.Run(ctx, bytes.NewReader(ndjsonProducts(1000))); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
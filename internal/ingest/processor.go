@@ -1,19 +1,96 @@
 package ingest
 
 import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
 	"github.com/ETAnderson/conductor/internal/domain"
 )
 
-type PreviousHashLookup func(productKey string) (string, bool, error)
+// PreviousState is the canonical state held for a product_key before the current run.
+// Product may be nil when only the hash is known. HashVersion is the version Hash was
+// computed with (zero for hashes stored before versioning, i.e. HashVersionV1).
+// Channels are the channels that accepted the previous document (see
+// ProductProcessResult.AcceptedChannels); nil when not recorded.
+type PreviousState struct {
+	Hash        string
+	HashVersion int
+	Product     *domain.Product
+	Channels    []string
+}
+
+type PreviousStateLookup func(productKey string) (PreviousState, bool, error)
 
 type ProductProcessResult struct {
 	ProductKey string `json:"product_key"`
 	Hash       string `json:"hash,omitempty"`
 
+	// HashVersion is the hash version Hash was computed with (set with Hash).
+	HashVersion int `json:"-"`
+
 	Disposition domain.ProductDisposition `json:"disposition"`
 	Reason      string                    `json:"reason,omitempty"`
 
+	// ChangedFields lists the top-level fields that differ from the previous document (when known).
+	ChangedFields []string `json:"changed_fields,omitempty"`
+
+	// ChangeClasses classifies ChangedFields (most urgent first) so executors can
+	// prioritize and pick lighter-weight channel update APIs.
+	ChangeClasses []domain.ChangeClass `json:"change_classes,omitempty"`
+
 	Issues []ValidationIssue `json:"issues,omitempty"`
+
+	// Channels is the per-channel validation outcome for the feed's enabled channels.
+	// A product rejected by some channels is still accepted for the others.
+	Channels map[string]ChannelResult `json:"channels,omitempty"`
+
+	// Product is the accepted document for valid products (persisted as canonical state, never serialized).
+	// It is normalized (see NormalizeProduct), so it is also what channels receive.
+	Product *domain.Product `json:"-"`
+
+	// received is the product as submitted, for rehashing with older hash versions.
+	received *domain.Product
+}
+
+// Urgent reports whether any change on this product should be pushed ahead of others.
+func (r ProductProcessResult) Urgent() bool {
+	for _, c := range r.ChangeClasses {
+		if c.Urgent() {
+			return true
+		}
+	}
+	return false
+}
+
+// PushableTo reports whether the product may be pushed to channel: true unless channel
+// validation rejected it there. Results without channel outcomes (e.g. from runs
+// before per-channel validation) are pushable everywhere.
+func (r ProductProcessResult) PushableTo(channel string) bool {
+	c, ok := r.Channels[channel]
+	return !ok || c.Status == ChannelStatusValid
+}
+
+// AcceptedChannels lists the channels that accepted the product, sorted. It is nil
+// when no channels were validated.
+func (r ProductProcessResult) AcceptedChannels() []string {
+	if r.Channels == nil {
+		return nil
+	}
+	out := make([]string, 0, len(r.Channels))
+	for ch, c := range r.Channels {
+		if c.Status == ChannelStatusValid {
+			out = append(out, ch)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// PriceInventoryOnly reports whether the change can be pushed as an inventory-only update.
+func (r ProductProcessResult) PriceInventoryOnly() bool {
+	return len(r.ChangeClasses) == 1 && r.ChangeClasses[0] == domain.ChangeClassPriceInventory
 }
 
 type ProcessSummary struct {
@@ -22,6 +99,14 @@ type ProcessSummary struct {
 	Rejected  int `json:"rejected"`
 	Unchanged int `json:"unchanged"`
 	Enqueued  int `json:"enqueued"`
+
+	// Duplicates counts received products skipped because their product_key repeated
+	// in the batch.
+	Duplicates int `json:"duplicates"`
+
+	// Errored counts products that hit an infrastructure error and were not processed
+	// (Received = Valid + Rejected + Duplicates + Errored).
+	Errored int `json:"errored"`
 }
 
 type ProcessOutput struct {
@@ -31,6 +116,15 @@ type ProcessOutput struct {
 
 type Processor struct {
 	Hasher Hasher
+
+	// Duplicates decides how ProcessProducts treats repeated product_keys.
+	// Defaults to DuplicatePolicyRejectAll.
+	Duplicates DuplicatePolicy
+
+	// Validation switches the optional validation rules. Ingest handlers set it per
+	// request to the policy stored with the feed being ingested, or with its tenant;
+	// this value applies only when neither has one.
+	Validation ValidationPolicy
 }
 
 func NewProcessor() Processor {
@@ -39,13 +133,40 @@ func NewProcessor() Processor {
 	}
 }
 
-func (p Processor) ProcessProduct(prod domain.Product, enabledChannels []string, lookup PreviousHashLookup) (ProductProcessResult, bool, error) {
+func (p Processor) ProcessProduct(prod domain.Product, enabledChannels []string, lookup PreviousStateLookup) (ProductProcessResult, bool, error) {
+	res, valid, err := p.Prepare(prod, enabledChannels)
+	if err != nil || !valid {
+		return res, valid, err
+	}
+
+	// Lookup previous state
+	var prev PreviousState
+	if lookup != nil {
+		prevState, ok, err := lookup(prod.ProductKey)
+		if err != nil {
+			return ProductProcessResult{}, false, err
+		}
+		if ok {
+			prev = prevState
+		}
+	}
+
+	return p.Decide(res, prev), true, nil
+}
+
+// Prepare is the state-independent half of ProcessProduct: normalization, validation
+// and hashing. It is safe to run concurrently. Invalid products come back as final
+// rejected results; valid ones carry Hash and the normalized Product and still need
+// Decide.
+func (p Processor) Prepare(received domain.Product, enabledChannels []string) (ProductProcessResult, bool, error) {
 	res := ProductProcessResult{
-		ProductKey: prod.ProductKey,
+		ProductKey: received.ProductKey,
 	}
 
+	prod := NormalizeProduct(received)
+
 	// Base validation
-	base := ValidateProductBase(prod)
+	base := ValidateProductBase(prod, p.Validation)
 	if !base.IsValid() {
 		res.Disposition = domain.ProductDispositionRejected
 		res.Reason = "base_validation_failed"
@@ -53,43 +174,127 @@ func (p Processor) ProcessProduct(prod domain.Product, enabledChannels []string,
 		return res, false, nil
 	}
 
-	// Channel control validation (only for enabled channels)
-	ch := ValidateChannelControls(prod, enabledChannels)
-	if !ch.IsValid() {
-		res.Disposition = domain.ProductDispositionRejected
-		res.Reason = "channel_validation_failed"
-		res.Issues = append(res.Issues, ch.Issues...)
-		return res, false, nil
+	// Channel validation (only for enabled channels). A product only has to be valid
+	// for one of them; the rest are recorded as rejected on the result. Their issues
+	// are also listed in Issues, under the channel (e.g. "channel.meta.gtin"), so issue
+	// filters and summaries see them whether or not the product was accepted.
+	if len(enabledChannels) > 0 {
+		channels := ValidateChannels(prod, enabledChannels, p.Validation)
+		res.Channels = make(map[string]ChannelResult, len(channels))
+		accepted := 0
+		for _, ch := range normalizeChannels(enabledChannels) {
+			chRes := channels[ch]
+			if chRes.IsValid() {
+				res.Channels[ch] = ChannelResult{Status: ChannelStatusValid}
+				accepted++
+				continue
+			}
+			res.Channels[ch] = ChannelResult{Status: ChannelStatusRejected, Issues: chRes.Issues}
+			res.Issues = append(res.Issues, channelIssues(ch, chRes.Issues)...)
+		}
+
+		if accepted == 0 {
+			res.Disposition = domain.ProductDispositionRejected
+			res.Reason = "channel_validation_failed"
+			return res, false, nil
+		}
 	}
 
-	// Hash normalized
-	hash, err := p.Hasher.HashNormalized(prod)
+	// Hash what was received: the hash version decides whether normalization is part
+	// of the hash.
+	hash, err := p.Hasher.HashNormalized(received)
 	if err != nil {
 		return ProductProcessResult{}, false, err
 	}
 	res.Hash = hash
+	res.HashVersion = p.Hasher.CurrentVersion()
+	res.Product = &prod
+	res.received = &received
 
-	// Lookup previous hash
-	prev := ""
-	if lookup != nil {
-		prevHash, ok, err := lookup(prod.ProductKey)
-		if err != nil {
-			return ProductProcessResult{}, false, err
+	return res, true, nil
+}
+
+// channelIssues places a channel's issues under "channel.<ch>"; issues about the channel
+// block itself already are.
+func channelIssues(ch string, issues []ValidationIssue) []ValidationIssue {
+	prefix := "channel." + ch
+	out := make([]ValidationIssue, len(issues))
+	for i, it := range issues {
+		if it.Path != prefix && !strings.HasPrefix(it.Path, prefix+".") {
+			it.Path = prefix + "." + it.Path
 		}
-		if ok {
-			prev = prevHash
+		out[i] = it
+	}
+	return out
+}
+
+// Decide completes a valid Prepare result against the product's previous state
+// (the zero PreviousState when it has none).
+//
+// When the previous hash was computed with another hash version the product is
+// rehashed with that version: if the old-version hash still matches, the product is
+// unchanged and persisting the result upgrades the stored hash to res.HashVersion.
+// A version this build cannot compute (e.g. written by a newer build) falls back to
+// comparing the hashes as they are.
+func (p Processor) Decide(res ProductProcessResult, prev PreviousState) ProductProcessResult {
+	if prev.Hash != "" && normalizeHashVersion(prev.HashVersion) != normalizeHashVersion(res.HashVersion) {
+		received := res.received
+		if received == nil {
+			received = res.Product
+		}
+		if old, err := p.Hasher.HashWithVersion(*received, prev.HashVersion); err == nil && old == prev.Hash {
+			prev.Hash = res.Hash
+		}
+	}
+
+	// Previous documents may predate normalization; compare like with like so only
+	// real changes are reported.
+	if prev.Product != nil {
+		normalized := NormalizeProduct(*prev.Product)
+		prev.Product = &normalized
+	}
+
+	decision := ComputeProductDelta(prev, *res.Product, res.Hash)
+
+	// The same document is a change when the channels accepting it changed (e.g. a
+	// policy now lets another channel take it), so every channel gets what it accepts.
+	if decision.Disposition == domain.ProductDispositionUnchanged && prev.Channels != nil {
+		if accepted := res.AcceptedChannels(); accepted != nil && !slices.Equal(prev.Channels, accepted) {
+			decision = DeltaDecision{Disposition: domain.ProductDispositionEnqueued, Reason: "channels_changed"}
 		}
 	}
 
-	decision := ComputeDisposition(prev, hash)
 	res.Disposition = decision.Disposition
 	res.Reason = decision.Reason
+	res.ChangedFields = decision.ChangedFields
+	res.ChangeClasses = decision.ChangeClasses
+	return res
+}
 
-	// valid = true
-	return res, true, nil
+// IssueCodeInternalError is the issue code on ProductDispositionError results.
+const IssueCodeInternalError = "internal_error"
+
+// ErrorResult is the result for a product that could not be processed because of
+// err (reason is e.g. "processing_failed").
+func ErrorResult(productKey, reason string, err error) ProductProcessResult {
+	return ProductProcessResult{
+		ProductKey:  productKey,
+		Disposition: domain.ProductDispositionError,
+		Reason:      reason,
+		Issues: []ValidationIssue{
+			{Path: "$", Code: IssueCodeInternalError, Message: err.Error()},
+		},
+	}
+}
+
+// KeylessProductKey is the product_key recorded for the result at batch position i when
+// the product has none (an unparseable line, a missing product_key), so every result of a
+// run keeps a distinct key.
+func KeylessProductKey(i int) string {
+	return fmt.Sprintf("$[%d]", i)
 }
 
-func (p Processor) ProcessProducts(products []domain.Product, enabledChannels []string, lookup PreviousHashLookup) (ProcessOutput, error) {
+func (p Processor) ProcessProducts(products []domain.Product, enabledChannels []string, lookup PreviousStateLookup) (ProcessOutput, error) {
 	out := ProcessOutput{
 		Summary: ProcessSummary{
 			Received: len(products),
@@ -97,11 +302,29 @@ func (p Processor) ProcessProducts(products []domain.Product, enabledChannels []
 		Products: make([]ProductProcessResult, 0, len(products)),
 	}
 
-	for _, prod := range products {
+	keys := make([]string, len(products))
+	for i, prod := range products {
+		keys[i] = prod.ProductKey
+	}
+	dups := ResolveDuplicates(keys, p.Duplicates)
+
+	for i, prod := range products {
+		if !dups.Keep(i) {
+			out.Summary.Duplicates++
+			if res, ok := dups.DuplicateResult(i); ok {
+				out.Products = append(out.Products, res)
+			}
+			continue
+		}
+
 		res, valid, err := p.ProcessProduct(prod, enabledChannels, lookup)
 		if err != nil {
 			return ProcessOutput{}, err
 		}
+		dups.Annotate(i, &res)
+		if res.ProductKey == "" {
+			res.ProductKey = KeylessProductKey(i)
+		}
 
 		out.Products = append(out.Products, res)
 
@@ -80,8 +80,8 @@ func TestProcessor_EnqueuesNewWhenNoPreviousHash(t *testing.T) {
 
 	p := validProductForProcessor("sku1")
 
-	out, err := proc.ProcessProducts([]domain.Product{p}, []string{"google"}, func(productKey string) (string, bool, error) {
-		return "", false, nil
+	out, err := proc.ProcessProducts([]domain.Product{p}, []string{"google"}, func(productKey string) (PreviousState, bool, error) {
+		return PreviousState{}, false, nil
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -111,8 +111,8 @@ func TestProcessor_UnchangedWhenSameHash(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	out, err := proc.ProcessProducts([]domain.Product{p}, []string{"google"}, func(productKey string) (string, bool, error) {
-		return hash, true, nil
+	out, err := proc.ProcessProducts([]domain.Product{p}, []string{"google"}, func(productKey string) (PreviousState, bool, error) {
+		return PreviousState{Hash: hash}, true, nil
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -136,8 +136,8 @@ func TestProcessor_PropagatesLookupError(t *testing.T) {
 	p := validProductForProcessor("sku1")
 	wantErr := errors.New("lookup failed")
 
-	_, err := proc.ProcessProducts([]domain.Product{p}, []string{"google"}, func(productKey string) (string, bool, error) {
-		return "", false, wantErr
+	_, err := proc.ProcessProducts([]domain.Product{p}, []string{"google"}, func(productKey string) (PreviousState, bool, error) {
+		return PreviousState{}, false, wantErr
 	})
 	if err == nil {
 		t.Fatalf("expected error")
@@ -146,3 +146,218 @@ func TestProcessor_PropagatesLookupError(t *testing.T) {
 		t.Fatalf("expected %q, got %q", wantErr.Error(), err.Error())
 	}
 }
+
+func TestProcessor_DuplicatePolicies(t *testing.T) {
+	first := validProductForProcessor("sku1")
+	first.Title = "First"
+	last := validProductForProcessor("sku1")
+	last.Title = "Last"
+	other := validProductForProcessor("sku2")
+
+	batch := []domain.Product{first, other, last}
+
+	cases := []struct {
+		policy    DuplicatePolicy
+		wantTitle string
+	}{
+		{DuplicatePolicyLastWins, "Last"},
+		{DuplicatePolicyFirstWins, "First"},
+	}
+
+	for _, tc := range cases {
+		p := NewProcessor()
+		p.Duplicates = tc.policy
+
+		out, err := p.ProcessProducts(batch, []string{"google"}, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected err: %v", tc.policy, err)
+		}
+		if out.Summary.Received != 3 || out.Summary.Valid != 2 || out.Summary.Duplicates != 1 {
+			t.Fatalf("%s: unexpected summary: %+v", tc.policy, out.Summary)
+		}
+		if len(out.Products) != 2 {
+			t.Fatalf("%s: expected one result per key, got %d", tc.policy, len(out.Products))
+		}
+
+		var kept ProductProcessResult
+		for _, r := range out.Products {
+			if r.ProductKey == "sku1" {
+				kept = r
+			}
+		}
+		if kept.Product == nil || kept.Product.Title != tc.wantTitle {
+			t.Fatalf("%s: expected %q kept, got %+v", tc.policy, tc.wantTitle, kept.Product)
+		}
+		if len(kept.Issues) != 1 || kept.Issues[0].Code != IssueCodeDuplicateProductKey {
+			t.Fatalf("%s: expected duplicate issue on kept result, got %+v", tc.policy, kept.Issues)
+		}
+	}
+}
+
+func TestProcessor_DuplicatesRejectAllByDefault(t *testing.T) {
+	batch := []domain.Product{
+		validProductForProcessor("sku1"),
+		validProductForProcessor("sku2"),
+		validProductForProcessor("sku1"),
+	}
+
+	out, err := NewProcessor().ProcessProducts(batch, []string{"google"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if out.Summary.Valid != 1 || out.Summary.Duplicates != 2 || out.Summary.Rejected != 0 {
+		t.Fatalf("unexpected summary: %+v", out.Summary)
+	}
+	if len(out.Products) != 2 {
+		t.Fatalf("expected one result per key, got %d", len(out.Products))
+	}
+
+	dup := out.Products[0]
+	if dup.ProductKey != "sku1" || dup.Disposition != domain.ProductDispositionDuplicate || dup.Issues[0].Code != IssueCodeDuplicateProductKey {
+		t.Fatalf("unexpected duplicate result: %+v", dup)
+	}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	if p, err := ParseDuplicatePolicy(""); err != nil || p != DuplicatePolicyRejectAll {
+		t.Fatalf("expected default reject_all, got %q %v", p, err)
+	}
+	if _, err := ParseDuplicatePolicy("newest"); err == nil {
+		t.Fatalf("expected error for unknown policy")
+	}
+}
+
+const fakeHashVersion = CurrentHashVersion + 1
+
+// fakeVersionHasher hashes with fakeHashVersion, which differs from v1 on every product,
+// next to the real versions.
+func fakeVersionHasher() Hasher {
+	encoders := make(map[int]hashEncoder, len(hashEncoders)+1)
+	for v, enc := range hashEncoders {
+		encoders[v] = enc
+	}
+	encoders[fakeHashVersion] = func(e *canonicalEncoder, p domain.Product) error {
+		if err := e.encodeProduct(p); err != nil {
+			return err
+		}
+		e.raw(`"fake"`)
+		return nil
+	}
+
+	return Hasher{Version: fakeHashVersion, encoders: encoders}
+}
+
+func TestProcessor_HashVersionMismatch(t *testing.T) {
+	proc := NewProcessor()
+	proc.Hasher = fakeVersionHasher()
+
+	p := validProductForProcessor("sku1")
+	v1, err := Hasher{Version: HashVersionV1}.HashNormalized(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, valid, err := proc.Prepare(p, []string{"google"})
+	if err != nil || !valid {
+		t.Fatalf("prepare: valid=%v err=%v", valid, err)
+	}
+	if res.HashVersion != fakeHashVersion || res.Hash == v1 {
+		t.Fatalf("expected a fake-version hash, got version %d hash %s", res.HashVersion, res.Hash)
+	}
+
+	// Stored v1 hash still matches: unchanged, and the result carries the new hash to store.
+	for _, prevVersion := range []int{0, HashVersionV1} {
+		got := proc.Decide(res, PreviousState{Hash: v1, HashVersion: prevVersion, Product: &p})
+		if got.Disposition != domain.ProductDispositionUnchanged || got.Reason != "no_change_detected" {
+			t.Fatalf("prev version %d: expected unchanged, got %s/%s", prevVersion, got.Disposition, got.Reason)
+		}
+		if got.Hash != res.Hash || got.HashVersion != fakeHashVersion {
+			t.Fatalf("prev version %d: expected upgraded hash, got %+v", prevVersion, got)
+		}
+	}
+
+	// A real change is still detected through the old version.
+	prev := validProductForProcessor("sku1")
+	prev.Title = "Old"
+	oldHash, _ := Hasher{Version: HashVersionV1}.HashNormalized(prev)
+
+	got := proc.Decide(res, PreviousState{Hash: oldHash, HashVersion: HashVersionV1, Product: &prev})
+	if got.Disposition != domain.ProductDispositionEnqueued || got.Reason != "content_changed" {
+		t.Fatalf("expected content_changed, got %s/%s", got.Disposition, got.Reason)
+	}
+	if len(got.ChangedFields) != 1 || got.ChangedFields[0] != "title" {
+		t.Fatalf("expected title changed, got %v", got.ChangedFields)
+	}
+
+	// A version this build cannot compute falls back to comparing hashes as stored.
+	got = proc.Decide(res, PreviousState{Hash: v1, HashVersion: 9, Product: &p})
+	if got.Disposition != domain.ProductDispositionEnqueued {
+		t.Fatalf("expected enqueued for unknown stored version, got %s", got.Disposition)
+	}
+}
+
+func TestProcessor_FormattingOnlyChangesAreUnchanged(t *testing.T) {
+	proc := NewProcessor()
+
+	first := validProductForProcessor("sku1")
+	first.Price.AmountDecimal = "19.90"
+
+	res, valid, err := proc.Prepare(first, []string{"google"})
+	if err != nil || !valid {
+		t.Fatalf("prepare: valid=%v err=%v", valid, err)
+	}
+	if res.Product.Price.AmountDecimal != "19.9" {
+		t.Fatalf("expected the normalized product to be kept, got %+v", res.Product.Price)
+	}
+
+	second := validProductForProcessor("sku1")
+	second.Title = " Test "
+	second.Condition = "NEW"
+	second.Price = domain.Money{AmountDecimal: "19.9", Currency: "usd"}
+
+	out, err := proc.ProcessProducts([]domain.Product{second}, []string{"google"}, func(string) (PreviousState, bool, error) {
+		return PreviousState{Hash: res.Hash, HashVersion: res.HashVersion, Product: res.Product}, true, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Products[0].Disposition != domain.ProductDispositionUnchanged {
+		t.Fatalf("expected unchanged, got %s (%v)", out.Products[0].Disposition, out.Products[0].ChangedFields)
+	}
+}
+
+func TestProcessor_UpgradesV1HashOfUnnormalizedProduct(t *testing.T) {
+	p := validProductForProcessor("sku1")
+	p.Price.AmountDecimal = "19.90"
+	p.Availability = "In_Stock"
+
+	// Stored before normalization: v1 hash and document of the product as received.
+	v1, err := Hasher{Version: HashVersionV1}.HashNormalized(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored := p
+
+	res, _, err := NewProcessor().ProcessProduct(p, []string{"google"}, func(string) (PreviousState, bool, error) {
+		return PreviousState{Hash: v1, Product: &stored}, true, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Disposition != domain.ProductDispositionUnchanged || res.HashVersion != CurrentHashVersion || res.Hash == v1 {
+		t.Fatalf("expected unchanged with an upgraded hash, got %+v", res)
+	}
+
+	// A real change against the same stored state only reports the changed field.
+	p.Title = "New title"
+	res, _, err = NewProcessor().ProcessProduct(p, []string{"google"}, func(string) (PreviousState, bool, error) {
+		return PreviousState{Hash: v1, Product: &stored}, true, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Disposition != domain.ProductDispositionEnqueued || len(res.ChangedFields) != 1 || res.ChangedFields[0] != "title" {
+		t.Fatalf("expected only title changed, got %s %v", res.Disposition, res.ChangedFields)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package ingest
+
+// raceEnabled reports whether the race detector is on; sync.Pool drops items at random
+// under it, so allocation counts are not meaningful.
+const raceEnabled = true
@@ -2,6 +2,7 @@ package ingest
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/ETAnderson/conductor/internal/domain"
@@ -21,7 +22,9 @@ func (r ValidationResult) IsValid() bool {
 	return len(r.Issues) == 0
 }
 
-func ValidateProductBase(p domain.Product) ValidationResult {
+// ValidateProductBase checks the channel-independent fields of p under the validation
+// policy.
+func ValidateProductBase(p domain.Product, policy ValidationPolicy) ValidationResult {
 	var res ValidationResult
 
 	// Required base fields (v1)
@@ -30,59 +33,83 @@ func ValidateProductBase(p domain.Product) ValidationResult {
 	requireNonEmpty(&res, "description", p.Description)
 	requireNonEmpty(&res, "link", p.Link)
 	requireNonEmpty(&res, "image_link", p.ImageLink)
-	requireNonEmpty(&res, "condition", p.Condition)
-	requireNonEmpty(&res, "availability", p.Availability)
+	requireNonEmpty(&res, "condition", string(p.Condition))
+	requireNonEmpty(&res, "availability", string(p.Availability))
 	requireNonEmpty(&res, "price.amount_decimal", p.Price.AmountDecimal)
 	requireNonEmpty(&res, "price.currency", p.Price.Currency)
 
-	// Basic sanity checks (cheap)
-	if p.Price.AmountDecimal != "" && !looksLikeDecimal(p.Price.AmountDecimal) {
-		addIssue(&res, "price.amount_decimal", "invalid_decimal", "amount_decimal must look like a decimal number (e.g. \"19.99\")")
-	}
-	if p.Price.Currency != "" && len(p.Price.Currency) != 3 {
-		addIssue(&res, "price.currency", "invalid_currency", "currency must be a 3-letter ISO code (e.g. \"USD\")")
-	}
+	validateVocabulary(&res, p)
+	validateMoneyFields(&res, p, policy)
+	validateGTIN(&res, p.GTIN)
+	validateLinks(&res, p, policy)
 
 	return res
 }
 
+// ValidateChannelControls is ValidateChannels under the default policy with every
+// channel's issues combined, so it is only valid when all enabledChannels accept p.
 func ValidateChannelControls(p domain.Product, enabledChannels []string) ValidationResult {
+	byChannel := ValidateChannels(p, enabledChannels, ValidationPolicy{})
+
 	var res ValidationResult
+	for _, ch := range normalizeChannels(enabledChannels) {
+		res.Issues = append(res.Issues, byChannel[ch].Issues...)
+	}
+	return res
+}
 
-	enabled := make(map[string]struct{}, len(enabledChannels))
-	for _, c := range enabledChannels {
-		enabled[strings.ToLower(strings.TrimSpace(c))] = struct{}{}
+// normalizeChannels lower-cases and trims channel names and drops repeats.
+func normalizeChannels(channels []string) []string {
+	out := make([]string, 0, len(channels))
+	for _, c := range channels {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if !slices.Contains(out, c) {
+			out = append(out, c)
+		}
 	}
+	return out
+}
 
-	// For each enabled channel: require presence and validate control.state
-	for ch := range enabled {
-		switch ch {
-		case "google":
-			if p.Channel.Google == nil {
-				addIssue(&res, "channel.google", "missing_channel_block", "google channel block is required because google is enabled for this feed")
-				continue
-			}
-			validateControlState(&res, "channel.google.control.state", p.Channel.Google.Control.State)
-		case "meta":
-			if p.Channel.Meta == nil {
-				addIssue(&res, "channel.meta", "missing_channel_block", "meta channel block is required because meta is enabled for this feed")
-				continue
-			}
-			validateControlState(&res, "channel.meta.control.state", p.Channel.Meta.Control.State)
-		case "yotpo":
-			if p.Channel.Yotpo == nil {
-				addIssue(&res, "channel.yotpo", "missing_channel_block", "yotpo channel block is required because yotpo is enabled for this feed")
-				continue
-			}
-			validateControlState(&res, "channel.yotpo.control.state", p.Channel.Yotpo.Control.State)
-		default:
-			// Unknown channel enabled at feed level.
-			// We don't fail the product, but we do record a warning-like issue.
-			addIssue(&res, fmt.Sprintf("channel.%s", ch), "unknown_channel", "channel is enabled but not recognized by this service version")
+func validateChannelControl(res *ValidationResult, p domain.Product, ch string) {
+	switch ch {
+	case "google":
+		if p.Channel.Google == nil {
+			addIssue(res, "channel.google", "missing_channel_block", "google channel block is required because google is enabled for this feed")
+			return
+		}
+		validateControlState(res, "channel.google.control.state", p.Channel.Google.Control.State)
+	case "meta":
+		if p.Channel.Meta == nil {
+			addIssue(res, "channel.meta", "missing_channel_block", "meta channel block is required because meta is enabled for this feed")
+			return
+		}
+		validateControlState(res, "channel.meta.control.state", p.Channel.Meta.Control.State)
+	case "yotpo":
+		if p.Channel.Yotpo == nil {
+			addIssue(res, "channel.yotpo", "missing_channel_block", "yotpo channel block is required because yotpo is enabled for this feed")
+			return
 		}
+		validateControlState(res, "channel.yotpo.control.state", p.Channel.Yotpo.Control.State)
+	default:
+		// Unknown channel enabled at feed level.
+		// We don't fail the product, but we do record a warning-like issue.
+		addIssue(res, fmt.Sprintf("channel.%s", ch), "unknown_channel", "channel is enabled but not recognized by this service version")
 	}
+}
 
-	return res
+// validateVocabulary rejects condition and availability values that are neither
+// canonical nor an accepted alias.
+func validateVocabulary(res *ValidationResult, p domain.Product) {
+	if p.Condition != "" {
+		if _, ok := domain.ParseCondition(string(p.Condition)); !ok {
+			addIssue(res, "condition", "invalid_condition", "condition must be one of: new, refurbished, used")
+		}
+	}
+	if p.Availability != "" {
+		if _, ok := domain.ParseAvailability(string(p.Availability)); !ok {
+			addIssue(res, "availability", "invalid_availability", "availability must be one of: in_stock, out_of_stock, preorder, backorder")
+		}
+	}
 }
 
 func validateControlState(res *ValidationResult, path string, state domain.ChannelLifecycleState) {
@@ -49,7 +49,7 @@ func TestValidateProductBase_RequiredFields(t *testing.T) {
 			p := validBaseProduct()
 			tt.mutate(&p)
 
-			res := ValidateProductBase(p)
+			res := ValidateProductBase(p, ValidationPolicy{})
 			if res.IsValid() {
 				t.Fatalf("expected invalid result")
 			}
@@ -66,7 +66,7 @@ func TestValidateProductBase_DecimalAndCurrency(t *testing.T) {
 		p := validBaseProduct()
 		p.Price.AmountDecimal = "19.9.9"
 
-		res := ValidateProductBase(p)
+		res := ValidateProductBase(p, ValidationPolicy{})
 		if res.IsValid() {
 			t.Fatalf("expected invalid result")
 		}
@@ -79,7 +79,7 @@ func TestValidateProductBase_DecimalAndCurrency(t *testing.T) {
 		p := validBaseProduct()
 		p.Price.Currency = "US"
 
-		res := ValidateProductBase(p)
+		res := ValidateProductBase(p, ValidationPolicy{})
 		if res.IsValid() {
 			t.Fatalf("expected invalid result")
 		}
@@ -89,11 +89,11 @@ func TestValidateProductBase_DecimalAndCurrency(t *testing.T) {
 	})
 }
 
-func TestValidateChannelControls_RequiredChannelBlock(t *testing.T) {
+func TestValidateChannels_RequiredChannelBlock(t *testing.T) {
 	p := validBaseProduct()
 	p.Channel.Google = nil
 
-	res := ValidateChannelControls(p, []string{"google"})
+	res := ValidateChannels(p, []string{"google"}, ValidationPolicy{})["google"]
 	if res.IsValid() {
 		t.Fatalf("expected invalid result")
 	}
@@ -102,11 +102,11 @@ func TestValidateChannelControls_RequiredChannelBlock(t *testing.T) {
 	}
 }
 
-func TestValidateChannelControls_InvalidState(t *testing.T) {
+func TestValidateChannels_InvalidState(t *testing.T) {
 	p := validBaseProduct()
 	p.Channel.Google.Control.State = "bogus"
 
-	res := ValidateChannelControls(p, []string{"google"})
+	res := ValidateChannels(p, []string{"google"}, ValidationPolicy{})["google"]
 	if res.IsValid() {
 		t.Fatalf("expected invalid result")
 	}
@@ -115,10 +115,10 @@ func TestValidateChannelControls_InvalidState(t *testing.T) {
 	}
 }
 
-func TestValidateChannelControls_UnknownEnabledChannel(t *testing.T) {
+func TestValidateChannels_UnknownEnabledChannel(t *testing.T) {
 	p := validBaseProduct()
 
-	res := ValidateChannelControls(p, []string{"notarealchannel"})
+	res := ValidateChannels(p, []string{"notarealchannel"}, ValidationPolicy{})["notarealchannel"]
 	if res.IsValid() {
 		t.Fatalf("expected invalid/issue result (unknown channel should emit issue)")
 	}
@@ -127,6 +127,20 @@ func TestValidateChannelControls_UnknownEnabledChannel(t *testing.T) {
 	}
 }
 
+func TestValidateChannelControls_CombinesChannels(t *testing.T) {
+	p := validBaseProduct()
+	p.Channel.Meta = nil
+
+	if res := ValidateChannelControls(p, []string{"google"}); !res.IsValid() {
+		t.Fatalf("expected valid result, got %#v", res.Issues)
+	}
+
+	res := ValidateChannelControls(p, []string{"google", "meta"})
+	if res.IsValid() || !hasIssueCode(res, "missing_channel_block") {
+		t.Fatalf("expected missing_channel_block for meta, got %#v", res.Issues)
+	}
+}
+
 func hasIssuePath(res ValidationResult, path string) bool {
 	for _, it := range res.Issues {
 		if it.Path == path {
@@ -0,0 +1,73 @@
+package ingest
+
+import "strings"
+
+// ValidationPolicy holds the optional validation switches. Tenants and feeds store
+// their own (see state.Feed); ingest applies the feed's policy, falling back to the
+// tenant's. The zero value is the strict default.
+type ValidationPolicy struct {
+	// AllowZeroPrice accepts price and sale_price amounts of zero (e.g. free samples).
+	AllowZeroPrice bool `json:"allow_zero_price,omitempty"`
+
+	// IdentifierChannels lists the channels that enforce the identifier-exists rule
+	// (ChannelProfile.RequireIdentifiers). Google requires it for any product that
+	// has manufacturer-assigned identifiers.
+	IdentifierChannels []string `json:"identifier_channels,omitempty"`
+
+	// RequireHTTPS rejects http:// links and image links.
+	RequireHTTPS bool `json:"require_https,omitempty"`
+
+	// LinkDomains, when set, restricts landing-page links (link) to these tenant
+	// domains and their subdomains. Image links may point at any host (e.g. a CDN).
+	LinkDomains []string `json:"link_domains,omitempty"`
+
+	// MaxAdditionalImages overrides the channel profile's cap on
+	// additional_image_links (lower-case channel name to limit).
+	MaxAdditionalImages map[string]int `json:"max_additional_images,omitempty"`
+
+	// Profiles replaces DefaultChannelProfiles for the channels it lists (lower-case
+	// channel name to profile).
+	Profiles map[string]ChannelProfile `json:"profiles,omitempty"`
+}
+
+// ChannelProfile is the profile validation applies for channel under this policy:
+// Profiles or the default, with IdentifierChannels and MaxAdditionalImages applied.
+// ok is false for channels without a profile.
+func (fp ValidationPolicy) ChannelProfile(channel string) (prof ChannelProfile, ok bool) {
+	prof, ok = fp.Profiles[channel]
+	if !ok {
+		prof, ok = DefaultChannelProfiles[channel]
+	}
+	if !ok {
+		return ChannelProfile{}, false
+	}
+
+	if fp.requiresIdentifiers(channel) {
+		prof.RequireIdentifiers = true
+	}
+	if limit, set := fp.MaxAdditionalImages[channel]; set {
+		prof.MaxAdditionalImages = limit
+	}
+	return prof, true
+}
+
+// allowsLinkHost reports whether host is one of LinkDomains or a subdomain of one.
+func (fp ValidationPolicy) allowsLinkHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, d := range fp.LinkDomains {
+		d = strings.ToLower(strings.Trim(strings.TrimSpace(d), "."))
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (fp ValidationPolicy) requiresIdentifiers(channel string) bool {
+	for _, c := range fp.IdentifierChannels {
+		if strings.EqualFold(strings.TrimSpace(c), channel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package state
+
+// runQueueKey identifies the ordering domain for runs: pushes for the same
+// tenant and feed must reach channels in ingest order.
+type runQueueKey struct {
+	tenantID uint64
+	feedID   uint64 // 0 when the run has no feed
+}
+
+func queueKeyOf(tenantID uint64, feedID *uint64) runQueueKey {
+	k := runQueueKey{tenantID: tenantID}
+	if feedID != nil {
+		k.feedID = *feedID
+	}
+	return k
+}
+
+// claimPlan is the outcome of planning one claim pass.
+type claimPlan struct {
+	Claims []RunClaim
+
+	// Superseded maps an older pending run_id to the claimed run that carries its changes.
+	Superseded map[string]string
+}
+
+// planClaims picks which pending runs may be claimed now.
+// pending must be ordered oldest first. Runs for a (tenant, feed) with a run
+// already processing are left alone, so at most one run per key is in flight.
+// For every other key, all pending runs are coalesced: the newest is claimed
+// (its product_state hashes are the latest) and the older ones are superseded by it.
+func planClaims(pending []RunRecord, busy map[runQueueKey]struct{}, limit int) claimPlan {
+	plan := claimPlan{
+		Claims:     make([]RunClaim, 0, limit),
+		Superseded: make(map[string]string),
+	}
+
+	// Group by key, remembering the order keys were first seen (oldest run first)
+	var order []runQueueKey
+	groups := make(map[runQueueKey][]RunRecord)
+	for _, r := range pending {
+		k := queueKeyOf(r.TenantID, r.FeedID)
+		if _, ok := busy[k]; ok {
+			continue
+		}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+
+	for _, k := range order {
+		if len(plan.Claims) >= limit {
+			break
+		}
+
+		runs := groups[k]
+		carrier := runs[len(runs)-1]
+
+		for _, r := range runs[:len(runs)-1] {
+			plan.Superseded[r.RunID] = carrier.RunID
+		}
+
+		plan.Claims = append(plan.Claims, RunClaim{
+			RunID:    carrier.RunID,
+			TenantID: carrier.TenantID,
+			FeedID:   carrier.FeedID,
+		})
+	}
+
+	return plan
+}
@@ -0,0 +1,23 @@
+package state
+
+import "context"
+
+type ctxKey string
+
+const workerIDKey ctxKey = "state_worker_id"
+
+// WithWorkerID tags the context with the worker acting on runs; the store records it
+// on run events written under this context.
+func WithWorkerID(ctx context.Context, workerID string) context.Context {
+	if workerID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, workerIDKey, workerID)
+}
+
+// WorkerID reads the worker ID from context ("" outside a worker).
+func WorkerID(ctx context.Context) string {
+	v := ctx.Value(workerIDKey)
+	s, _ := v.(string)
+	return s
+}
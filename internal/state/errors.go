@@ -0,0 +1,48 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+var (
+	ErrRunNotFound = errors.New("run not found")
+
+	// ErrRunExists is returned when committing a run whose run_id is already recorded.
+	ErrRunExists = errors.New("run already exists")
+
+	// ErrRunNotCancellable is returned when cancelling a run that already finished.
+	ErrRunNotCancellable = errors.New("run is not cancellable")
+
+	// ErrRunNotClaimed is returned when renewing the claim of a run that is no longer processing.
+	ErrRunNotClaimed = errors.New("run is not claimed")
+
+	// ErrRunCancelled is returned by executors that stopped because cancellation was requested.
+	ErrRunCancelled = errors.New("run cancelled")
+
+	ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+
+	ErrFeedNotFound   = errors.New("feed not found")
+	ErrTenantNotFound = errors.New("tenant not found")
+
+	// ErrInvalidRunTransition is matched (errors.Is) by every *RunTransitionError.
+	ErrInvalidRunTransition = errors.New("invalid run status transition")
+)
+
+// RunTransitionError reports a status change the run lifecycle does not allow,
+// e.g. completing a run that was never claimed.
+type RunTransitionError struct {
+	RunID string
+	From  domain.RunStatus
+	To    domain.RunStatus
+}
+
+func (e *RunTransitionError) Error() string {
+	return fmt.Sprintf("run %s: cannot transition from %s to %s", e.RunID, e.From, e.To)
+}
+
+func (e *RunTransitionError) Is(target error) bool {
+	return target == ErrInvalidRunTransition
+}
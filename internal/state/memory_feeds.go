@@ -0,0 +1,90 @@
+package state
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/ingest"
+)
+
+func (s *MemoryStore) CreateFeed(ctx context.Context, f Feed) (Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextFeedID++
+	f.ID = s.nextFeedID
+	f.Validation = copyValidationPolicy(f.Validation)
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now().UTC()
+	}
+
+	s.feeds[f.ID] = f
+	return f, nil
+}
+
+func (s *MemoryStore) GetFeed(ctx context.Context, tenantID uint64, feedID uint64) (Feed, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.feeds[feedID]
+	if !ok || f.TenantID != tenantID {
+		return Feed{}, false, nil
+	}
+	f.Validation = copyValidationPolicy(f.Validation)
+	return f, true, nil
+}
+
+func (s *MemoryStore) ListFeeds(ctx context.Context, tenantID uint64) ([]Feed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []Feed
+	for _, f := range s.feeds {
+		if f.TenantID == tenantID {
+			f.Validation = copyValidationPolicy(f.Validation)
+			out = append(out, f)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *MemoryStore) UpdateFeedValidation(ctx context.Context, tenantID uint64, feedID uint64, policy *ingest.ValidationPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.feeds[feedID]
+	if !ok || f.TenantID != tenantID {
+		return ErrFeedNotFound
+	}
+
+	f.Validation = copyValidationPolicy(policy)
+	s.feeds[feedID] = f
+	return nil
+}
+
+// copyValidationPolicy keeps callers from sharing the stored policy's slices and maps.
+func copyValidationPolicy(p *ingest.ValidationPolicy) *ingest.ValidationPolicy {
+	if p == nil {
+		return nil
+	}
+
+	cp := *p
+	cp.IdentifierChannels = append([]string(nil), p.IdentifierChannels...)
+	cp.LinkDomains = append([]string(nil), p.LinkDomains...)
+	if p.MaxAdditionalImages != nil {
+		cp.MaxAdditionalImages = make(map[string]int, len(p.MaxAdditionalImages))
+		for k, v := range p.MaxAdditionalImages {
+			cp.MaxAdditionalImages[k] = v
+		}
+	}
+	if p.Profiles != nil {
+		cp.Profiles = make(map[string]ingest.ChannelProfile, len(p.Profiles))
+		for k, v := range p.Profiles {
+			cp.Profiles[k] = v
+		}
+	}
+	return &cp
+}
@@ -3,84 +3,376 @@ package state
 import (
 	"context"
 	"sort"
-)
+	"time"
 
-func (s *MemoryStore) ClaimRuns(ctx context.Context, limit int) ([]RunClaim, error) {
-	_ = ctx
+	"github.com/ETAnderson/conductor/internal/domain"
+)
 
+func (s *MemoryStore) ClaimRuns(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]RunClaim, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	now = now.UTC()
+	leaseEnd := now.Add(lease)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var candidates []RunRecord
+	var pending, expired []RunRecord
+	busy := make(map[runQueueKey]struct{})
 	for _, r := range s.runs {
-		if r.Status == "has_changes" && r.PushTriggered && r.TenantID != 0 {
-			candidates = append(candidates, r)
+		if r.TenantID == 0 {
+			continue
+		}
+		switch {
+		case r.Status == string(domain.RunStatusProcessing):
+			// Reclaimed runs keep their key busy, so pending runs still wait behind them
+			busy[queueKeyOf(r.TenantID, r.FeedID)] = struct{}{}
+			if r.ClaimExpiresAt != nil && !r.ClaimExpiresAt.After(now) {
+				expired = append(expired, r)
+			}
+		case r.Status == string(domain.RunStatusHasChanges) && r.PushTriggered:
+			pending = append(pending, r)
 		}
 	}
 
-	// Oldest first (stable-ish order for consistent processing)
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].CreatedAt.Before(candidates[j].CreatedAt)
+	// Expired claims first, longest expired first
+	sort.Slice(expired, func(i, j int) bool {
+		if !expired[i].ClaimExpiresAt.Equal(*expired[j].ClaimExpiresAt) {
+			return expired[i].ClaimExpiresAt.Before(*expired[j].ClaimExpiresAt)
+		}
+		return expired[i].RunID < expired[j].RunID
 	})
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	reclaimed := make([]RunClaim, 0, len(expired))
+	for _, r := range expired {
+		s.appendRunEventLocked(ctx, RunEvent{
+			RunID:     r.RunID,
+			TenantID:  r.TenantID,
+			Type:      domain.RunEventClaimExpired,
+			Details:   map[string]any{"expired_at": r.ClaimExpiresAt.Format(time.RFC3339Nano)},
+			CreatedAt: now,
+		})
 
-	if len(candidates) > limit {
-		candidates = candidates[:limit]
+		claimedAt, expiresAt := now, leaseEnd
+		r.ClaimedAt = &claimedAt
+		r.ClaimExpiresAt = &expiresAt
+		s.runs[r.RunID] = r
+
+		reclaimed = append(reclaimed, RunClaim{RunID: r.RunID, TenantID: r.TenantID, FeedID: r.FeedID})
 	}
 
-	out := make([]RunClaim, 0, len(candidates))
-	for _, r := range candidates {
+	// Oldest first (run_id breaks ties so order is deterministic)
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].CreatedAt.Equal(pending[j].CreatedAt) {
+			return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+		}
+		return pending[i].RunID < pending[j].RunID
+	})
+
+	plan := planClaims(pending, busy, limit-len(reclaimed))
+
+	for runID, carrier := range plan.Superseded {
+		r, err := s.transitionRunLocked(ctx, s.runs[runID].TenantID, runID, domain.RunStatusSuperseded, now,
+			map[string]any{"superseded_by": carrier})
+		if err != nil {
+			return nil, err
+		}
+		r.SupersededBy = carrier
+		s.runs[runID] = r
+	}
+
+	for _, c := range plan.Claims {
 		// Mark claimed
-		r.Status = "processing"
-		s.runs[r.RunID] = r
+		r, err := s.transitionRunLocked(ctx, c.TenantID, c.RunID, domain.RunStatusProcessing, now, nil)
+		if err != nil {
+			return nil, err
+		}
+		expiresAt := leaseEnd
+		r.ClaimExpiresAt = &expiresAt
+		s.runs[c.RunID] = r
+	}
 
-		out = append(out, RunClaim{
-			RunID:    r.RunID,
-			TenantID: r.TenantID,
-		})
+	return append(reclaimed, plan.Claims...), nil
+}
+
+func (s *MemoryStore) RenewRunClaim(ctx context.Context, tenantID uint64, runID string, now time.Time, lease time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.runs[runID]
+	if !ok || r.TenantID != tenantID {
+		return ErrRunNotFound
+	}
+	if r.Status != string(domain.RunStatusProcessing) {
+		return ErrRunNotClaimed
 	}
 
+	expiresAt := now.UTC().Add(lease)
+	r.ClaimExpiresAt = &expiresAt
+	s.runs[runID] = r
+	return nil
+}
+
+func (s *MemoryStore) ListSupersededRuns(ctx context.Context, tenantID uint64, runID string) ([]RunRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []RunRecord
+	for _, r := range s.runs {
+		if r.TenantID == tenantID && r.SupersededBy == runID {
+			out = append(out, r)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].RunID < out[j].RunID
+	})
+
 	return out, nil
 }
 
-func (s *MemoryStore) CompleteRun(ctx context.Context, tenantID uint64, runID string) error {
-	_ = ctx
+func (s *MemoryStore) CompleteRun(ctx context.Context, tenantID uint64, runID string, outbox RunOutbox) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.runs[runID]
+	if !ok || r.TenantID != tenantID {
+		return ErrRunNotFound
+	}
+
+	next := r
+	if err := applyRunTransition(&next, completedStatus(r.Errored), time.Now().UTC()); err != nil {
+		return err
+	}
+	events, err := runOutboxEvents(outbox, next)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.transitionRunLocked(ctx, tenantID, runID, completedStatus(r.Errored), *next.FinishedAt, nil); err != nil {
+		return err
+	}
+	s.enqueueWebhookEventsLocked(tenantID, events)
+	return nil
+}
 
+func (s *MemoryStore) FailRun(ctx context.Context, tenantID uint64, runID string, message string, outbox RunOutbox) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	r, ok := s.runs[runID]
-	if !ok {
-		return nil
+	if !ok || r.TenantID != tenantID {
+		return ErrRunNotFound
+	}
+
+	next := r
+	if err := applyRunTransition(&next, domain.RunStatusFailed, time.Now().UTC()); err != nil {
+		return err
 	}
-	if r.TenantID != tenantID {
-		return nil
+	next.ErrorMessage = message
+	events, err := runOutboxEvents(outbox, next)
+	if err != nil {
+		return err
 	}
 
-	r.Status = "completed"
+	r, err = s.transitionRunLocked(ctx, tenantID, runID, domain.RunStatusFailed, *next.FinishedAt,
+		map[string]any{"error": message})
+	if err != nil {
+		return err
+	}
+
+	r.ErrorMessage = message
 	s.runs[runID] = r
+	s.enqueueWebhookEventsLocked(tenantID, events)
 	return nil
 }
 
-func (s *MemoryStore) FailRun(ctx context.Context, tenantID uint64, runID string, message string) error {
-	_ = ctx
-	_ = message
+// transitionRunLocked applies a lifecycle transition to a stored run and records it
+// on the run's timeline. Caller holds s.mu.
+func (s *MemoryStore) transitionRunLocked(ctx context.Context, tenantID uint64, runID string, to domain.RunStatus, now time.Time, details map[string]any) (RunRecord, error) {
+	r, ok := s.runs[runID]
+	if !ok || r.TenantID != tenantID {
+		return RunRecord{}, ErrRunNotFound
+	}
 
+	if err := applyRunTransition(&r, to, now); err != nil {
+		return r, err
+	}
+
+	s.runs[runID] = r
+	s.appendRunEventLocked(ctx, RunEvent{
+		RunID:     runID,
+		TenantID:  tenantID,
+		Type:      domain.RunEventStatusChanged,
+		Status:    string(to),
+		Details:   details,
+		CreatedAt: now,
+	})
+	return r, nil
+}
+
+func (s *MemoryStore) CancelRun(ctx context.Context, tenantID uint64, runID string) (RunRecord, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	r, ok := s.runs[runID]
-	if !ok {
-		return nil
+	if !ok || r.TenantID != tenantID {
+		return RunRecord{}, ErrRunNotFound
 	}
-	if r.TenantID != tenantID {
-		return nil
+
+	switch r.Status {
+	case string(domain.RunStatusHasChanges):
+		cancelled, err := s.transitionRunLocked(ctx, tenantID, runID, domain.RunStatusCancelled, time.Now().UTC(), nil)
+		if err != nil {
+			return r, err
+		}
+		r = cancelled
+		s.rollbackProductStateLocked(tenantID, []string{runID})
+
+	case string(domain.RunStatusProcessing):
+		r.CancelRequested = true
+		s.runs[runID] = r
+		s.appendRunEventLocked(ctx, RunEvent{
+			RunID:     runID,
+			TenantID:  tenantID,
+			Type:      domain.RunEventCancelRequested,
+			CreatedAt: time.Now().UTC(),
+		})
+
+	default:
+		return r, ErrRunNotCancellable
 	}
 
-	r.Status = "failed"
-	s.runs[runID] = r
+	return r, nil
+}
+
+func (s *MemoryStore) MarkRunCancelled(ctx context.Context, tenantID uint64, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.runs[runID]
+	if !ok || r.TenantID != tenantID {
+		return ErrRunNotFound
+	}
+	if r.Status != string(domain.RunStatusProcessing) {
+		return ErrRunNotCancellable
+	}
+	if _, err := s.transitionRunLocked(ctx, tenantID, runID, domain.RunStatusCancelled, time.Now().UTC(), nil); err != nil {
+		return err
+	}
+
+	// Changes coalesced into this run were never pushed either
+	runIDs := []string{runID}
+	for _, other := range s.runs {
+		if other.TenantID == tenantID && other.SupersededBy == runID {
+			runIDs = append(runIDs, other.RunID)
+		}
+	}
+	s.rollbackProductStateLocked(tenantID, runIDs)
+
+	return nil
+}
+
+// rollbackProductStateLocked undoes the product_state advanced by cancelled runs so those
+// products are re-evaluated on the next ingest. A product is only rolled back while its
+// state still holds the hash a cancelled run wrote; newer changes are left alone.
+func (s *MemoryStore) rollbackProductStateLocked(tenantID uint64, runIDs []string) {
+	cancelled := make(map[string]struct{}, len(runIDs))
+	for _, id := range runIDs {
+		cancelled[id] = struct{}{}
+	}
+
+	states := s.productState[tenantID]
+	versions := s.productVersions[tenantID]
+
+	for _, runID := range runIDs {
+		for _, p := range s.runProducts[runID] {
+			if p.Disposition != domain.ProductDispositionEnqueued {
+				continue
+			}
+
+			cur, ok := states[p.ProductKey]
+			if !ok || cur.Hash != p.Hash {
+				continue
+			}
+
+			kept := versions[p.ProductKey][:0]
+			for _, v := range versions[p.ProductKey] {
+				if _, drop := cancelled[v.RunID]; !drop {
+					kept = append(kept, v)
+				}
+			}
+			if versions != nil {
+				versions[p.ProductKey] = kept
+			}
+
+			if len(kept) == 0 {
+				delete(states, p.ProductKey)
+				continue
+			}
+
+			prev := kept[len(kept)-1]
+			states[p.ProductKey] = ProductStateRecord{
+				ProductKey:  p.ProductKey,
+				Hash:        prev.Hash,
+				HashVersion: prev.HashVersion,
+				LastRunID:   prev.RunID,
+				Product:     prev.Product,
+				UpdatedAt:   time.Now().UTC(),
+			}
+		}
+	}
+}
+
+func (s *MemoryStore) AppendRunEvent(ctx context.Context, ev RunEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.runs[ev.RunID]
+	if !ok || r.TenantID != ev.TenantID {
+		return ErrRunNotFound
+	}
+
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = time.Now().UTC()
+	}
+	s.appendRunEventLocked(ctx, ev)
 	return nil
 }
+
+func (s *MemoryStore) ListRunEvents(ctx context.Context, tenantID uint64, runID string) ([]RunEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []RunEvent
+	for _, ev := range s.runEvents[runID] {
+		if ev.TenantID == tenantID {
+			out = append(out, ev)
+		}
+	}
+	return out, nil
+}
+
+// WatchRunEvents implements RunEventWatcher.
+func (s *MemoryStore) WatchRunEvents(tenantID uint64, runID string) (<-chan struct{}, func()) {
+	return s.eventHub.watch(tenantID, runID)
+}
+
+// appendRunEventLocked assigns the next event ID, tags the worker from ctx and wakes
+// watchers. Caller holds s.mu.
+func (s *MemoryStore) appendRunEventLocked(ctx context.Context, ev RunEvent) {
+	s.nextEventID++
+	ev.EventID = s.nextEventID
+	if ev.WorkerID == "" {
+		ev.WorkerID = WorkerID(ctx)
+	}
+	s.runEvents[ev.RunID] = append(s.runEvents[ev.RunID], ev)
+	s.eventHub.publish(ev.TenantID, ev.RunID)
+}
@@ -5,30 +5,55 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/ETAnderson/conductor/internal/domain"
 	"github.com/ETAnderson/conductor/internal/ingest"
 )
 
 type MemoryStore struct {
 	mu sync.RWMutex
 
-	productHash map[uint64]map[string]string
+	productState    map[uint64]map[string]ProductStateRecord
+	productVersions map[uint64]map[string][]ProductVersion
 
 	runs        map[string]RunRecord
 	runProducts map[string][]ingest.ProductProcessResult
+	runEvents   map[string][]RunEvent
+	nextEventID uint64
+	eventHub    *runEventHub
+
+	feeds      map[uint64]Feed
+	nextFeedID uint64
+
+	tenantValidation map[uint64]*ingest.ValidationPolicy
+
+	webhookEndpoints  map[uint64]WebhookEndpoint
+	webhookDeliveries map[uint64]WebhookDelivery
+	nextWebhookID     uint64
 
 	idem map[uint64]map[string]map[string]IdempotencyRecord // tenant -> endpoint -> keyhash -> record
 }
 
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		productHash: make(map[uint64]map[string]string),
-		runs:        make(map[string]RunRecord),
-		runProducts: make(map[string][]ingest.ProductProcessResult),
-		idem:        make(map[uint64]map[string]map[string]IdempotencyRecord),
+		productState:     make(map[uint64]map[string]ProductStateRecord),
+		productVersions:  make(map[uint64]map[string][]ProductVersion),
+		runs:             make(map[string]RunRecord),
+		runProducts:      make(map[string][]ingest.ProductProcessResult),
+		runEvents:        make(map[string][]RunEvent),
+		eventHub:         newRunEventHub(),
+		idem:             make(map[uint64]map[string]map[string]IdempotencyRecord),
+		feeds:            make(map[uint64]Feed),
+		tenantValidation: make(map[uint64]*ingest.ValidationPolicy),
+
+		webhookEndpoints:  make(map[uint64]WebhookEndpoint),
+		webhookDeliveries: make(map[uint64]WebhookDelivery),
 	}
 }
 
@@ -36,42 +61,283 @@ func (s *MemoryStore) GetProductHash(ctx context.Context, tenantID uint64, produ
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	m, ok := s.productHash[tenantID]
+	m, ok := s.productState[tenantID]
 	if !ok {
 		return "", false, nil
 	}
-	h, ok := m[productKey]
-	return h, ok, nil
+	rec, ok := m[productKey]
+	return rec.Hash, ok, nil
 }
 
 func (s *MemoryStore) UpsertProductHash(ctx context.Context, tenantID uint64, productKey string, hash string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	m, ok := s.productHash[tenantID]
-	if !ok {
-		m = make(map[string]string)
-		s.productHash[tenantID] = m
+	m := s.tenantProductStateLocked(tenantID)
+
+	rec := m[productKey]
+	rec.ProductKey = productKey
+	rec.Hash = hash
+	rec.UpdatedAt = time.Now().UTC()
+	m[productKey] = rec
+	return nil
+}
+
+func (s *MemoryStore) UpsertProductState(ctx context.Context, tenantID uint64, rec ProductStateRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.upsertProductStateLocked(tenantID, rec)
+	return nil
+}
+
+func (s *MemoryStore) upsertProductStateLocked(tenantID uint64, rec ProductStateRecord) {
+	if rec.UpdatedAt.IsZero() {
+		rec.UpdatedAt = time.Now().UTC()
+	}
+	if rec.Product != nil {
+		cp := *rec.Product
+		rec.Product = &cp
+	}
+	rec.Channels = slices.Clone(rec.Channels)
+
+	s.tenantProductStateLocked(tenantID)[rec.ProductKey] = rec
+}
+
+func (s *MemoryStore) GetProductState(ctx context.Context, tenantID uint64, productKey string) (ProductStateRecord, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.productState[tenantID][productKey]
+	return rec, ok, nil
+}
+
+func (s *MemoryStore) GetProductStates(ctx context.Context, tenantID uint64, productKeys []string) (map[string]ProductStateRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := s.productState[tenantID]
+	out := make(map[string]ProductStateRecord, len(productKeys))
+	for _, k := range productKeys {
+		if rec, ok := m[k]; ok {
+			out[k] = rec
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) UpsertProductStates(ctx context.Context, tenantID uint64, recs []ProductStateRecord) error {
+	for _, rec := range recs {
+		if err := s.UpsertProductState(ctx, tenantID, rec); err != nil {
+			return err
+		}
 	}
-	m[productKey] = hash
 	return nil
 }
 
+func (s *MemoryStore) ListProductStates(ctx context.Context, tenantID uint64, afterKey string, limit int) ([]ProductStateRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := s.productState[tenantID]
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k > afterKey {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	if limit > 0 && limit < len(keys) {
+		keys = keys[:limit]
+	}
+
+	out := make([]ProductStateRecord, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, m[k])
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) AppendProductVersion(ctx context.Context, tenantID uint64, v ProductVersion) (ProductVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.appendProductVersionLocked(tenantID, v), nil
+}
+
+func (s *MemoryStore) appendProductVersionLocked(tenantID uint64, v ProductVersion) ProductVersion {
+	m, ok := s.productVersions[tenantID]
+	if !ok {
+		m = make(map[string][]ProductVersion)
+		s.productVersions[tenantID] = m
+	}
+
+	// A retried append returns the version the run already added
+	if v.RunID != "" {
+		for _, existing := range m[v.ProductKey] {
+			if existing.RunID == v.RunID {
+				return existing
+			}
+		}
+	}
+
+	// Numbered after the highest one kept, as MySQL does: a rollback can remove
+	// versions from the middle of the history
+	v.Version = 1
+	for _, existing := range m[v.ProductKey] {
+		if existing.Version >= v.Version {
+			v.Version = existing.Version + 1
+		}
+	}
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now().UTC()
+	}
+	if v.Product != nil {
+		cp := *v.Product
+		v.Product = &cp
+	}
+
+	m[v.ProductKey] = append(m[v.ProductKey], v)
+	return v
+}
+
+func (s *MemoryStore) AppendProductVersions(ctx context.Context, tenantID uint64, vs []ProductVersion) ([]ProductVersion, error) {
+	out := make([]ProductVersion, 0, len(vs))
+	for _, v := range vs {
+		appended, err := s.AppendProductVersion(ctx, tenantID, v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, appended)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListProductVersions(ctx context.Context, tenantID uint64, productKey string, limit int) ([]ProductVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := s.productVersions[tenantID][productKey]
+
+	// Newest first
+	out := make([]ProductVersion, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		out = append(out, items[i])
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) GetProductVersion(ctx context.Context, tenantID uint64, productKey string, version int) (ProductVersion, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, v := range s.productVersions[tenantID][productKey] {
+		if v.Version == version {
+			return v, true, nil
+		}
+	}
+	return ProductVersion{}, false, nil
+}
+
+func (s *MemoryStore) tenantProductStateLocked(tenantID uint64) map[string]ProductStateRecord {
+	m, ok := s.productState[tenantID]
+	if !ok {
+		m = make(map[string]ProductStateRecord)
+		s.productState[tenantID] = m
+	}
+	return m
+}
+
 func (s *MemoryStore) InsertRun(ctx context.Context, run RunRecord) error {
+	if err := validateRunStatus(run.Status); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.runs[run.RunID] = run
+	s.insertRunLocked(ctx, run)
 	return nil
 }
 
+func (s *MemoryStore) insertRunLocked(ctx context.Context, run RunRecord) {
+	s.runs[run.RunID] = run
+
+	createdAt := run.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+	s.appendRunEventLocked(ctx, RunEvent{
+		RunID:     run.RunID,
+		TenantID:  run.TenantID,
+		Type:      domain.RunEventStatusChanged,
+		Status:    run.Status,
+		CreatedAt: createdAt,
+	})
+}
+
 func (s *MemoryStore) InsertRunProducts(ctx context.Context, runID string, products []ingest.ProductProcessResult) error {
+	if err := checkRunProductKeys(runID, products); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.insertRunProductsLocked(runID, products)
+	return nil
+}
+
+func (s *MemoryStore) insertRunProductsLocked(runID string, products []ingest.ProductProcessResult) {
 	cp := make([]ingest.ProductProcessResult, len(products))
 	copy(cp, products)
 	s.runProducts[runID] = cp
+}
+
+// checkRunProductKeys enforces the run_products primary key: one row per product_key
+// (compared byte for byte) in a run.
+func checkRunProductKeys(runID string, products []ingest.ProductProcessResult) error {
+	seen := make(map[string]struct{}, len(products))
+	for _, p := range products {
+		if _, dup := seen[p.ProductKey]; dup {
+			return fmt.Errorf("run %s already has product %q", runID, p.ProductKey)
+		}
+		seen[p.ProductKey] = struct{}{}
+	}
+	return nil
+}
+
+// CommitIngestRun applies the whole ingest run under one lock: everything is checked
+// before anything is written.
+func (s *MemoryStore) CommitIngestRun(ctx context.Context, in IngestRun) error {
+	if err := validateRunStatus(in.Run.Status); err != nil {
+		return err
+	}
+	if err := checkRunProductKeys(in.Run.RunID, in.Products); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.runs[in.Run.RunID]; exists {
+		return fmt.Errorf("run %s: %w", in.Run.RunID, ErrRunExists)
+	}
+
+	s.insertRunLocked(ctx, in.Run)
+	s.insertRunProductsLocked(in.Run.RunID, in.Products)
+	for _, w := range in.States {
+		s.upsertProductStateLocked(in.Run.TenantID, w.State)
+		if w.Version != nil {
+			s.appendProductVersionLocked(in.Run.TenantID, *w.Version)
+		}
+	}
+	s.enqueueWebhookEventsLocked(in.Run.TenantID, in.Webhooks)
 	return nil
 }
 
@@ -169,7 +435,7 @@ func (s *MemoryStore) GetRun(ctx context.Context, tenantID uint64, runID string)
 	return r, true, nil
 }
 
-func (s *MemoryStore) ListRunProducts(ctx context.Context, runID string, limit int) ([]ingest.ProductProcessResult, error) {
+func (s *MemoryStore) ListRunProducts(ctx context.Context, runID string, filter RunProductFilter, limit int) ([]ingest.ProductProcessResult, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -178,8 +444,12 @@ func (s *MemoryStore) ListRunProducts(ctx context.Context, runID string, limit i
 		return []ingest.ProductProcessResult{}, nil
 	}
 
-	out := make([]ingest.ProductProcessResult, len(items))
-	copy(out, items)
+	out := make([]ingest.ProductProcessResult, 0, len(items))
+	for _, p := range items {
+		if filter.matches(p) {
+			out = append(out, p)
+		}
+	}
 
 	// stable ordering for predictability
 	sort.Slice(out, func(i, j int) bool {
@@ -191,3 +461,49 @@ func (s *MemoryStore) ListRunProducts(ctx context.Context, runID string, limit i
 	}
 	return out[:limit], nil
 }
+
+func (s *MemoryStore) EachRunProduct(ctx context.Context, runID string, fn func(ingest.ProductProcessResult) error) error {
+	s.mu.RLock()
+	items := append([]ingest.ProductProcessResult(nil), s.runProducts[runID]...)
+	s.mu.RUnlock()
+
+	for _, p := range items {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) SummarizeRunIssues(ctx context.Context, runID string, sampleSize int) ([]RunIssueSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return SummarizeIssues(s.runProducts[runID], sampleSize), nil
+}
+
+func (f RunProductFilter) matches(p ingest.ProductProcessResult) bool {
+	if f.Disposition != "" && string(p.Disposition) != f.Disposition {
+		return false
+	}
+	if f.Reason != "" && p.Reason != f.Reason {
+		return false
+	}
+	if f.ProductKeyPrefix != "" && !strings.HasPrefix(p.ProductKey, f.ProductKeyPrefix) {
+		return false
+	}
+	if f.IssueCode == "" && f.IssuePath == "" {
+		return true
+	}
+
+	for _, it := range p.Issues {
+		if f.IssueCode != "" && it.Code != f.IssueCode {
+			continue
+		}
+		if f.IssuePath != "" && it.Path != f.IssuePath {
+			continue
+		}
+		return true
+	}
+	return false
+}
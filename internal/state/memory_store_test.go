@@ -2,9 +2,11 @@ package state
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ETAnderson/conductor/internal/domain"
 	"github.com/ETAnderson/conductor/internal/ingest"
 )
 
@@ -59,3 +61,143 @@ func TestWarningsToJSON_IsDeterministic(t *testing.T) {
 		t.Fatalf("unexpected json: %s", j)
 	}
 }
+
+func TestMemoryStore_ListRunProducts_Filters(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	_ = s.InsertRunProducts(ctx, "run1", []ingest.ProductProcessResult{
+		{ProductKey: "shoe-1", Disposition: domain.ProductDispositionEnqueued, Reason: "new_product"},
+		{ProductKey: "shoe-2", Disposition: domain.ProductDispositionRejected, Reason: "base_validation_failed",
+			Issues: []ingest.ValidationIssue{{Path: "price.currency", Code: "invalid_currency"}}},
+		{ProductKey: "hat-1", Disposition: domain.ProductDispositionRejected, Reason: "base_validation_failed",
+			Issues: []ingest.ValidationIssue{{Path: "title", Code: "required"}, {Path: "price.currency", Code: "required"}}},
+	})
+
+	tests := []struct {
+		name   string
+		filter RunProductFilter
+		want   []string
+	}{
+		{"no filter", RunProductFilter{}, []string{"hat-1", "shoe-1", "shoe-2"}},
+		{"disposition", RunProductFilter{Disposition: "rejected"}, []string{"hat-1", "shoe-2"}},
+		{"reason", RunProductFilter{Reason: "new_product"}, []string{"shoe-1"}},
+		{"issue code", RunProductFilter{IssueCode: "invalid_currency"}, []string{"shoe-2"}},
+		{"issue path", RunProductFilter{IssuePath: "price.currency"}, []string{"hat-1", "shoe-2"}},
+		{"code and path on same issue", RunProductFilter{IssueCode: "required", IssuePath: "price.currency"}, []string{"hat-1"}},
+		{"code and path on different issues", RunProductFilter{IssueCode: "invalid_currency", IssuePath: "title"}, nil},
+		{"key prefix", RunProductFilter{ProductKeyPrefix: "shoe-"}, []string{"shoe-1", "shoe-2"}},
+		{"combined", RunProductFilter{Disposition: "rejected", ProductKeyPrefix: "shoe"}, []string{"shoe-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ListRunProducts(ctx, "run1", tt.filter, 0)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			keys := make([]string, 0, len(got))
+			for _, p := range got {
+				keys = append(keys, p.ProductKey)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("expected %v, got %v", tt.want, keys)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_BatchedProductStateAndVersions(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	err := s.UpsertProductStates(ctx, 1, []ProductStateRecord{
+		{ProductKey: "sku1", Hash: "h1"},
+		{ProductKey: "sku2", Hash: "h2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	got, err := s.GetProductStates(ctx, 1, []string{"sku1", "sku2", "missing"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got) != 2 || got["sku2"].Hash != "h2" {
+		t.Fatalf("unexpected states: %+v", got)
+	}
+
+	vs, err := s.AppendProductVersions(ctx, 1, []ProductVersion{
+		{ProductKey: "sku1", Hash: "a"},
+		{ProductKey: "sku2", Hash: "b"},
+		{ProductKey: "sku1", Hash: "c"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if vs[0].Version != 1 || vs[1].Version != 1 || vs[2].Version != 2 {
+		t.Fatalf("expected per-product numbering in slice order, got %+v", vs)
+	}
+}
+
+func TestMemoryStore_CommitIngestRunIsAllOrNothing(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	in := IngestRun{
+		Run:      RunRecord{RunID: "r1", TenantID: 1, Status: "bogus"},
+		Products: []ingest.ProductProcessResult{{ProductKey: "sku1", Disposition: "enqueued", Hash: "h1"}},
+		States: []ProductStateWrite{{
+			State:   ProductStateRecord{ProductKey: "sku1", Hash: "h1", LastRunID: "r1"},
+			Version: &ProductVersion{ProductKey: "sku1", RunID: "r1", Hash: "h1"},
+		}},
+	}
+	if err := s.CommitIngestRun(ctx, in); err == nil {
+		t.Fatalf("expected invalid status to fail the commit")
+	}
+	if _, ok, _ := s.GetProductState(ctx, 1, "sku1"); ok {
+		t.Fatalf("expected no state from a failed commit")
+	}
+
+	in.Run.Status = "has_changes"
+	if err := s.CommitIngestRun(ctx, in); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if v, ok, _ := s.GetProductVersion(ctx, 1, "sku1", 1); !ok || v.RunID != "r1" {
+		t.Fatalf("expected version 1 from r1, got ok=%v %+v", ok, v)
+	}
+	if err := s.CommitIngestRun(ctx, in); err == nil {
+		t.Fatalf("expected committing the same run twice to fail")
+	}
+	if vs, _ := s.ListProductVersions(ctx, 1, "sku1", 0); len(vs) != 1 {
+		t.Fatalf("expected a single version, got %+v", vs)
+	}
+}
+
+func TestMemoryStore_RetriedVersionAppendIsIdempotent(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	batch := []ProductVersion{
+		{ProductKey: "sku1", RunID: "r1", Hash: "a"},
+		{ProductKey: "sku2", RunID: "r1", Hash: "b"},
+	}
+	if _, err := s.AppendProductVersions(ctx, 1, batch); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	vs, err := s.AppendProductVersions(ctx, 1, batch)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if vs[0].Version != 1 || vs[1].Version != 1 {
+		t.Fatalf("expected the retry to return the existing versions, got %+v", vs)
+	}
+
+	if _, err := s.AppendProductVersion(ctx, 1, ProductVersion{ProductKey: "sku1", RunID: "r2", Hash: "c"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, _ := s.ListProductVersions(ctx, 1, "sku1", 0); len(got) != 2 || got[0].RunID != "r2" {
+		t.Fatalf("expected one version per run, got %+v", got)
+	}
+}
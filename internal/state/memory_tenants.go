@@ -0,0 +1,26 @@
+package state
+
+import (
+	"context"
+
+	"github.com/ETAnderson/conductor/internal/ingest"
+)
+
+func (s *MemoryStore) GetTenantValidation(ctx context.Context, tenantID uint64) (*ingest.ValidationPolicy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return copyValidationPolicy(s.tenantValidation[tenantID]), nil
+}
+
+func (s *MemoryStore) UpdateTenantValidation(ctx context.Context, tenantID uint64, policy *ingest.ValidationPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy == nil {
+		delete(s.tenantValidation, tenantID)
+		return nil
+	}
+	s.tenantValidation[tenantID] = copyValidationPolicy(policy)
+	return nil
+}
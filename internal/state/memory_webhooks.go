@@ -0,0 +1,205 @@
+package state
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *MemoryStore) CreateWebhookEndpoint(ctx context.Context, ep WebhookEndpoint) (WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextWebhookID++
+	ep.ID = s.nextWebhookID
+	ep.Events = append([]string(nil), ep.Events...)
+	if ep.CreatedAt.IsZero() {
+		ep.CreatedAt = time.Now().UTC()
+	}
+
+	s.webhookEndpoints[ep.ID] = ep
+	return ep, nil
+}
+
+func (s *MemoryStore) ListWebhookEndpoints(ctx context.Context, tenantID uint64) ([]WebhookEndpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []WebhookEndpoint
+	for _, ep := range s.webhookEndpoints {
+		if ep.TenantID == tenantID {
+			out = append(out, ep)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *MemoryStore) DeleteWebhookEndpoint(ctx context.Context, tenantID uint64, endpointID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ep, ok := s.webhookEndpoints[endpointID]
+	if !ok || ep.TenantID != tenantID {
+		return ErrWebhookEndpointNotFound
+	}
+	delete(s.webhookEndpoints, endpointID)
+
+	// Keep the delivery log, but stop retrying anything still owed to this endpoint
+	for id, d := range s.webhookDeliveries {
+		if d.EndpointID == endpointID && d.Status == WebhookDeliveryPending {
+			d.Status = WebhookDeliveryFailed
+			d.LastError = "endpoint deleted"
+			s.webhookDeliveries[id] = d
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) EnqueueWebhookEvent(ctx context.Context, tenantID uint64, eventType string, runID string, payload []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enqueueWebhookEventLocked(tenantID, WebhookEvent{Type: eventType, RunID: runID, Payload: payload}), nil
+}
+
+func (s *MemoryStore) enqueueWebhookEventsLocked(tenantID uint64, events []WebhookEvent) {
+	for _, ev := range events {
+		s.enqueueWebhookEventLocked(tenantID, ev)
+	}
+}
+
+func (s *MemoryStore) enqueueWebhookEventLocked(tenantID uint64, ev WebhookEvent) int {
+	now := time.Now().UTC()
+	n := 0
+
+	for _, ep := range s.webhookEndpoints {
+		if ep.TenantID != tenantID || !containsString(ep.Events, ev.Type) {
+			continue
+		}
+
+		s.nextWebhookID++
+		s.webhookDeliveries[s.nextWebhookID] = WebhookDelivery{
+			ID:            s.nextWebhookID,
+			TenantID:      tenantID,
+			EndpointID:    ep.ID,
+			EventType:     ev.Type,
+			RunID:         ev.RunID,
+			Payload:       append([]byte(nil), ev.Payload...),
+			Status:        WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		n++
+	}
+
+	return n
+}
+
+func (s *MemoryStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []WebhookDelivery
+	for _, d := range s.webhookDeliveries {
+		if d.Status != WebhookDeliveryPending || d.NextAttemptAt.After(now) {
+			continue
+		}
+		if _, ok := s.webhookEndpoints[d.EndpointID]; !ok {
+			continue
+		}
+		due = append(due, d)
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i, d := range due {
+		// Lease: hidden from other claims until the attempt is recorded or the lease expires
+		d.NextAttemptAt = now.Add(lease)
+		s.webhookDeliveries[d.ID] = d
+
+		ep := s.webhookEndpoints[d.EndpointID]
+		due[i].EndpointURL = ep.URL
+		due[i].EndpointSecret = ep.Secret
+	}
+
+	return due, nil
+}
+
+func (s *MemoryStore) RecordWebhookAttempt(ctx context.Context, deliveryID uint64, attempt WebhookAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.webhookDeliveries[deliveryID]
+	if !ok {
+		return nil
+	}
+
+	applyWebhookAttempt(&d, attempt)
+	s.webhookDeliveries[deliveryID] = d
+	return nil
+}
+
+func (s *MemoryStore) ListWebhookDeliveries(ctx context.Context, tenantID uint64, endpointID uint64, limit int) ([]WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []WebhookDelivery
+	for _, d := range s.webhookDeliveries {
+		if d.TenantID == tenantID && d.EndpointID == endpointID {
+			out = append(out, d)
+		}
+	}
+
+	// Newest first
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func applyWebhookAttempt(d *WebhookDelivery, attempt WebhookAttempt) {
+	d.Attempts++
+	d.LastStatusCode = attempt.StatusCode
+	d.LastError = attempt.Error
+
+	switch {
+	case attempt.Delivered:
+		at := attempt.At
+		d.Status = WebhookDeliveryDelivered
+		d.DeliveredAt = &at
+	case attempt.RetryAt != nil:
+		d.Status = WebhookDeliveryPending
+		d.NextAttemptAt = *attempt.RetryAt
+	default:
+		d.Status = WebhookDeliveryFailed
+	}
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
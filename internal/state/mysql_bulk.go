@@ -0,0 +1,329 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/ingest"
+)
+
+// mysqlBatchRows caps the rows (or IN-list keys) per multi-row statement so a large
+// batch stays well under max_allowed_packet and the placeholder limit.
+const mysqlBatchRows = 500
+
+// valuesList returns "(?,...,?),(?,...,?)" for rows tuples of width columns.
+func valuesList(rows, width int) string {
+	tuple := "(" + strings.TrimSuffix(strings.Repeat("?,", width), ",") + ")"
+	return strings.TrimSuffix(strings.Repeat(tuple+",", rows), ",")
+}
+
+// chunks calls fn for consecutive [start, end) windows of at most mysqlBatchRows over n items.
+func chunks(n int, fn func(start, end int) error) error {
+	for start := 0; start < n; start += mysqlBatchRows {
+		end := start + mysqlBatchRows
+		if end > n {
+			end = n
+		}
+		if err := fn(start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MySQLStore) GetProductStates(ctx context.Context, tenantID uint64, productKeys []string) (map[string]ProductStateRecord, error) {
+	out := make(map[string]ProductStateRecord, len(productKeys))
+
+	err := chunks(len(productKeys), func(start, end int) error {
+		keys := productKeys[start:end]
+		args := make([]any, 0, len(keys)+1)
+		args = append(args, tenantID)
+		for _, k := range keys {
+			args = append(args, k)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+
+		rows, err := s.db.QueryContext(ctx, `
+SELECT product_key, normalized_hash, hash_version, document_json, last_run_id, updated_at, channels_json
+FROM product_state
+WHERE tenant_id = ? AND product_key IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rec, err := scanProductState(rows)
+			if err != nil {
+				return err
+			}
+			out[rec.ProductKey] = rec
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *MySQLStore) UpsertProductStates(ctx context.Context, tenantID uint64, recs []ProductStateRecord) error {
+	return upsertProductStates(ctx, s.db, tenantID, recs)
+}
+
+func upsertProductStates(ctx context.Context, ex runExecer, tenantID uint64, recs []ProductStateRecord) error {
+	return chunks(len(recs), func(start, end int) error {
+		batch := recs[start:end]
+		args := make([]any, 0, len(batch)*7)
+		for _, rec := range batch {
+			var doc []byte
+			if rec.Product != nil {
+				b, err := json.Marshal(rec.Product)
+				if err != nil {
+					return err
+				}
+				doc = b
+			}
+			channels, err := marshalStateChannels(rec.Channels)
+			if err != nil {
+				return err
+			}
+			args = append(args, tenantID, rec.ProductKey, rec.Hash, hashVersion(rec.HashVersion), doc, nullIfEmpty(rec.LastRunID), channels)
+		}
+
+		_, err := ex.ExecContext(ctx, `
+INSERT INTO product_state (tenant_id, product_key, normalized_hash, hash_version, document_json, last_run_id, channels_json)
+VALUES `+valuesList(len(batch), 7)+`
+ON DUPLICATE KEY UPDATE
+  normalized_hash = VALUES(normalized_hash),
+  hash_version = VALUES(hash_version),
+  document_json = VALUES(document_json),
+  last_run_id = VALUES(last_run_id),
+  channels_json = VALUES(channels_json)`, args...)
+		return err
+	})
+}
+
+func (s *MySQLStore) AppendProductVersions(ctx context.Context, tenantID uint64, vs []ProductVersion) ([]ProductVersion, error) {
+	if len(vs) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	out, err := appendProductVersions(ctx, tx, tenantID, vs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// versionRunKey identifies the version a run added to a product; a run adds at most one.
+type versionRunKey struct{ productKey, runID string }
+
+func appendProductVersions(ctx context.Context, tx *sql.Tx, tenantID uint64, vs []ProductVersion) ([]ProductVersion, error) {
+	// Lock the affected histories (as AppendProductVersion does per product) and
+	// number the new versions after each product's latest.
+	last := make(map[string]int)
+	var keys []string
+	runs := make(map[string]struct{})
+	for _, v := range vs {
+		if _, seen := last[v.ProductKey]; !seen {
+			last[v.ProductKey] = 0
+			keys = append(keys, v.ProductKey)
+		}
+		runs[v.RunID] = struct{}{}
+	}
+
+	err := chunks(len(keys), func(start, end int) error {
+		batch := keys[start:end]
+		args := make([]any, 0, len(batch)+1)
+		args = append(args, tenantID)
+		for _, k := range batch {
+			args = append(args, k)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+
+		rows, err := tx.QueryContext(ctx, `
+SELECT product_key, MAX(version)
+FROM product_versions
+WHERE tenant_id = ? AND product_key IN (`+placeholders+`)
+GROUP BY product_key
+FOR UPDATE`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var key string
+			var version int
+			if err := rows.Scan(&key, &version); err != nil {
+				return err
+			}
+			last[key] = version
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A retried append must not add the run's versions a second time.
+	existing, err := runVersions(ctx, tx, tenantID, keys, runs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	out := make([]ProductVersion, len(vs))
+	var added []ProductVersion
+	for i, v := range vs {
+		k := versionRunKey{productKey: v.ProductKey, runID: v.RunID}
+		if version, ok := existing[k]; ok {
+			v.Version = version
+			out[i] = v
+			continue
+		}
+
+		last[v.ProductKey]++
+		v.Version = last[v.ProductKey]
+		if v.CreatedAt.IsZero() {
+			v.CreatedAt = now
+		}
+		out[i] = v
+		existing[k] = v.Version
+		added = append(added, v)
+	}
+
+	err = chunks(len(added), func(start, end int) error {
+		batch := added[start:end]
+		args := make([]any, 0, len(batch)*9)
+		for _, v := range batch {
+			doc, err := json.Marshal(v.Product)
+			if err != nil {
+				return err
+			}
+			changed, err := json.Marshal(v.ChangedFields)
+			if err != nil {
+				return err
+			}
+			args = append(args, tenantID, v.ProductKey, v.Version, v.RunID, v.Hash, hashVersion(v.HashVersion), changed, doc, v.CreatedAt.UTC())
+		}
+
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO product_versions (
+  tenant_id, product_key, version, run_id, normalized_hash, hash_version,
+  changed_fields_json, document_json, created_at
+) VALUES `+valuesList(len(batch), 9), args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// runVersions returns the versions the given runs already added to the given products.
+func runVersions(ctx context.Context, tx *sql.Tx, tenantID uint64, productKeys []string, runs map[string]struct{}) (map[versionRunKey]int, error) {
+	out := make(map[versionRunKey]int)
+
+	runArgs := make([]any, 0, len(runs))
+	for id := range runs {
+		runArgs = append(runArgs, id)
+	}
+	runPlaceholders := strings.TrimSuffix(strings.Repeat("?,", len(runArgs)), ",")
+
+	err := chunks(len(productKeys), func(start, end int) error {
+		batch := productKeys[start:end]
+		args := make([]any, 0, len(batch)+len(runArgs)+1)
+		args = append(args, tenantID)
+		args = append(args, runArgs...)
+		for _, k := range batch {
+			args = append(args, k)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+
+		rows, err := tx.QueryContext(ctx, `
+SELECT product_key, run_id, version
+FROM product_versions
+WHERE tenant_id = ? AND run_id IN (`+runPlaceholders+`) AND product_key IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var k versionRunKey
+			var version int
+			if err := rows.Scan(&k.productKey, &k.runID, &version); err != nil {
+				return err
+			}
+			out[k] = version
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *MySQLStore) InsertRunProducts(ctx context.Context, runID string, products []ingest.ProductProcessResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertRunProducts(ctx, tx, runID, products); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertRunProducts(ctx context.Context, tx *sql.Tx, runID string, products []ingest.ProductProcessResult) error {
+	return chunks(len(products), func(start, end int) error {
+		batch := products[start:end]
+		args := make([]any, 0, len(batch)*10)
+		for i, p := range batch {
+			issues, err := json.Marshal(p.Issues)
+			if err != nil {
+				return err
+			}
+			changed, err := json.Marshal(p.ChangedFields)
+			if err != nil {
+				return err
+			}
+			classes, err := json.Marshal(p.ChangeClasses)
+			if err != nil {
+				return err
+			}
+			var channels []byte
+			if len(p.Channels) > 0 {
+				if channels, err = json.Marshal(p.Channels); err != nil {
+					return err
+				}
+			}
+			args = append(args, runID, p.ProductKey, start+i, p.Disposition, p.Reason, p.Hash, issues, changed, classes, nullIfEmptyBytes(channels))
+		}
+
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO run_products (run_id, product_key, position, disposition, reason, normalized_hash, issues_json, changed_fields_json, change_classes_json, channels_json)
+VALUES `+valuesList(len(batch), 10), args...)
+		return err
+	})
+
+}
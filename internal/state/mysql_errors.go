@@ -0,0 +1,42 @@
+package state
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL server error numbers the store reacts to.
+const (
+	mysqlErrDuplicateKey    = 1062
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+)
+
+// IsTransient reports whether err may succeed when the same call is retried: a
+// deadlock, a lock wait timeout or a lost connection. Anything else (constraint
+// violations, bad input, a cancelled context) fails the same way again.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return me.Number == mysqlErrDeadlock || me.Number == mysqlErrLockWaitTimeout
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+func isDuplicateKey(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == mysqlErrDuplicateKey
+}
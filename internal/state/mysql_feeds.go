@@ -0,0 +1,125 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/ingest"
+)
+
+func (s *MySQLStore) CreateFeed(ctx context.Context, f Feed) (Feed, error) {
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now().UTC()
+	}
+
+	policy, err := marshalValidationPolicy(f.Validation)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+INSERT INTO feeds (tenant_id, name, validation_json, created_at)
+VALUES (?, ?, ?, ?)`, f.TenantID, f.Name, policy, f.CreatedAt.UTC())
+	if err != nil {
+		return Feed{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Feed{}, err
+	}
+
+	f.ID = uint64(id)
+	return f, nil
+}
+
+func (s *MySQLStore) GetFeed(ctx context.Context, tenantID uint64, feedID uint64) (Feed, bool, error) {
+	f, err := scanFeed(s.db.QueryRowContext(ctx, `
+SELECT feed_id, tenant_id, name, validation_json, created_at
+FROM feeds
+WHERE tenant_id = ? AND feed_id = ?`, tenantID, feedID))
+	if err == sql.ErrNoRows {
+		return Feed{}, false, nil
+	}
+	if err != nil {
+		return Feed{}, false, err
+	}
+	return f, true, nil
+}
+
+func (s *MySQLStore) ListFeeds(ctx context.Context, tenantID uint64) ([]Feed, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT feed_id, tenant_id, name, validation_json, created_at
+FROM feeds
+WHERE tenant_id = ?
+ORDER BY feed_id ASC`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Feed
+	for rows.Next() {
+		f, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (s *MySQLStore) UpdateFeedValidation(ctx context.Context, tenantID uint64, feedID uint64, policy *ingest.ValidationPolicy) error {
+	b, err := marshalValidationPolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+UPDATE feeds SET validation_json = ?
+WHERE tenant_id = ? AND feed_id = ?`, b, tenantID, feedID)
+	if err != nil {
+		return err
+	}
+
+	// RowsAffected is 0 for an unchanged policy too, so check the feed exists
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	if _, ok, err := s.GetFeed(ctx, tenantID, feedID); err != nil {
+		return err
+	} else if !ok {
+		return ErrFeedNotFound
+	}
+	return nil
+}
+
+func scanFeed(row rowScanner) (Feed, error) {
+	var f Feed
+	var policy []byte
+	if err := row.Scan(&f.ID, &f.TenantID, &f.Name, &policy, &f.CreatedAt); err != nil {
+		return Feed{}, err
+	}
+
+	if len(policy) > 0 {
+		var p ingest.ValidationPolicy
+		if err := json.Unmarshal(policy, &p); err != nil {
+			return Feed{}, err
+		}
+		f.Validation = &p
+	}
+	return f, nil
+}
+
+func marshalValidationPolicy(p *ingest.ValidationPolicy) (any, error) {
+	if p == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
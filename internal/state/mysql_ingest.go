@@ -0,0 +1,48 @@
+package state
+
+import (
+	"context"
+	"fmt"
+)
+
+func (s *MySQLStore) CommitIngestRun(ctx context.Context, in IngestRun) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertRun(ctx, tx, in.Run); err != nil {
+		if isDuplicateKey(err) {
+			return fmt.Errorf("run %s: %w", in.Run.RunID, ErrRunExists)
+		}
+		return err
+	}
+	if err := insertRunProducts(ctx, tx, in.Run.RunID, in.Products); err != nil {
+		return err
+	}
+
+	recs := make([]ProductStateRecord, 0, len(in.States))
+	var versions []ProductVersion
+	for _, w := range in.States {
+		recs = append(recs, w.State)
+		if w.Version != nil {
+			versions = append(versions, *w.Version)
+		}
+	}
+
+	if err := upsertProductStates(ctx, tx, in.Run.TenantID, recs); err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		if _, err := appendProductVersions(ctx, tx, in.Run.TenantID, versions); err != nil {
+			return err
+		}
+	}
+
+	if err := enqueueWebhookEvents(ctx, tx, in.Run.TenantID, in.Webhooks); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
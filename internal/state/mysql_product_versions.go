@@ -0,0 +1,151 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+func (s *MySQLStore) AppendProductVersion(ctx context.Context, tenantID uint64, v ProductVersion) (ProductVersion, error) {
+	doc, err := json.Marshal(v.Product)
+	if err != nil {
+		return ProductVersion{}, err
+	}
+	changed, err := json.Marshal(v.ChangedFields)
+	if err != nil {
+		return ProductVersion{}, err
+	}
+
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now().UTC()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ProductVersion{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Lock the product's history so concurrent appends get distinct version numbers
+	var last int
+	err = tx.QueryRowContext(ctx, `
+SELECT COALESCE(MAX(version), 0)
+FROM product_versions
+WHERE tenant_id = ? AND product_key = ?
+FOR UPDATE`, tenantID, v.ProductKey).Scan(&last)
+	if err != nil {
+		return ProductVersion{}, err
+	}
+
+	// A retried append returns the version the run already added
+	var existing int
+	err = tx.QueryRowContext(ctx, `
+SELECT version
+FROM product_versions
+WHERE tenant_id = ? AND product_key = ? AND run_id = ?`, tenantID, v.ProductKey, v.RunID).Scan(&existing)
+	if err == nil {
+		v.Version = existing
+		return v, nil
+	}
+	if err != sql.ErrNoRows {
+		return ProductVersion{}, err
+	}
+
+	v.Version = last + 1
+
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO product_versions (
+  tenant_id, product_key, version, run_id, normalized_hash, hash_version,
+  changed_fields_json, document_json, created_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tenantID, v.ProductKey, v.Version, v.RunID, v.Hash, hashVersion(v.HashVersion),
+		changed, doc, v.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return ProductVersion{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ProductVersion{}, err
+	}
+
+	return v, nil
+}
+
+func (s *MySQLStore) ListProductVersions(ctx context.Context, tenantID uint64, productKey string, limit int) ([]ProductVersion, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT product_key, version, run_id, normalized_hash, hash_version, changed_fields_json, document_json, created_at
+FROM product_versions
+WHERE tenant_id = ? AND product_key = ?
+ORDER BY version DESC
+LIMIT ?`, tenantID, productKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]ProductVersion, 0, limit)
+	for rows.Next() {
+		v, err := scanProductVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *MySQLStore) GetProductVersion(ctx context.Context, tenantID uint64, productKey string, version int) (ProductVersion, bool, error) {
+	row := s.db.QueryRowContext(ctx, `
+SELECT product_key, version, run_id, normalized_hash, hash_version, changed_fields_json, document_json, created_at
+FROM product_versions
+WHERE tenant_id = ? AND product_key = ? AND version = ?`, tenantID, productKey, version)
+
+	v, err := scanProductVersion(row)
+	if err == sql.ErrNoRows {
+		return ProductVersion{}, false, nil
+	}
+	if err != nil {
+		return ProductVersion{}, false, err
+	}
+	return v, true, nil
+}
+
+func scanProductVersion(row rowScanner) (ProductVersion, error) {
+	var v ProductVersion
+	var changed, doc []byte
+	var created time.Time
+
+	if err := row.Scan(&v.ProductKey, &v.Version, &v.RunID, &v.Hash, &v.HashVersion, &changed, &doc, &created); err != nil {
+		return ProductVersion{}, err
+	}
+
+	v.CreatedAt = created.UTC()
+
+	if len(changed) > 0 {
+		_ = json.Unmarshal(changed, &v.ChangedFields)
+	}
+	if len(doc) > 0 {
+		var p domain.Product
+		if err := json.Unmarshal(doc, &p); err == nil {
+			v.Product = &p
+		}
+	}
+
+	return v, nil
+}
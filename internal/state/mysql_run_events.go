@@ -0,0 +1,88 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+func (s *MySQLStore) AppendRunEvent(ctx context.Context, ev RunEvent) error {
+	var exists int
+	err := s.db.QueryRowContext(ctx, `
+SELECT 1 FROM runs WHERE tenant_id = ? AND run_id = ?`, ev.TenantID, ev.RunID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrRunNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return insertRunEvent(ctx, s.db, ev)
+}
+
+func (s *MySQLStore) ListRunEvents(ctx context.Context, tenantID uint64, runID string) ([]RunEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT event_id, run_id, tenant_id, event_type, status, channel, worker_id, details_json, created_at
+FROM run_events
+WHERE tenant_id = ? AND run_id = ?
+ORDER BY event_id ASC`, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []RunEvent
+	for rows.Next() {
+		var ev RunEvent
+		var eventType string
+		var status, channel, workerID sql.NullString
+		var details []byte
+		var created time.Time
+
+		if err := rows.Scan(&ev.EventID, &ev.RunID, &ev.TenantID, &eventType, &status, &channel, &workerID, &details, &created); err != nil {
+			return nil, err
+		}
+
+		ev.Type = domain.RunEventType(eventType)
+		ev.Status = status.String
+		ev.Channel = channel.String
+		ev.WorkerID = workerID.String
+		ev.CreatedAt = created.UTC()
+		if len(details) > 0 {
+			_ = json.Unmarshal(details, &ev.Details)
+		}
+
+		out = append(out, ev)
+	}
+
+	return out, rows.Err()
+}
+
+// insertRunEvent writes one timeline entry, tagging the worker from ctx when unset.
+func insertRunEvent(ctx context.Context, ex runExecer, ev RunEvent) error {
+	if ev.WorkerID == "" {
+		ev.WorkerID = WorkerID(ctx)
+	}
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = time.Now().UTC()
+	}
+
+	var details []byte
+	if len(ev.Details) > 0 {
+		b, err := json.Marshal(ev.Details)
+		if err != nil {
+			return err
+		}
+		details = b
+	}
+
+	_, err := ex.ExecContext(ctx, `
+INSERT INTO run_events (run_id, tenant_id, event_type, status, channel, worker_id, details_json, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		ev.RunID, ev.TenantID, string(ev.Type), nullIfEmpty(ev.Status), nullIfEmpty(ev.Channel),
+		nullIfEmpty(ev.WorkerID), details, ev.CreatedAt.UTC())
+	return err
+}
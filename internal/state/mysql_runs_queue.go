@@ -3,12 +3,18 @@ package state
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/domain"
 )
 
-func (s *MySQLStore) ClaimRuns(ctx context.Context, limit int) ([]RunClaim, error) {
+func (s *MySQLStore) ClaimRuns(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]RunClaim, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	now = now.UTC()
+	leaseEnd := now.Add(lease)
 
 	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
 	if err != nil {
@@ -16,38 +22,77 @@ func (s *MySQLStore) ClaimRuns(ctx context.Context, limit int) ([]RunClaim, erro
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	// Expired claims first: their runs stay processing, so their keys remain busy below
+	reclaimed, err := reclaimExpiredRuns(ctx, tx, now, leaseEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	limit -= len(reclaimed)
+	if limit <= 0 {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return reclaimed, nil
+	}
+
+	// Lock pending runs first; concurrent claimers block here until we commit,
+	// then see our claims as processing (busy) below.
 	rows, err := tx.QueryContext(ctx, `
-SELECT run_id, tenant_id
+SELECT run_id, tenant_id, feed_id
 FROM runs
-WHERE status = 'has_changes' AND push_triggered = 1
-ORDER BY created_at ASC
+WHERE status = ? AND push_triggered = 1
+ORDER BY created_at ASC, run_id ASC
 LIMIT ?
 FOR UPDATE
-`, limit)
+`, string(domain.RunStatusHasChanges), pendingScanLimit(limit))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var claims []RunClaim
+	var pending []RunRecord
 	for rows.Next() {
-		var c RunClaim
-		if err := rows.Scan(&c.RunID, &c.TenantID); err != nil {
+		var r RunRecord
+		var feedID sql.NullInt64
+		if err := rows.Scan(&r.RunID, &r.TenantID, &feedID); err != nil {
 			return nil, err
 		}
-		claims = append(claims, c)
+		if feedID.Valid {
+			v := uint64(feedID.Int64)
+			r.FeedID = &v
+		}
+		pending = append(pending, r)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	_ = rows.Close()
+
+	busy, err := processingQueueKeys(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := planClaims(pending, busy, limit)
+	claims := plan.Claims
+
+	tenantOf := make(map[string]uint64, len(pending))
+	for _, r := range pending {
+		tenantOf[r.RunID] = r.TenantID
+	}
+
+	for runID, carrier := range plan.Superseded {
+		err := transitionRun(ctx, tx, tenantOf[runID], runID, domain.RunStatusSuperseded,
+			map[string]any{"superseded_by": carrier}, ", superseded_by = ?", carrier)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Mark them processing
 	for _, c := range claims {
-		_, err := tx.ExecContext(ctx, `
-UPDATE runs
-SET status = 'processing'
-WHERE run_id = ? AND tenant_id = ? AND status = 'has_changes'
-`, c.RunID, c.TenantID)
+		err := transitionRun(ctx, tx, c.TenantID, c.RunID, domain.RunStatusProcessing, nil,
+			", claim_expires_at = ?", leaseEnd)
 		if err != nil {
 			return nil, err
 		}
@@ -57,24 +102,366 @@ WHERE run_id = ? AND tenant_id = ? AND status = 'has_changes'
 		return nil, err
 	}
 
+	return append(reclaimed, claims...), nil
+}
+
+// reclaimExpiredRuns claims processing runs whose lease ended at or before now again,
+// leasing them until leaseEnd.
+func reclaimExpiredRuns(ctx context.Context, tx *sql.Tx, now, leaseEnd time.Time, limit int) ([]RunClaim, error) {
+	rows, err := tx.QueryContext(ctx, `
+SELECT run_id, tenant_id, feed_id, claim_expires_at
+FROM runs
+WHERE status = ? AND claim_expires_at <= ?
+ORDER BY claim_expires_at ASC, run_id ASC
+LIMIT ?
+FOR UPDATE
+`, string(domain.RunStatusProcessing), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claims []RunClaim
+	var expiredAt []time.Time
+	for rows.Next() {
+		var c RunClaim
+		var feedID sql.NullInt64
+		var expires time.Time
+		if err := rows.Scan(&c.RunID, &c.TenantID, &feedID, &expires); err != nil {
+			return nil, err
+		}
+		if feedID.Valid {
+			v := uint64(feedID.Int64)
+			c.FeedID = &v
+		}
+		claims = append(claims, c)
+		expiredAt = append(expiredAt, expires.UTC())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	for i, c := range claims {
+		_, err := tx.ExecContext(ctx, `
+UPDATE runs SET claimed_at = ?, claim_expires_at = ?
+WHERE run_id = ? AND tenant_id = ?`, now, leaseEnd, c.RunID, c.TenantID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = insertRunEvent(ctx, tx, RunEvent{
+			RunID:     c.RunID,
+			TenantID:  c.TenantID,
+			Type:      domain.RunEventClaimExpired,
+			Details:   map[string]any{"expired_at": expiredAt[i].Format(time.RFC3339Nano)},
+			CreatedAt: now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return claims, nil
 }
 
-func (s *MySQLStore) CompleteRun(ctx context.Context, tenantID uint64, runID string) error {
-	_, err := s.db.ExecContext(ctx, `
-UPDATE runs
-SET status = 'completed'
-WHERE run_id = ? AND tenant_id = ?
-`, runID, tenantID)
-	return err
+func (s *MySQLStore) RenewRunClaim(ctx context.Context, tenantID uint64, runID string, now time.Time, lease time.Duration) error {
+	res, err := s.db.ExecContext(ctx, `
+UPDATE runs SET claim_expires_at = ?
+WHERE run_id = ? AND tenant_id = ? AND status = ?`,
+		now.UTC().Add(lease), runID, tenantID, string(domain.RunStatusProcessing))
+	if err != nil {
+		return err
+	}
+
+	// RowsAffected is 0 for an unchanged lease too, so report from the run's status
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+
+	var status string
+	err = s.db.QueryRowContext(ctx, `
+SELECT status FROM runs WHERE run_id = ? AND tenant_id = ?`, runID, tenantID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrRunNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != string(domain.RunStatusProcessing) {
+		return ErrRunNotClaimed
+	}
+	return nil
+}
+
+// pendingScanLimit bounds how many pending runs one claim inspects. Runs for busy
+// feeds are skipped, so we look past the first `limit` rows.
+func pendingScanLimit(limit int) int {
+	n := limit * 20
+	if n < 200 {
+		n = 200
+	}
+	return n
+}
+
+func processingQueueKeys(ctx context.Context, tx *sql.Tx) (map[runQueueKey]struct{}, error) {
+	rows, err := tx.QueryContext(ctx, `
+SELECT DISTINCT tenant_id, feed_id
+FROM runs
+WHERE status = ?
+`, string(domain.RunStatusProcessing))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	busy := make(map[runQueueKey]struct{})
+	for rows.Next() {
+		var tenantID uint64
+		var feedID sql.NullInt64
+		if err := rows.Scan(&tenantID, &feedID); err != nil {
+			return nil, err
+		}
+		k := runQueueKey{tenantID: tenantID}
+		if feedID.Valid {
+			k.feedID = uint64(feedID.Int64)
+		}
+		busy[k] = struct{}{}
+	}
+
+	return busy, rows.Err()
 }
 
-func (s *MySQLStore) FailRun(ctx context.Context, tenantID uint64, runID string, message string) error {
-	_ = message
-	_, err := s.db.ExecContext(ctx, `
-UPDATE runs
-SET status = 'failed'
-WHERE run_id = ? AND tenant_id = ?
-`, runID, tenantID)
+func (s *MySQLStore) CompleteRun(ctx context.Context, tenantID uint64, runID string, outbox RunOutbox) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var errored int
+	err = tx.QueryRowContext(ctx, `
+SELECT errored FROM runs WHERE run_id = ? AND tenant_id = ? FOR UPDATE`, runID, tenantID).Scan(&errored)
+	if err == sql.ErrNoRows {
+		return ErrRunNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := transitionRun(ctx, tx, tenantID, runID, completedStatus(errored), nil, ""); err != nil {
+		return err
+	}
+	if err := enqueueRunOutbox(ctx, tx, tenantID, runID, outbox); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySQLStore) FailRun(ctx context.Context, tenantID uint64, runID string, message string, outbox RunOutbox) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	err = transitionRun(ctx, tx, tenantID, runID, domain.RunStatusFailed,
+		map[string]any{"error": message}, ", error_message = ?", message)
+	if err != nil {
+		return err
+	}
+	if err := enqueueRunOutbox(ctx, tx, tenantID, runID, outbox); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySQLStore) ListSupersededRuns(ctx context.Context, tenantID uint64, runID string) ([]RunRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+runColumns+`
+FROM runs
+WHERE tenant_id = ? AND superseded_by = ?
+ORDER BY created_at ASC, run_id ASC`, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []RunRecord
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+
+	return out, rows.Err()
+}
+
+func (s *MySQLStore) CancelRun(ctx context.Context, tenantID uint64, runID string) (RunRecord, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RunRecord{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	r, err := scanRun(tx.QueryRowContext(ctx, `
+SELECT `+runColumns+`
+FROM runs
+WHERE tenant_id = ? AND run_id = ?
+FOR UPDATE`, tenantID, runID))
+	if err == sql.ErrNoRows {
+		return RunRecord{}, ErrRunNotFound
+	}
+	if err != nil {
+		return RunRecord{}, err
+	}
+
+	switch r.Status {
+	case string(domain.RunStatusHasChanges):
+		if err := transitionRun(ctx, tx, tenantID, runID, domain.RunStatusCancelled, nil, ""); err != nil {
+			return RunRecord{}, err
+		}
+		if err := rollbackProductState(ctx, tx, tenantID, []string{runID}); err != nil {
+			return RunRecord{}, err
+		}
+		r.Status = string(domain.RunStatusCancelled)
+
+	case string(domain.RunStatusProcessing):
+		if _, err := tx.ExecContext(ctx, `
+UPDATE runs SET cancel_requested = 1
+WHERE run_id = ? AND tenant_id = ?`, runID, tenantID); err != nil {
+			return RunRecord{}, err
+		}
+		if err := insertRunEvent(ctx, tx, RunEvent{
+			RunID:     runID,
+			TenantID:  tenantID,
+			Type:      domain.RunEventCancelRequested,
+			CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			return RunRecord{}, err
+		}
+		r.CancelRequested = true
+
+	default:
+		return r, ErrRunNotCancellable
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RunRecord{}, err
+	}
+
+	return r, nil
+}
+
+func (s *MySQLStore) MarkRunCancelled(ctx context.Context, tenantID uint64, runID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Only a processing run is finished this way; a pending one is cancelled by CancelRun.
+	var status string
+	err = tx.QueryRowContext(ctx, `
+SELECT status FROM runs WHERE run_id = ? AND tenant_id = ? FOR UPDATE`, runID, tenantID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrRunNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != string(domain.RunStatusProcessing) {
+		return ErrRunNotCancellable
+	}
+
+	if err := transitionRun(ctx, tx, tenantID, runID, domain.RunStatusCancelled, nil, ""); err != nil {
+		return err
+	}
+
+	// Changes coalesced into this run were never pushed either
+	runIDs := []string{runID}
+	rows, err := tx.QueryContext(ctx, `
+SELECT run_id FROM runs WHERE tenant_id = ? AND superseded_by = ?`, tenantID, runID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		runIDs = append(runIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	_ = rows.Close()
+
+	if err := rollbackProductState(ctx, tx, tenantID, runIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rollbackProductState undoes the product_state advanced by cancelled runs so those
+// products are re-evaluated on the next ingest. A product is only rolled back while its
+// state still holds the hash a cancelled run wrote; newer changes are left alone.
+// Each step is one statement over every affected product: drop the cancelled runs'
+// versions, restore state from the newest version left, then drop state that has none.
+func rollbackProductState(ctx context.Context, tx *sql.Tx, tenantID uint64, runIDs []string) error {
+	if len(runIDs) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(runIDs)), ",")
+	runArgs := make([]any, 0, len(runIDs))
+	for _, id := range runIDs {
+		runArgs = append(runArgs, id)
+	}
+
+	// Products whose state still holds a hash one of the cancelled runs enqueued
+	affected := `
+JOIN run_products rp
+  ON rp.product_key = ps.product_key AND rp.normalized_hash = ps.normalized_hash
+ AND rp.disposition = 'enqueued' AND rp.run_id IN (` + placeholders + `)`
+
+	args := append(append([]any{}, runArgs...), tenantID)
+	args = append(args, runArgs...)
+	if _, err := tx.ExecContext(ctx, `
+DELETE pv
+FROM product_versions pv
+JOIN product_state ps ON ps.tenant_id = pv.tenant_id AND ps.product_key = pv.product_key`+affected+`
+WHERE pv.tenant_id = ? AND pv.run_id IN (`+placeholders+`)`, args...); err != nil {
+		return err
+	}
+
+	args = append(append([]any{}, runArgs...), tenantID)
+	if _, err := tx.ExecContext(ctx, `
+UPDATE product_state ps`+affected+`
+JOIN product_versions pv
+  ON pv.tenant_id = ps.tenant_id AND pv.product_key = ps.product_key
+ AND pv.version = (
+   SELECT MAX(v.version) FROM product_versions v
+   WHERE v.tenant_id = ps.tenant_id AND v.product_key = ps.product_key
+ )
+SET ps.normalized_hash = pv.normalized_hash, ps.hash_version = pv.hash_version,
+    ps.last_run_id = pv.run_id, ps.document_json = pv.document_json, ps.channels_json = NULL
+WHERE ps.tenant_id = ?`, args...); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+DELETE ps
+FROM product_state ps`+affected+`
+WHERE ps.tenant_id = ?
+  AND NOT EXISTS (
+    SELECT 1 FROM product_versions v
+    WHERE v.tenant_id = ps.tenant_id AND v.product_key = ps.product_key
+  )`, args...)
 	return err
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"strings"
 	"time"
 
+	"github.com/ETAnderson/conductor/internal/domain"
 	"github.com/ETAnderson/conductor/internal/ingest"
 )
 
@@ -45,45 +47,194 @@ func (s *MySQLStore) UpsertProductHash(ctx context.Context, tenantID uint64, pro
 	return err
 }
 
-func (s *MySQLStore) InsertRun(ctx context.Context, run RunRecord) error {
-	wb, err := json.Marshal(run.Warnings)
+func (s *MySQLStore) UpsertProductState(ctx context.Context, tenantID uint64, rec ProductStateRecord) error {
+	var doc []byte
+	if rec.Product != nil {
+		b, err := json.Marshal(rec.Product)
+		if err != nil {
+			return err
+		}
+		doc = b
+	}
+	channels, err := marshalStateChannels(rec.Channels)
 	if err != nil {
 		return err
 	}
 
 	_, err = s.db.ExecContext(
 		ctx,
-		`INSERT INTO runs (
-			run_id, tenant_id, feed_id, status, push_triggered,
-			received, valid, rejected, unchanged, enqueued,
-			warnings_json, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		run.RunID, run.TenantID, run.FeedID, run.Status, run.PushTriggered,
-		run.Received, run.Valid, run.Rejected, run.Unchanged, run.Enqueued,
-		wb, run.CreatedAt.UTC(),
+		`INSERT INTO product_state (tenant_id, product_key, normalized_hash, hash_version, document_json, last_run_id, channels_json)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE
+		   normalized_hash = VALUES(normalized_hash),
+		   hash_version = VALUES(hash_version),
+		   document_json = VALUES(document_json),
+		   last_run_id = VALUES(last_run_id),
+		   channels_json = VALUES(channels_json)`,
+		tenantID, rec.ProductKey, rec.Hash, hashVersion(rec.HashVersion), doc, nullIfEmpty(rec.LastRunID), channels,
 	)
 	return err
 }
 
-func (s *MySQLStore) InsertRunProducts(ctx context.Context, runID string, products []ingest.ProductProcessResult) error {
-	// Simple row-by-row insert (optimize to bulk insert later)
-	for _, p := range products {
-		issues, err := json.Marshal(p.Issues)
+func (s *MySQLStore) GetProductState(ctx context.Context, tenantID uint64, productKey string) (ProductStateRecord, bool, error) {
+	row := s.db.QueryRowContext(ctx, `
+SELECT product_key, normalized_hash, hash_version, document_json, last_run_id, updated_at, channels_json
+FROM product_state
+WHERE tenant_id = ? AND product_key = ?`, tenantID, productKey)
+
+	rec, err := scanProductState(row)
+	if err == sql.ErrNoRows {
+		return ProductStateRecord{}, false, nil
+	}
+	if err != nil {
+		return ProductStateRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+func (s *MySQLStore) ListProductStates(ctx context.Context, tenantID uint64, afterKey string, limit int) ([]ProductStateRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT product_key, normalized_hash, hash_version, document_json, last_run_id, updated_at, channels_json
+FROM product_state
+WHERE tenant_id = ? AND product_key > ?
+ORDER BY product_key ASC
+LIMIT ?`, tenantID, afterKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]ProductStateRecord, 0, limit)
+	for rows.Next() {
+		rec, err := scanProductState(rows)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		out = append(out, rec)
+	}
 
-		_, err = s.db.ExecContext(
-			ctx,
-			`INSERT INTO run_products (run_id, product_key, disposition, reason, normalized_hash, issues_json)
-			 VALUES (?, ?, ?, ?, ?, ?)`,
-			runID, p.ProductKey, p.Disposition, p.Reason, p.Hash, issues,
-		)
-		if err != nil {
-			return err
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// marshalStateChannels encodes accepted channels for channels_json; nil (not
+// recorded) stays NULL.
+func marshalStateChannels(channels []string) ([]byte, error) {
+	if channels == nil {
+		return nil, nil
+	}
+	return json.Marshal(channels)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProductState(row rowScanner) (ProductStateRecord, error) {
+	var rec ProductStateRecord
+	var doc []byte
+	var lastRunID sql.NullString
+	var updated time.Time
+	var channels []byte
+
+	if err := row.Scan(&rec.ProductKey, &rec.Hash, &rec.HashVersion, &doc, &lastRunID, &updated, &channels); err != nil {
+		return ProductStateRecord{}, err
+	}
+
+	rec.LastRunID = lastRunID.String
+	rec.UpdatedAt = updated.UTC()
+
+	if len(doc) > 0 {
+		var p domain.Product
+		if err := json.Unmarshal(doc, &p); err == nil {
+			rec.Product = &p
 		}
 	}
-	return nil
+	if len(channels) > 0 {
+		_ = json.Unmarshal(channels, &rec.Channels)
+	}
+
+	return rec, nil
+}
+
+// hashVersion is the hash_version stored for v: unversioned records are v1.
+func hashVersion(v int) int {
+	if v <= 0 {
+		return ingest.HashVersionV1
+	}
+	return v
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullIfEmptyBytes(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
+func (s *MySQLStore) InsertRun(ctx context.Context, run RunRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertRun(ctx, tx, run); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertRun writes run and its initial status_changed event.
+func insertRun(ctx context.Context, tx *sql.Tx, run RunRecord) error {
+	if err := validateRunStatus(run.Status); err != nil {
+		return err
+	}
+
+	wb, err := json.Marshal(run.Warnings)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO runs (
+			run_id, tenant_id, feed_id, status, push_triggered,
+			received, valid, rejected, unchanged, enqueued, duplicates, errored,
+			warnings_json, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.RunID, run.TenantID, run.FeedID, run.Status, run.PushTriggered,
+		run.Received, run.Valid, run.Rejected, run.Unchanged, run.Enqueued, run.Duplicates, run.Errored,
+		wb, run.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return insertRunEvent(ctx, tx, RunEvent{
+		RunID:     run.RunID,
+		TenantID:  run.TenantID,
+		Type:      domain.RunEventStatusChanged,
+		Status:    run.Status,
+		CreatedAt: run.CreatedAt,
+	})
 }
 
 func (s *MySQLStore) GetIdempotency(ctx context.Context, tenantID uint64, endpoint string, idemKeyHash string) (IdempotencyRecord, bool, error) {
@@ -91,14 +242,15 @@ func (s *MySQLStore) GetIdempotency(ctx context.Context, tenantID uint64, endpoi
 	var body []byte
 	var created time.Time
 	var expires time.Time
+	var runID sql.NullString
 
 	err := s.db.QueryRowContext(
 		ctx,
-		`SELECT status_code, response_body_json, created_at, expires_at
+		`SELECT status_code, response_body_json, created_at, expires_at, run_id
 		 FROM idempotency
 		 WHERE tenant_id = ? AND endpoint = ? AND idem_key_hash = ?`,
 		tenantID, endpoint, idemKeyHash,
-	).Scan(&status, &body, &created, &expires)
+	).Scan(&status, &body, &created, &expires, &runID)
 
 	if err == sql.ErrNoRows {
 		return IdempotencyRecord{}, false, nil
@@ -116,20 +268,23 @@ func (s *MySQLStore) GetIdempotency(ctx context.Context, tenantID uint64, endpoi
 		BodyJSON:   body,
 		CreatedAt:  created.UTC(),
 		ExpiresAt:  expires.UTC(),
+		RunID:      runID.String,
 	}, true, nil
 }
 
 func (s *MySQLStore) PutIdempotency(ctx context.Context, tenantID uint64, endpoint string, idemKeyHash string, rec IdempotencyRecord) error {
 	_, err := s.db.ExecContext(
 		ctx,
-		`INSERT INTO idempotency (tenant_id, endpoint, idem_key_hash, status_code, response_body_json, created_at, expires_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		`INSERT INTO idempotency (tenant_id, endpoint, idem_key_hash, status_code, response_body_json, run_id, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		 ON DUPLICATE KEY UPDATE
 		   status_code = VALUES(status_code),
 		   response_body_json = VALUES(response_body_json),
+		   run_id = VALUES(run_id),
 		   created_at = VALUES(created_at),
 		   expires_at = VALUES(expires_at)`,
-		tenantID, endpoint, idemKeyHash, rec.StatusCode, rec.BodyJSON, rec.CreatedAt.UTC(), rec.ExpiresAt.UTC(),
+		tenantID, endpoint, idemKeyHash, rec.StatusCode, nullIfEmptyBytes(rec.BodyJSON), nullIfEmpty(rec.RunID),
+		rec.CreatedAt.UTC(), rec.ExpiresAt.UTC(),
 	)
 	return err
 }
@@ -143,9 +298,7 @@ func (s *MySQLStore) ListRuns(ctx context.Context, tenantID uint64, limit int) (
 	}
 
 	rows, err := s.db.QueryContext(ctx, `
-SELECT run_id, tenant_id, feed_id, status, push_triggered,
-       received, valid, rejected, unchanged, enqueued,
-       warnings_json, created_at
+SELECT `+runColumns+`
 FROM runs
 WHERE tenant_id = ?
 ORDER BY created_at DESC
@@ -158,42 +311,10 @@ LIMIT ?`, tenantID, limit)
 	out := make([]RunRecord, 0, limit)
 
 	for rows.Next() {
-		var r RunRecord
-		var feedID sql.NullInt64
-		var push int
-		var warningsBytes []byte
-		var created time.Time
-
-		err := rows.Scan(
-			&r.RunID,
-			&r.TenantID,
-			&feedID,
-			&r.Status,
-			&push,
-			&r.Received,
-			&r.Valid,
-			&r.Rejected,
-			&r.Unchanged,
-			&r.Enqueued,
-			&warningsBytes,
-			&created,
-		)
+		r, err := scanRun(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if feedID.Valid {
-			v := uint64(feedID.Int64)
-			r.FeedID = &v
-		}
-
-		r.PushTriggered = push == 1
-		r.CreatedAt = created.UTC()
-
-		if len(warningsBytes) > 0 {
-			_ = json.Unmarshal(warningsBytes, &r.Warnings)
-		}
-
 		out = append(out, r)
 	}
 
@@ -205,33 +326,12 @@ LIMIT ?`, tenantID, limit)
 }
 
 func (s *MySQLStore) GetRun(ctx context.Context, tenantID uint64, runID string) (RunRecord, bool, error) {
-	var r RunRecord
-	var feedID sql.NullInt64
-	var push int
-	var warningsBytes []byte
-	var created time.Time
-
-	err := s.db.QueryRowContext(ctx, `
-SELECT run_id, tenant_id, feed_id, status, push_triggered,
-       received, valid, rejected, unchanged, enqueued,
-       warnings_json, created_at
+	row := s.db.QueryRowContext(ctx, `
+SELECT `+runColumns+`
 FROM runs
-WHERE tenant_id = ? AND run_id = ?`, tenantID, runID).
-		Scan(
-			&r.RunID,
-			&r.TenantID,
-			&feedID,
-			&r.Status,
-			&push,
-			&r.Received,
-			&r.Valid,
-			&r.Rejected,
-			&r.Unchanged,
-			&r.Enqueued,
-			&warningsBytes,
-			&created,
-		)
+WHERE tenant_id = ? AND run_id = ?`, tenantID, runID)
 
+	r, err := scanRun(row)
 	if err == sql.ErrNoRows {
 		return RunRecord{}, false, nil
 	}
@@ -239,21 +339,82 @@ WHERE tenant_id = ? AND run_id = ?`, tenantID, runID).
 		return RunRecord{}, false, err
 	}
 
+	return r, true, nil
+}
+
+// runColumns is the select list scanRun expects.
+const runColumns = `run_id, tenant_id, feed_id, status, push_triggered,
+       received, valid, rejected, unchanged, enqueued, duplicates, errored,
+       warnings_json, created_at, superseded_by, cancel_requested,
+       claimed_at, finished_at, error_message, claim_expires_at`
+
+func scanRun(row rowScanner) (RunRecord, error) {
+	var r RunRecord
+	var feedID sql.NullInt64
+	var push int
+	var warningsBytes []byte
+	var created time.Time
+	var supersededBy sql.NullString
+	var cancelRequested int
+	var claimedAt, finishedAt, claimExpiresAt sql.NullTime
+	var errorMessage sql.NullString
+
+	err := row.Scan(
+		&r.RunID,
+		&r.TenantID,
+		&feedID,
+		&r.Status,
+		&push,
+		&r.Received,
+		&r.Valid,
+		&r.Rejected,
+		&r.Unchanged,
+		&r.Enqueued,
+		&r.Duplicates,
+		&r.Errored,
+		&warningsBytes,
+		&created,
+		&supersededBy,
+		&cancelRequested,
+		&claimedAt,
+		&finishedAt,
+		&errorMessage,
+		&claimExpiresAt,
+	)
+	if err != nil {
+		return RunRecord{}, err
+	}
+
 	if feedID.Valid {
 		v := uint64(feedID.Int64)
 		r.FeedID = &v
 	}
 	r.PushTriggered = push == 1
 	r.CreatedAt = created.UTC()
+	r.SupersededBy = supersededBy.String
+	r.CancelRequested = cancelRequested == 1
+	r.ErrorMessage = errorMessage.String
+	if claimedAt.Valid {
+		t := claimedAt.Time.UTC()
+		r.ClaimedAt = &t
+	}
+	if finishedAt.Valid {
+		t := finishedAt.Time.UTC()
+		r.FinishedAt = &t
+	}
+	if claimExpiresAt.Valid {
+		t := claimExpiresAt.Time.UTC()
+		r.ClaimExpiresAt = &t
+	}
 
 	if len(warningsBytes) > 0 {
 		_ = json.Unmarshal(warningsBytes, &r.Warnings)
 	}
 
-	return r, true, nil
+	return r, nil
 }
 
-func (s *MySQLStore) ListRunProducts(ctx context.Context, runID string, limit int) ([]ingest.ProductProcessResult, error) {
+func (s *MySQLStore) ListRunProducts(ctx context.Context, runID string, filter RunProductFilter, limit int) ([]ingest.ProductProcessResult, error) {
 	if limit <= 0 {
 		limit = 200
 	}
@@ -261,12 +422,15 @@ func (s *MySQLStore) ListRunProducts(ctx context.Context, runID string, limit in
 		limit = 2000
 	}
 
+	where, args := runProductsWhere(runID, filter)
+	args = append(args, limit)
+
 	rows, err := s.db.QueryContext(ctx, `
-SELECT product_key, disposition, reason, normalized_hash, issues_json
-FROM run_products
-WHERE run_id = ?
+SELECT `+runProductColumns+`
+FROM run_products rp
+WHERE `+where+`
 ORDER BY product_key ASC
-LIMIT ?`, runID, limit)
+LIMIT ?`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -275,32 +439,189 @@ LIMIT ?`, runID, limit)
 	out := make([]ingest.ProductProcessResult, 0, limit)
 
 	for rows.Next() {
-		var p ingest.ProductProcessResult
-		var reason sql.NullString
-		var hash sql.NullString
-		var issuesBytes []byte
-
-		err := rows.Scan(&p.ProductKey, &p.Disposition, &reason, &hash, &issuesBytes)
+		p, err := scanRunProduct(rows)
 		if err != nil {
 			return nil, err
 		}
+		out = append(out, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *MySQLStore) EachRunProduct(ctx context.Context, runID string, fn func(ingest.ProductProcessResult) error) error {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+runProductColumns+`
+FROM run_products rp
+WHERE run_id = ?
+ORDER BY position ASC, product_key ASC`, runID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-		if reason.Valid {
-			p.Reason = reason.String
+	for rows.Next() {
+		p, err := scanRunProduct(rows)
+		if err != nil {
+			return err
 		}
-		if hash.Valid {
-			p.Hash = hash.String
+		if err := fn(p); err != nil {
+			return err
 		}
-		if len(issuesBytes) > 0 {
-			_ = json.Unmarshal(issuesBytes, &p.Issues)
+	}
+
+	return rows.Err()
+}
+
+// runProductColumns is the select list scanRunProduct expects, read from run_products rp.
+// The accepted document comes from the version the run wrote, so only enqueued products
+// carry one.
+const runProductColumns = `product_key, disposition, reason, normalized_hash, issues_json,
+       changed_fields_json, change_classes_json, channels_json,
+       (SELECT pv.document_json
+          FROM runs r
+          JOIN product_versions pv
+            ON pv.tenant_id = r.tenant_id AND pv.product_key = rp.product_key AND pv.run_id = r.run_id
+         WHERE r.run_id = rp.run_id) AS document_json`
+
+func scanRunProduct(row rowScanner) (ingest.ProductProcessResult, error) {
+	var p ingest.ProductProcessResult
+	var reason sql.NullString
+	var hash sql.NullString
+	var issuesBytes []byte
+	var changedBytes []byte
+	var classesBytes []byte
+	var channelsBytes []byte
+	var docBytes []byte
+
+	err := row.Scan(&p.ProductKey, &p.Disposition, &reason, &hash, &issuesBytes, &changedBytes, &classesBytes, &channelsBytes, &docBytes)
+	if err != nil {
+		return ingest.ProductProcessResult{}, err
+	}
+
+	if reason.Valid {
+		p.Reason = reason.String
+	}
+	if hash.Valid {
+		p.Hash = hash.String
+	}
+	if len(issuesBytes) > 0 {
+		_ = json.Unmarshal(issuesBytes, &p.Issues)
+	}
+	if len(changedBytes) > 0 {
+		_ = json.Unmarshal(changedBytes, &p.ChangedFields)
+	}
+	if len(classesBytes) > 0 {
+		_ = json.Unmarshal(classesBytes, &p.ChangeClasses)
+	}
+	if len(channelsBytes) > 0 {
+		_ = json.Unmarshal(channelsBytes, &p.Channels)
+	}
+	if len(docBytes) > 0 {
+		var prod domain.Product
+		if err := json.Unmarshal(docBytes, &prod); err == nil {
+			p.Product = &prod
 		}
+	}
 
-		out = append(out, p)
+	return p, nil
+}
+
+func (s *MySQLStore) SummarizeRunIssues(ctx context.Context, runID string, sampleSize int) ([]RunIssueSummary, error) {
+	// Samples are picked with ROW_NUMBER rather than GROUP_CONCAT, which would build
+	// every key in the group and can truncate the last kept one at group_concat_max_len.
+	// At least one row per group is read so groups still report their count.
+	rows, err := s.db.QueryContext(ctx, `
+WITH issues AS (
+  SELECT DISTINCT COALESCE(jt.path, '') AS path, COALESCE(jt.code, '') AS code, rp.product_key
+  FROM run_products rp,
+       JSON_TABLE(rp.issues_json, '$[*]' COLUMNS (
+         path VARCHAR(255) PATH '$.path',
+         code VARCHAR(64) PATH '$.code'
+       )) jt
+  WHERE rp.run_id = ?
+), ranked AS (
+  SELECT path, code, product_key,
+         COUNT(*) OVER (PARTITION BY code, path) AS cnt,
+         ROW_NUMBER() OVER (PARTITION BY code, path ORDER BY product_key) AS rn
+  FROM issues
+)
+SELECT path, code, cnt, product_key
+FROM ranked
+WHERE rn <= GREATEST(?, 1)
+ORDER BY code, path, rn`, runID, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]RunIssueSummary, 0, 16)
+
+	for rows.Next() {
+		var path, code, key string
+		var count int
+
+		if err := rows.Scan(&path, &code, &count, &key); err != nil {
+			return nil, err
+		}
+
+		if n := len(out); n == 0 || out[n-1].Path != path || out[n-1].Code != code {
+			out = append(out, RunIssueSummary{Path: path, Code: code, Count: count, SampleProductKeys: []string{}})
+		}
+
+		sum := &out[len(out)-1]
+		if len(sum.SampleProductKeys) < sampleSize {
+			sum.SampleProductKeys = append(sum.SampleProductKeys, key)
+		}
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
+	sortIssueSummaries(out)
 	return out, nil
 }
+
+// runProductsWhere builds the WHERE clause for run_products reads.
+// Issue filters use JSON_CONTAINS so code and path must match within the same issue object.
+func runProductsWhere(runID string, filter RunProductFilter) (string, []any) {
+	conds := []string{"run_id = ?"}
+	args := []any{runID}
+
+	if filter.Disposition != "" {
+		conds = append(conds, "disposition = ?")
+		args = append(args, filter.Disposition)
+	}
+	if filter.Reason != "" {
+		conds = append(conds, "reason = ?")
+		args = append(args, filter.Reason)
+	}
+	if filter.ProductKeyPrefix != "" {
+		conds = append(conds, `product_key LIKE ? ESCAPE '\\'`)
+		args = append(args, escapeLike(filter.ProductKeyPrefix)+"%")
+	}
+
+	switch {
+	case filter.IssueCode != "" && filter.IssuePath != "":
+		conds = append(conds, "JSON_CONTAINS(issues_json, JSON_OBJECT('code', ?, 'path', ?))")
+		args = append(args, filter.IssueCode, filter.IssuePath)
+	case filter.IssueCode != "":
+		conds = append(conds, "JSON_CONTAINS(issues_json, JSON_OBJECT('code', ?))")
+		args = append(args, filter.IssueCode)
+	case filter.IssuePath != "":
+		conds = append(conds, "JSON_CONTAINS(issues_json, JSON_OBJECT('path', ?))")
+		args = append(args, filter.IssuePath)
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
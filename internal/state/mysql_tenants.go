@@ -0,0 +1,47 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/ETAnderson/conductor/internal/ingest"
+)
+
+func (s *MySQLStore) GetTenantValidation(ctx context.Context, tenantID uint64) (*ingest.ValidationPolicy, error) {
+	var policy []byte
+	err := s.db.QueryRowContext(ctx, `
+SELECT validation_json FROM tenants WHERE tenant_id = ?`, tenantID).Scan(&policy)
+	if err == sql.ErrNoRows {
+		return nil, ErrTenantNotFound
+	}
+	if err != nil || len(policy) == 0 {
+		return nil, err
+	}
+
+	var p ingest.ValidationPolicy
+	if err := json.Unmarshal(policy, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *MySQLStore) UpdateTenantValidation(ctx context.Context, tenantID uint64, policy *ingest.ValidationPolicy) error {
+	b, err := marshalValidationPolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+UPDATE tenants SET validation_json = ? WHERE tenant_id = ?`, b, tenantID)
+	if err != nil {
+		return err
+	}
+
+	// RowsAffected is 0 for an unchanged policy too, so check the tenant exists
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	_, err = s.GetTenantValidation(ctx, tenantID)
+	return err
+}
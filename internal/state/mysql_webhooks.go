@@ -0,0 +1,295 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+func (s *MySQLStore) CreateWebhookEndpoint(ctx context.Context, ep WebhookEndpoint) (WebhookEndpoint, error) {
+	if ep.CreatedAt.IsZero() {
+		ep.CreatedAt = time.Now().UTC()
+	}
+
+	events, err := json.Marshal(ep.Events)
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+INSERT INTO webhook_endpoints (tenant_id, url, secret, events_json, created_at)
+VALUES (?, ?, ?, ?, ?)`, ep.TenantID, ep.URL, ep.Secret, events, ep.CreatedAt.UTC())
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+
+	ep.ID = uint64(id)
+	return ep, nil
+}
+
+func (s *MySQLStore) ListWebhookEndpoints(ctx context.Context, tenantID uint64) ([]WebhookEndpoint, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT endpoint_id, tenant_id, url, secret, events_json, created_at
+FROM webhook_endpoints
+WHERE tenant_id = ? AND deleted_at IS NULL
+ORDER BY endpoint_id ASC`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []WebhookEndpoint
+	for rows.Next() {
+		var ep WebhookEndpoint
+		var events []byte
+		var created time.Time
+
+		if err := rows.Scan(&ep.ID, &ep.TenantID, &ep.URL, &ep.Secret, &events, &created); err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			_ = json.Unmarshal(events, &ep.Events)
+		}
+		ep.CreatedAt = created.UTC()
+
+		out = append(out, ep)
+	}
+
+	return out, rows.Err()
+}
+
+func (s *MySQLStore) DeleteWebhookEndpoint(ctx context.Context, tenantID uint64, endpointID uint64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Soft delete keeps the delivery log joinable
+	res, err := tx.ExecContext(ctx, `
+UPDATE webhook_endpoints SET deleted_at = ?
+WHERE endpoint_id = ? AND tenant_id = ? AND deleted_at IS NULL`, time.Now().UTC(), endpointID, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrWebhookEndpointNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+UPDATE webhook_deliveries SET status = ?, last_error = 'endpoint deleted'
+WHERE endpoint_id = ? AND status = ?`, WebhookDeliveryFailed, endpointID, WebhookDeliveryPending); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *MySQLStore) EnqueueWebhookEvent(ctx context.Context, tenantID uint64, eventType string, runID string, payload []byte) (int, error) {
+	return enqueueWebhookEvent(ctx, s.db, tenantID, WebhookEvent{Type: eventType, RunID: runID, Payload: payload})
+}
+
+func enqueueWebhookEvents(ctx context.Context, ex runExecer, tenantID uint64, events []WebhookEvent) error {
+	for _, ev := range events {
+		if _, err := enqueueWebhookEvent(ctx, ex, tenantID, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func enqueueWebhookEvent(ctx context.Context, ex runExecer, tenantID uint64, ev WebhookEvent) (int, error) {
+	now := time.Now().UTC()
+
+	res, err := ex.ExecContext(ctx, `
+INSERT INTO webhook_deliveries (tenant_id, endpoint_id, event_type, run_id, payload_json, status, attempts, next_attempt_at, created_at)
+SELECT tenant_id, endpoint_id, ?, ?, ?, ?, 0, ?, ?
+FROM webhook_endpoints
+WHERE tenant_id = ? AND deleted_at IS NULL AND JSON_CONTAINS(events_json, JSON_QUOTE(?))`,
+		ev.Type, nullIfEmpty(ev.RunID), ev.Payload, WebhookDeliveryPending, now, now,
+		tenantID, ev.Type)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// enqueueRunOutbox enqueues the events outbox builds for the run as it stands in tx.
+func enqueueRunOutbox(ctx context.Context, tx *sql.Tx, tenantID uint64, runID string, outbox RunOutbox) error {
+	if outbox == nil {
+		return nil
+	}
+
+	run, err := scanRun(tx.QueryRowContext(ctx, `
+SELECT `+runColumns+`
+FROM runs
+WHERE tenant_id = ? AND run_id = ?`, tenantID, runID))
+	if err != nil {
+		return err
+	}
+
+	events, err := runOutboxEvents(outbox, run)
+	if err != nil {
+		return err
+	}
+	return enqueueWebhookEvents(ctx, tx, tenantID, events)
+}
+
+func (s *MySQLStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+SELECT `+webhookDeliveryColumns+`, e.url, e.secret
+FROM webhook_deliveries d
+JOIN webhook_endpoints e ON e.endpoint_id = d.endpoint_id AND e.deleted_at IS NULL
+WHERE d.status = ? AND d.next_attempt_at <= ?
+ORDER BY d.next_attempt_at ASC, d.delivery_id ASC
+LIMIT ?
+FOR UPDATE OF d SKIP LOCKED`, WebhookDeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows, &sql.NullString{}, &sql.NullString{})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	leaseUntil := now.Add(lease).UTC()
+	for _, d := range out {
+		if _, err := tx.ExecContext(ctx, `
+UPDATE webhook_deliveries SET next_attempt_at = ? WHERE delivery_id = ?`, leaseUntil, d.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *MySQLStore) RecordWebhookAttempt(ctx context.Context, deliveryID uint64, attempt WebhookAttempt) error {
+	status := WebhookDeliveryFailed
+	var deliveredAt, retryAt any
+	switch {
+	case attempt.Delivered:
+		status = WebhookDeliveryDelivered
+		deliveredAt = attempt.At.UTC()
+	case attempt.RetryAt != nil:
+		status = WebhookDeliveryPending
+		retryAt = attempt.RetryAt.UTC()
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+UPDATE webhook_deliveries
+SET status = ?,
+    attempts = attempts + 1,
+    last_status_code = ?,
+    last_error = ?,
+    delivered_at = COALESCE(?, delivered_at),
+    next_attempt_at = COALESCE(?, next_attempt_at)
+WHERE delivery_id = ?`,
+		status, attempt.StatusCode, nullIfEmpty(attempt.Error), deliveredAt, retryAt, deliveryID)
+	return err
+}
+
+func (s *MySQLStore) ListWebhookDeliveries(ctx context.Context, tenantID uint64, endpointID uint64, limit int) ([]WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+webhookDeliveryColumns+`
+FROM webhook_deliveries d
+WHERE d.tenant_id = ? AND d.endpoint_id = ?
+ORDER BY d.delivery_id DESC
+LIMIT ?`, tenantID, endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+
+	return out, rows.Err()
+}
+
+// webhookDeliveryColumns is the select list scanWebhookDelivery expects (aliased d).
+const webhookDeliveryColumns = `d.delivery_id, d.tenant_id, d.endpoint_id, d.event_type, d.run_id, d.payload_json,
+       d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+
+// scanWebhookDelivery scans webhookDeliveryColumns; when url/secret are given they are
+// scanned after them and copied onto the delivery.
+func scanWebhookDelivery(row rowScanner, endpoint ...*sql.NullString) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	var runID, lastError sql.NullString
+	var lastStatus sql.NullInt64
+	var payload []byte
+	var next, created time.Time
+	var delivered sql.NullTime
+
+	dest := []any{
+		&d.ID, &d.TenantID, &d.EndpointID, &d.EventType, &runID, &payload,
+		&d.Status, &d.Attempts, &next, &lastStatus, &lastError, &created, &delivered,
+	}
+	for _, e := range endpoint {
+		dest = append(dest, e)
+	}
+
+	if err := row.Scan(dest...); err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	d.RunID = runID.String
+	d.Payload = payload
+	d.NextAttemptAt = next.UTC()
+	d.LastStatusCode = int(lastStatus.Int64)
+	d.LastError = lastError.String
+	d.CreatedAt = created.UTC()
+	if delivered.Valid {
+		t := delivered.Time.UTC()
+		d.DeliveredAt = &t
+	}
+	if len(endpoint) == 2 {
+		d.EndpointURL = endpoint[0].String
+		d.EndpointSecret = endpoint[1].String
+	}
+
+	return d, nil
+}
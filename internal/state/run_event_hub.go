@@ -0,0 +1,66 @@
+package state
+
+import "sync"
+
+// RunEventWatcher is implemented by stores that can signal new run events in-process
+// (MemoryStore). Callers of other stores poll ListRunEvents instead.
+//
+// notify receives a value whenever events were appended for the run since the last
+// receive; it carries no data, so re-read with ListRunEvents. stop must be called.
+type RunEventWatcher interface {
+	WatchRunEvents(tenantID uint64, runID string) (notify <-chan struct{}, stop func())
+}
+
+type runWatchKey struct {
+	tenantID uint64
+	runID    string
+}
+
+// runEventHub is a minimal in-process pub/sub keyed by tenant/run.
+type runEventHub struct {
+	mu   sync.Mutex
+	subs map[runWatchKey]map[chan struct{}]struct{}
+}
+
+func newRunEventHub() *runEventHub {
+	return &runEventHub{subs: make(map[runWatchKey]map[chan struct{}]struct{})}
+}
+
+func (h *runEventHub) watch(tenantID uint64, runID string) (<-chan struct{}, func()) {
+	k := runWatchKey{tenantID: tenantID, runID: runID}
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subs[k] == nil {
+		h.subs[k] = make(map[chan struct{}]struct{})
+	}
+	h.subs[k][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subs[k], ch)
+			if len(h.subs[k]) == 0 {
+				delete(h.subs, k)
+			}
+		})
+	}
+
+	return ch, stop
+}
+
+// publish wakes every watcher of the run without blocking; pending wake-ups coalesce.
+func (h *runEventHub) publish(tenantID uint64, runID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs[runWatchKey{tenantID: tenantID, runID: runID}] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
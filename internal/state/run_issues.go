@@ -0,0 +1,63 @@
+package state
+
+import (
+	"sort"
+
+	"github.com/ETAnderson/conductor/internal/ingest"
+)
+
+// SummarizeIssues groups the issues of a run's products by path and code, counting
+// products (not occurrences) and keeping up to sampleSize product keys per group.
+// It is SummarizeRunIssues for results that have not been stored yet.
+func SummarizeIssues(products []ingest.ProductProcessResult, sampleSize int) []RunIssueSummary {
+	type issueKey struct{ path, code string }
+
+	byKey := make(map[issueKey]*RunIssueSummary)
+	seen := make(map[issueKey]map[string]struct{})
+
+	for _, p := range products {
+		for _, it := range p.Issues {
+			k := issueKey{path: it.Path, code: it.Code}
+
+			sum, ok := byKey[k]
+			if !ok {
+				sum = &RunIssueSummary{Path: it.Path, Code: it.Code, SampleProductKeys: []string{}}
+				byKey[k] = sum
+				seen[k] = make(map[string]struct{})
+			}
+
+			// Count products, not issue occurrences
+			if _, dup := seen[k][p.ProductKey]; dup {
+				continue
+			}
+			seen[k][p.ProductKey] = struct{}{}
+			sum.Count++
+			sum.SampleProductKeys = append(sum.SampleProductKeys, p.ProductKey)
+		}
+	}
+
+	out := make([]RunIssueSummary, 0, len(byKey))
+	for _, sum := range byKey {
+		sort.Strings(sum.SampleProductKeys)
+		if len(sum.SampleProductKeys) > sampleSize {
+			sum.SampleProductKeys = sum.SampleProductKeys[:sampleSize]
+		}
+		out = append(out, *sum)
+	}
+
+	sortIssueSummaries(out)
+	return out
+}
+
+// sortIssueSummaries orders by count desc, then path/code for stable output.
+func sortIssueSummaries(items []RunIssueSummary) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		if items[i].Path != items[j].Path {
+			return items[i].Path < items[j].Path
+		}
+		return items[i].Code < items[j].Code
+	})
+}
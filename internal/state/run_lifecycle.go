@@ -0,0 +1,131 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+)
+
+// applyRunTransition moves r to `to` if the lifecycle allows it and stamps the
+// transition time: ClaimedAt when processing starts, FinishedAt on terminal states.
+func applyRunTransition(r *RunRecord, to domain.RunStatus, now time.Time) error {
+	from := domain.RunStatus(r.Status)
+	if !from.CanTransitionTo(to) {
+		return &RunTransitionError{RunID: r.RunID, From: from, To: to}
+	}
+
+	r.Status = string(to)
+	if to == domain.RunStatusProcessing {
+		r.ClaimedAt = &now
+	}
+	if to.IsTerminal() {
+		r.FinishedAt = &now
+	}
+	return nil
+}
+
+// completedStatus is the status a successfully executed run ends in: runs that lost
+// products to ingest errors are only a partial success.
+func completedStatus(errored int) domain.RunStatus {
+	if errored > 0 {
+		return domain.RunStatusPartialSuccess
+	}
+	return domain.RunStatusCompleted
+}
+
+// runOutboxEvents calls outbox for run; a nil outbox owes nothing.
+func runOutboxEvents(outbox RunOutbox, run RunRecord) ([]WebhookEvent, error) {
+	if outbox == nil {
+		return nil, nil
+	}
+	return outbox(run)
+}
+
+func validateRunStatus(status string) error {
+	if !domain.RunStatus(status).Valid() {
+		return fmt.Errorf("unknown run status %q", status)
+	}
+	return nil
+}
+
+// runExecer is satisfied by both *sql.DB and *sql.Tx.
+type runExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// transitionRun is the compare-and-set form of applyRunTransition: the UPDATE only
+// matches while the run is in a status allowed to move to `to`, and the transition is
+// recorded in run_events. extraSet/extraArgs assign additional columns
+// (e.g. ", error_message = ?").
+func transitionRun(ctx context.Context, ex runExecer, tenantID uint64, runID string, to domain.RunStatus, details map[string]any, extraSet string, extraArgs ...any) error {
+	var from []string
+	for _, s := range domain.RunStatuses() {
+		if s.CanTransitionTo(to) {
+			from = append(from, string(s))
+		}
+	}
+	if len(from) == 0 {
+		return fmt.Errorf("run status %s has no incoming transitions", to)
+	}
+
+	now := time.Now().UTC()
+	set := "status = ?"
+	args := []any{string(to)}
+	if to == domain.RunStatusProcessing {
+		set += ", claimed_at = ?"
+		args = append(args, now)
+	}
+	if to.IsTerminal() {
+		set += ", finished_at = ?"
+		args = append(args, now)
+	}
+	set += extraSet
+	args = append(args, extraArgs...)
+
+	args = append(args, runID, tenantID)
+	for _, s := range from {
+		args = append(args, s)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(from)), ",")
+
+	res, err := ex.ExecContext(ctx, `
+UPDATE runs
+SET `+set+`
+WHERE run_id = ? AND tenant_id = ? AND status IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return insertRunEvent(ctx, ex, RunEvent{
+			RunID:     runID,
+			TenantID:  tenantID,
+			Type:      domain.RunEventStatusChanged,
+			Status:    string(to),
+			Details:   details,
+			CreatedAt: now,
+		})
+	}
+
+	// Nothing matched: report whether the run is missing or in the wrong status.
+	var current string
+	err = ex.QueryRowContext(ctx, `
+SELECT status FROM runs WHERE run_id = ? AND tenant_id = ?`, runID, tenantID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return ErrRunNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return &RunTransitionError{RunID: runID, From: domain.RunStatus(current), To: to}
+}
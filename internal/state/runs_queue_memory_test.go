@@ -2,8 +2,12 @@ package state
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/ingest"
 )
 
 func TestMemoryStore_ClaimRuns_MarksProcessingAndIsOrdered(t *testing.T) {
@@ -19,9 +23,11 @@ func TestMemoryStore_ClaimRuns_MarksProcessingAndIsOrdered(t *testing.T) {
 		PushTriggered: true,
 		CreatedAt:     now.Add(-2 * time.Minute),
 	})
+	feed2 := uint64(2)
 	_ = st.InsertRun(context.Background(), RunRecord{
 		RunID:         "run2",
 		TenantID:      1,
+		FeedID:        &feed2, // different feed, so it is not serialized behind run1
 		Status:        "has_changes",
 		PushTriggered: true,
 		CreatedAt:     now.Add(-1 * time.Minute),
@@ -36,7 +42,7 @@ func TestMemoryStore_ClaimRuns_MarksProcessingAndIsOrdered(t *testing.T) {
 		CreatedAt:     now,
 	})
 
-	claims, err := st.ClaimRuns(context.Background(), 10)
+	claims, err := st.ClaimRuns(context.Background(), time.Now(), time.Minute, 10)
 	if err != nil {
 		t.Fatalf("ClaimRuns err: %v", err)
 	}
@@ -48,7 +54,7 @@ func TestMemoryStore_ClaimRuns_MarksProcessingAndIsOrdered(t *testing.T) {
 	}
 
 	// Ensure they are marked processing and not re-claimable
-	claims2, err := st.ClaimRuns(context.Background(), 10)
+	claims2, err := st.ClaimRuns(context.Background(), time.Now(), time.Minute, 10)
 	if err != nil {
 		t.Fatalf("ClaimRuns(2) err: %v", err)
 	}
@@ -56,3 +62,399 @@ func TestMemoryStore_ClaimRuns_MarksProcessingAndIsOrdered(t *testing.T) {
 		t.Fatalf("expected 0 claims after processing mark, got %d", len(claims2))
 	}
 }
+
+func TestMemoryStore_ClaimRuns_SerializesPerTenantFeed(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	feed := uint64(7)
+
+	insert := func(runID string, tenantID uint64, at time.Time) {
+		_ = st.InsertRun(ctx, RunRecord{
+			RunID:         runID,
+			TenantID:      tenantID,
+			FeedID:        &feed,
+			Status:        "has_changes",
+			PushTriggered: true,
+			CreatedAt:     at,
+		})
+	}
+
+	insert("old", 1, now)
+	// Same feed ID under another tenant is an independent queue
+	insert("other_tenant", 2, now.Add(time.Minute))
+
+	claims, err := st.ClaimRuns(ctx, time.Now(), time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimRuns err: %v", err)
+	}
+	if len(claims) != 2 || claims[0].RunID != "old" || claims[1].RunID != "other_tenant" {
+		t.Fatalf("expected [old other_tenant], got %+v", claims)
+	}
+
+	// "new" stays blocked while "old" is processing
+	insert("new", 1, now.Add(2*time.Minute))
+
+	claims, _ = st.ClaimRuns(ctx, time.Now(), time.Minute, 10)
+	if len(claims) != 0 {
+		t.Fatalf("expected no claims while feed busy, got %+v", claims)
+	}
+
+	if err := st.CompleteRun(ctx, 1, "old", nil); err != nil {
+		t.Fatalf("CompleteRun err: %v", err)
+	}
+
+	claims, _ = st.ClaimRuns(ctx, time.Now(), time.Minute, 10)
+	if len(claims) != 1 || claims[0].RunID != "new" {
+		t.Fatalf("expected [new] after old completed, got %+v", claims)
+	}
+}
+
+func TestMemoryStore_ClaimRuns_ReclaimsExpiredClaims(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+
+	t0 := time.Now().UTC()
+	for i, runID := range []string{"stalled", "waiting"} {
+		_ = st.InsertRun(ctx, RunRecord{
+			RunID:         runID,
+			TenantID:      1,
+			Status:        "has_changes",
+			PushTriggered: true,
+			CreatedAt:     t0.Add(time.Duration(i) * time.Second),
+		})
+	}
+
+	claims, err := st.ClaimRuns(ctx, t0, time.Minute, 1)
+	if err != nil || len(claims) != 1 || claims[0].RunID != "waiting" {
+		t.Fatalf("expected [waiting] (stalled coalesced into it), claims=%+v err=%v", claims, err)
+	}
+
+	// A renewed lease keeps the claim
+	if err := st.RenewRunClaim(ctx, 1, "waiting", t0.Add(50*time.Second), time.Minute); err != nil {
+		t.Fatalf("RenewRunClaim err: %v", err)
+	}
+	if claims, _ := st.ClaimRuns(ctx, t0.Add(100*time.Second), time.Minute, 10); len(claims) != 0 {
+		t.Fatalf("expected no claims within the renewed lease, got %+v", claims)
+	}
+
+	// Nothing renews it again (the worker died): the next claimer takes it over
+	_ = st.InsertRun(ctx, RunRecord{
+		RunID:         "queued",
+		TenantID:      1,
+		Status:        "has_changes",
+		PushTriggered: true,
+		CreatedAt:     t0.Add(time.Minute),
+	})
+
+	reclaimAt := t0.Add(3 * time.Minute)
+	claims, err = st.ClaimRuns(ctx, reclaimAt, time.Minute, 10)
+	if err != nil || len(claims) != 1 || claims[0].RunID != "waiting" {
+		t.Fatalf("expected the expired claim [waiting] re-claimed before queued, claims=%+v err=%v", claims, err)
+	}
+
+	rec, _, _ := st.GetRun(ctx, 1, "waiting")
+	if rec.Status != "processing" || rec.ClaimExpiresAt == nil || !rec.ClaimExpiresAt.Equal(reclaimAt.Add(time.Minute)) {
+		t.Fatalf("expected a fresh lease, got status=%s expires=%v", rec.Status, rec.ClaimExpiresAt)
+	}
+
+	events, _ := st.ListRunEvents(ctx, 1, "waiting")
+	if last := events[len(events)-1]; last.Type != domain.RunEventClaimExpired {
+		t.Fatalf("expected a claim_expired event, got %+v", last)
+	}
+
+	if err := st.CompleteRun(ctx, 1, "waiting", nil); err != nil {
+		t.Fatalf("CompleteRun err: %v", err)
+	}
+	if err := st.RenewRunClaim(ctx, 1, "waiting", reclaimAt, time.Minute); !errors.Is(err, ErrRunNotClaimed) {
+		t.Fatalf("expected ErrRunNotClaimed for a finished run, got %v", err)
+	}
+}
+
+func TestMemoryStore_ClaimRuns_CoalescesPendingRuns(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for i, id := range []string{"r1", "r2", "r3"} {
+		_ = st.InsertRun(ctx, RunRecord{
+			RunID:         id,
+			TenantID:      1,
+			Status:        "has_changes",
+			PushTriggered: true,
+			CreatedAt:     now.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	claims, err := st.ClaimRuns(ctx, time.Now(), time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimRuns err: %v", err)
+	}
+	if len(claims) != 1 || claims[0].RunID != "r3" {
+		t.Fatalf("expected newest run r3 claimed, got %+v", claims)
+	}
+
+	superseded, err := st.ListSupersededRuns(ctx, 1, "r3")
+	if err != nil {
+		t.Fatalf("ListSupersededRuns err: %v", err)
+	}
+	if len(superseded) != 2 || superseded[0].RunID != "r1" || superseded[1].RunID != "r2" {
+		t.Fatalf("expected [r1 r2] superseded, got %+v", superseded)
+	}
+	for _, r := range superseded {
+		if r.Status != "superseded" {
+			t.Fatalf("expected status superseded, got %q", r.Status)
+		}
+	}
+}
+
+func TestMemoryStore_CancelRun_RollsBackProductState(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// r1 was already pushed
+	for _, run := range []struct {
+		id     string
+		hash   string
+		status string
+	}{{"r1", "h1", "completed"}, {"r2", "h2", "has_changes"}} {
+		_ = st.InsertRun(ctx, RunRecord{
+			RunID:         run.id,
+			TenantID:      1,
+			Status:        run.status,
+			PushTriggered: true,
+			CreatedAt:     now,
+		})
+		_ = st.InsertRunProducts(ctx, run.id, []ingest.ProductProcessResult{
+			{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued, Hash: run.hash},
+		})
+		_ = st.UpsertProductState(ctx, 1, ProductStateRecord{ProductKey: "sku1", Hash: run.hash, LastRunID: run.id})
+		_, _ = st.AppendProductVersion(ctx, 1, ProductVersion{ProductKey: "sku1", RunID: run.id, Hash: run.hash})
+	}
+
+	if _, err := st.CancelRun(ctx, 2, "r2"); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound for other tenant, got %v", err)
+	}
+	if _, err := st.CancelRun(ctx, 1, "r1"); !errors.Is(err, ErrRunNotCancellable) {
+		t.Fatalf("expected ErrRunNotCancellable for completed run, got %v", err)
+	}
+
+	rec, err := st.CancelRun(ctx, 1, "r2")
+	if err != nil {
+		t.Fatalf("CancelRun err: %v", err)
+	}
+	if rec.Status != "cancelled" {
+		t.Fatalf("expected status cancelled, got %q", rec.Status)
+	}
+
+	ps, ok, _ := st.GetProductState(ctx, 1, "sku1")
+	if !ok || ps.Hash != "h1" || ps.LastRunID != "r1" {
+		t.Fatalf("expected state rolled back to r1, got ok=%v %+v", ok, ps)
+	}
+
+	versions, _ := st.ListProductVersions(ctx, 1, "sku1", 0)
+	if len(versions) != 1 || versions[0].RunID != "r1" {
+		t.Fatalf("expected only r1 version to remain, got %+v", versions)
+	}
+}
+
+func TestMemoryStore_CancelRun_ProcessingIsDeferred(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+
+	_ = st.InsertRun(ctx, RunRecord{
+		RunID:         "r1",
+		TenantID:      1,
+		Status:        "has_changes",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	})
+	_ = st.InsertRunProducts(ctx, "r1", []ingest.ProductProcessResult{
+		{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued, Hash: "h1"},
+	})
+	_ = st.UpsertProductState(ctx, 1, ProductStateRecord{ProductKey: "sku1", Hash: "h1", LastRunID: "r1"})
+
+	if _, err := st.ClaimRuns(ctx, time.Now(), time.Minute, 10); err != nil {
+		t.Fatalf("ClaimRuns err: %v", err)
+	}
+
+	rec, err := st.CancelRun(ctx, 1, "r1")
+	if err != nil {
+		t.Fatalf("CancelRun err: %v", err)
+	}
+	if rec.Status != "processing" || !rec.CancelRequested {
+		t.Fatalf("expected cancel requested on processing run, got %+v", rec)
+	}
+
+	if err := st.MarkRunCancelled(ctx, 1, "r1"); err != nil {
+		t.Fatalf("MarkRunCancelled err: %v", err)
+	}
+
+	got, _, _ := st.GetRun(ctx, 1, "r1")
+	if got.Status != "cancelled" {
+		t.Fatalf("expected status cancelled, got %q", got.Status)
+	}
+	if _, ok, _ := st.GetProductState(ctx, 1, "sku1"); ok {
+		t.Fatalf("expected product state removed for never-pushed product")
+	}
+}
+
+func TestMemoryStore_RunLifecycle_EnforcesTransitions(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+
+	_ = st.InsertRun(ctx, RunRecord{
+		RunID:         "r1",
+		TenantID:      1,
+		Status:        "has_changes",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	})
+
+	err := st.CompleteRun(ctx, 1, "r1", nil)
+	var terr *RunTransitionError
+	if !errors.As(err, &terr) || !errors.Is(err, ErrInvalidRunTransition) {
+		t.Fatalf("expected RunTransitionError completing an unclaimed run, got %v", err)
+	}
+	if terr.From != domain.RunStatusHasChanges || terr.To != domain.RunStatusCompleted {
+		t.Fatalf("unexpected transition error: %+v", terr)
+	}
+
+	if err := st.CompleteRun(ctx, 1, "missing", nil); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+
+	if _, err := st.ClaimRuns(ctx, time.Now(), time.Minute, 10); err != nil {
+		t.Fatalf("ClaimRuns err: %v", err)
+	}
+	if err := st.FailRun(ctx, 1, "r1", "boom", nil); err != nil {
+		t.Fatalf("FailRun err: %v", err)
+	}
+
+	rec, _, _ := st.GetRun(ctx, 1, "r1")
+	if rec.Status != "failed" || rec.ErrorMessage != "boom" {
+		t.Fatalf("expected failed with message, got %+v", rec)
+	}
+	if rec.ClaimedAt == nil || rec.FinishedAt == nil {
+		t.Fatalf("expected claimed/finished timestamps, got %+v", rec)
+	}
+
+	if err := st.CompleteRun(ctx, 1, "r1", nil); !errors.Is(err, ErrInvalidRunTransition) {
+		t.Fatalf("expected terminal run to reject transitions, got %v", err)
+	}
+
+	if err := st.InsertRun(ctx, RunRecord{RunID: "r2", TenantID: 1, Status: "bogus"}); err == nil {
+		t.Fatalf("expected unknown status to be rejected")
+	}
+}
+
+func TestMemoryStore_CompleteRun_PartialSuccessWhenProductsErrored(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+
+	_ = st.InsertRun(ctx, RunRecord{
+		RunID:         "r1",
+		TenantID:      1,
+		Status:        "has_changes",
+		PushTriggered: true,
+		Errored:       2,
+		CreatedAt:     time.Now().UTC(),
+	})
+
+	if _, err := st.ClaimRuns(ctx, time.Now(), time.Minute, 10); err != nil {
+		t.Fatalf("ClaimRuns err: %v", err)
+	}
+	if err := st.CompleteRun(ctx, 1, "r1", nil); err != nil {
+		t.Fatalf("CompleteRun err: %v", err)
+	}
+
+	rec, _, _ := st.GetRun(ctx, 1, "r1")
+	if rec.Status != string(domain.RunStatusPartialSuccess) || rec.FinishedAt == nil {
+		t.Fatalf("expected finished partial_success, got %+v", rec)
+	}
+}
+
+func TestMemoryStore_CompleteRun_EnqueuesOutboxWithTransition(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+
+	ep, _ := st.CreateWebhookEndpoint(ctx, WebhookEndpoint{TenantID: 1, URL: "http://hook", Events: []string{"run.completed"}})
+	_ = st.InsertRun(ctx, RunRecord{
+		RunID:         "r1",
+		TenantID:      1,
+		Status:        "has_changes",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC(),
+	})
+	if _, err := st.ClaimRuns(ctx, time.Now(), time.Minute, 10); err != nil {
+		t.Fatalf("ClaimRuns err: %v", err)
+	}
+
+	// An outbox that fails leaves the run unfinished and nothing enqueued
+	boom := errors.New("boom")
+	err := st.CompleteRun(ctx, 1, "r1", func(RunRecord) ([]WebhookEvent, error) { return nil, boom })
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected outbox error, got %v", err)
+	}
+	rec, _, _ := st.GetRun(ctx, 1, "r1")
+	if rec.Status != string(domain.RunStatusProcessing) {
+		t.Fatalf("expected run still processing, got %s", rec.Status)
+	}
+	if log, _ := st.ListWebhookDeliveries(ctx, 1, ep.ID, 10); len(log) != 0 {
+		t.Fatalf("expected no deliveries, got %+v", log)
+	}
+
+	err = st.CompleteRun(ctx, 1, "r1", func(run RunRecord) ([]WebhookEvent, error) {
+		// The outbox sees the run as it is committed
+		return []WebhookEvent{{Type: "run.completed", RunID: run.RunID, Payload: []byte(run.Status)}}, nil
+	})
+	if err != nil {
+		t.Fatalf("CompleteRun err: %v", err)
+	}
+
+	log, _ := st.ListWebhookDeliveries(ctx, 1, ep.ID, 10)
+	if len(log) != 1 || log[0].RunID != "r1" || string(log[0].Payload) != "completed" {
+		t.Fatalf("unexpected deliveries: %+v", log)
+	}
+}
+
+func TestMemoryStore_CancelRun_KeepsVersionNumbersAfterGap(t *testing.T) {
+	st := NewMemoryStore()
+	ctx := context.Background()
+
+	// r4 changed sku1 back to the hash cancelled r2 wrote, so r2's version is dropped
+	// from the middle of the history
+	for _, run := range []struct {
+		id     string
+		hash   string
+		status string
+	}{{"r1", "h1", "completed"}, {"r2", "h2", "has_changes"}, {"r3", "h3", "completed"}, {"r4", "h2", "completed"}} {
+		_ = st.InsertRun(ctx, RunRecord{RunID: run.id, TenantID: 1, Status: run.status, PushTriggered: true, CreatedAt: time.Now().UTC()})
+		_ = st.InsertRunProducts(ctx, run.id, []ingest.ProductProcessResult{
+			{ProductKey: "sku1", Disposition: domain.ProductDispositionEnqueued, Hash: run.hash},
+		})
+		_ = st.UpsertProductState(ctx, 1, ProductStateRecord{ProductKey: "sku1", Hash: run.hash, LastRunID: run.id})
+		_, _ = st.AppendProductVersion(ctx, 1, ProductVersion{ProductKey: "sku1", RunID: run.id, Hash: run.hash})
+	}
+
+	if _, err := st.CancelRun(ctx, 1, "r2"); err != nil {
+		t.Fatalf("CancelRun err: %v", err)
+	}
+
+	for version, runID := range map[int]string{1: "r1", 3: "r3", 4: "r4"} {
+		v, ok, _ := st.GetProductVersion(ctx, 1, "sku1", version)
+		if !ok || v.RunID != runID {
+			t.Fatalf("version %d: expected %s, got ok=%v %+v", version, runID, ok, v)
+		}
+	}
+	if _, ok, _ := st.GetProductVersion(ctx, 1, "sku1", 2); ok {
+		t.Fatalf("expected cancelled version 2 to be gone")
+	}
+
+	v, _ := st.AppendProductVersion(ctx, 1, ProductVersion{ProductKey: "sku1", RunID: "r5", Hash: "h5"})
+	if v.Version != 5 {
+		t.Fatalf("expected next version 5, got %d", v.Version)
+	}
+}
@@ -2,8 +2,10 @@ package state
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
+	"github.com/ETAnderson/conductor/internal/domain"
 	"github.com/ETAnderson/conductor/internal/ingest"
 )
 
@@ -20,13 +22,202 @@ type RunRecord struct {
 	Unchanged int
 	Enqueued  int
 
+	// Duplicates counts products skipped because their product_key repeated in the batch.
+	Duplicates int
+
+	// Errored counts products that hit a store error during ingest; a run that
+	// finishes with Errored > 0 ends as partial_success instead of completed.
+	Errored int
+
 	Warnings  ingest.UnknownKeyWarning
 	CreatedAt time.Time
+
+	// SupersededBy is the run that carried this run's changes when it was coalesced.
+	SupersededBy string
+
+	// CancelRequested is set when a processing run is cancelled; the executor stops between batches.
+	CancelRequested bool
+
+	// Lifecycle timestamps (see domain.RunStatus): set when the run is claimed and
+	// when it reaches a terminal status.
+	ClaimedAt  *time.Time
+	FinishedAt *time.Time
+
+	// ClaimExpiresAt is the end of the processing run's claim lease. A run still
+	// processing after it is claimed again (see Store.ClaimRuns).
+	ClaimExpiresAt *time.Time
+
+	// ErrorMessage is the executor error recorded by FailRun.
+	ErrorMessage string
+}
+
+// ProductStateRecord is the last accepted canonical state for a tenant's product_key.
+type ProductStateRecord struct {
+	ProductKey string          `json:"product_key"`
+	Hash       string          `json:"hash"`
+	LastRunID  string          `json:"last_run_id,omitempty"`
+	Product    *domain.Product `json:"product,omitempty"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+
+	// HashVersion is the ingest hash version Hash was computed with (zero reads as
+	// ingest.HashVersionV1).
+	HashVersion int `json:"hash_version"`
+
+	// Channels are the channels that accepted Product, sorted (see
+	// ingest.ProductProcessResult.AcceptedChannels). Nil when not recorded, e.g. for
+	// state written before channels were tracked or restored by a rollback.
+	Channels []string `json:"channels,omitempty"`
+}
+
+// ProductVersion is one accepted change to a product's normalized document.
+// Versions are numbered per tenant/product_key starting at 1.
+type ProductVersion struct {
+	ProductKey    string          `json:"product_key"`
+	Version       int             `json:"version"`
+	RunID         string          `json:"run_id"`
+	Hash          string          `json:"hash"`
+	HashVersion   int             `json:"hash_version"`
+	ChangedFields []string        `json:"changed_fields,omitempty"`
+	Product       *domain.Product `json:"product,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// ProductStateWrite is what an accepted product leaves behind: its canonical state and,
+// when its document changed, a new version.
+type ProductStateWrite struct {
+	State   ProductStateRecord
+	Version *ProductVersion
+}
+
+// IngestRun is everything one ingest request records. CommitIngestRun writes it
+// all-or-nothing, so canonical state never moves without the run that accounts for it.
+type IngestRun struct {
+	Run      RunRecord
+	Products []ingest.ProductProcessResult
+	States   []ProductStateWrite
+	Webhooks []WebhookEvent
+}
+
+// WebhookEvent is an outbox write owed for a run change. Stores enqueue it (one
+// delivery per endpoint subscribed to Type) in the transaction that writes the
+// change, so the event exists exactly when the change is committed.
+type WebhookEvent struct {
+	Type    string
+	RunID   string
+	Payload []byte
+}
+
+// RunOutbox builds the webhook events owed for a run that just changed status. Stores
+// call it with the run as updated, inside the transaction that updates it; a nil
+// RunOutbox enqueues nothing.
+type RunOutbox func(run RunRecord) ([]WebhookEvent, error)
+
+// RunEvent is one entry in a run's timeline: a lifecycle transition, executor
+// progress or a channel milestone.
+type RunEvent struct {
+	EventID   uint64              `json:"event_id"`
+	RunID     string              `json:"run_id"`
+	TenantID  uint64              `json:"-"`
+	Type      domain.RunEventType `json:"type"`
+	Status    string              `json:"status,omitempty"`
+	Channel   string              `json:"channel,omitempty"`
+	WorkerID  string              `json:"worker_id,omitempty"`
+	Details   map[string]any      `json:"details,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// Feed is a tenant's product feed. Validation, when set, is the policy its ingest
+// requests are validated under; nil leaves them under the tenant's policy.
+type Feed struct {
+	ID         uint64                   `json:"id"`
+	TenantID   uint64                   `json:"-"`
+	Name       string                   `json:"name"`
+	Validation *ingest.ValidationPolicy `json:"validation,omitempty"`
+	CreatedAt  time.Time                `json:"created_at"`
+}
+
+// Summary is the run's ingest counts.
+func (r RunRecord) Summary() ingest.ProcessSummary {
+	return ingest.ProcessSummary{
+		Received:   r.Received,
+		Valid:      r.Valid,
+		Rejected:   r.Rejected,
+		Unchanged:  r.Unchanged,
+		Enqueued:   r.Enqueued,
+		Duplicates: r.Duplicates,
+		Errored:    r.Errored,
+	}
 }
 
 type RunClaim struct {
 	RunID    string
 	TenantID uint64
+	FeedID   *uint64
+}
+
+// RunProductFilter narrows ListRunProducts. Empty fields are ignored; set
+// fields are combined with AND. IssueCode and IssuePath must match the same issue.
+type RunProductFilter struct {
+	Disposition      string
+	Reason           string
+	IssueCode        string
+	IssuePath        string
+	ProductKeyPrefix string
+}
+
+// RunIssueSummary aggregates validation issues across a run's products.
+type RunIssueSummary struct {
+	Path              string   `json:"path"`
+	Code              string   `json:"code"`
+	Count             int      `json:"count"`
+	SampleProductKeys []string `json:"sample_product_keys"`
+}
+
+// WebhookEndpoint is a tenant-registered receiver for run lifecycle callbacks.
+// Secret signs deliveries and is never serialized.
+type WebhookEndpoint struct {
+	ID        uint64    `json:"id"`
+	TenantID  uint64    `json:"-"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one outbox entry: an event payload owed to one endpoint.
+// EndpointURL/EndpointSecret are filled in by ClaimWebhookDeliveries for the dispatcher.
+type WebhookDelivery struct {
+	ID             uint64          `json:"id"`
+	TenantID       uint64          `json:"-"`
+	EndpointID     uint64          `json:"endpoint_id"`
+	EndpointURL    string          `json:"-"`
+	EndpointSecret string          `json:"-"`
+	EventType      string          `json:"event_type"`
+	RunID          string          `json:"run_id,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// WebhookAttempt is the outcome of one delivery attempt. A failed attempt with a nil
+// RetryAt marks the delivery failed for good.
+type WebhookAttempt struct {
+	Delivered  bool
+	StatusCode int
+	Error      string
+	At         time.Time
+	RetryAt    *time.Time
 }
 
 type IdempotencyRecord struct {
@@ -34,17 +225,53 @@ type IdempotencyRecord struct {
 	BodyJSON   []byte
 	ExpiresAt  time.Time
 	CreatedAt  time.Time
+
+	// RunID, when set, replaces BodyJSON: the response is rebuilt from the run on
+	// replay instead of caching a potentially huge body.
+	RunID string
 }
 
 type Store interface {
 	// Canonical product state
 	GetProductHash(ctx context.Context, tenantID uint64, productKey string) (hash string, ok bool, err error)
 	UpsertProductHash(ctx context.Context, tenantID uint64, productKey string, hash string) error
+	UpsertProductState(ctx context.Context, tenantID uint64, rec ProductStateRecord) error
+	GetProductState(ctx context.Context, tenantID uint64, productKey string) (ProductStateRecord, bool, error)
+	ListProductStates(ctx context.Context, tenantID uint64, afterKey string, limit int) ([]ProductStateRecord, error)
+
+	// Batched forms for bulk ingest. GetProductStates omits keys without state;
+	// AppendProductVersions numbers versions in slice order and is all-or-nothing.
+	GetProductStates(ctx context.Context, tenantID uint64, productKeys []string) (map[string]ProductStateRecord, error)
+	UpsertProductStates(ctx context.Context, tenantID uint64, recs []ProductStateRecord) error
+
+	// Product version history. A run adds at most one version per product: appending
+	// again for the same run_id returns the existing version.
+	AppendProductVersion(ctx context.Context, tenantID uint64, v ProductVersion) (ProductVersion, error)
+	AppendProductVersions(ctx context.Context, tenantID uint64, vs []ProductVersion) ([]ProductVersion, error)
+	ListProductVersions(ctx context.Context, tenantID uint64, productKey string, limit int) ([]ProductVersion, error)
+	GetProductVersion(ctx context.Context, tenantID uint64, productKey string, version int) (ProductVersion, bool, error)
 
 	// Runs (write)
 	InsertRun(ctx context.Context, run RunRecord) error
 	InsertRunProducts(ctx context.Context, runID string, products []ingest.ProductProcessResult) error
 
+	// CommitIngestRun records an ingest run, its products, the product state and
+	// versions it advances and its webhook events in one transaction; nothing is
+	// written if any part fails.
+	CommitIngestRun(ctx context.Context, in IngestRun) error
+
+	// Feeds. UpdateFeedValidation replaces the feed's policy (nil restores the default)
+	// and returns ErrFeedNotFound for feeds of other tenants.
+	CreateFeed(ctx context.Context, f Feed) (Feed, error)
+	GetFeed(ctx context.Context, tenantID uint64, feedID uint64) (Feed, bool, error)
+	ListFeeds(ctx context.Context, tenantID uint64) ([]Feed, error)
+	UpdateFeedValidation(ctx context.Context, tenantID uint64, feedID uint64, policy *ingest.ValidationPolicy) error
+
+	// Tenant validation policy: applies to the tenant's requests whose feed has no
+	// policy of its own. nil means the tenant uses the default policy.
+	GetTenantValidation(ctx context.Context, tenantID uint64) (*ingest.ValidationPolicy, error)
+	UpdateTenantValidation(ctx context.Context, tenantID uint64, policy *ingest.ValidationPolicy) error
+
 	// Idempotency cache
 	GetIdempotency(ctx context.Context, tenantID uint64, endpoint string, idemKeyHash string) (IdempotencyRecord, bool, error)
 	PutIdempotency(ctx context.Context, tenantID uint64, endpoint string, idemKeyHash string, rec IdempotencyRecord) error
@@ -52,10 +279,46 @@ type Store interface {
 	// Runs (read/debug)
 	ListRuns(ctx context.Context, tenantID uint64, limit int) ([]RunRecord, error)
 	GetRun(ctx context.Context, tenantID uint64, runID string) (RunRecord, bool, error)
-	ListRunProducts(ctx context.Context, runID string, limit int) ([]ingest.ProductProcessResult, error)
+	ListRunProducts(ctx context.Context, runID string, filter RunProductFilter, limit int) ([]ingest.ProductProcessResult, error)
+	// EachRunProduct calls fn with every product of the run in ingest order (the order
+	// they were inserted in), stopping at fn's first error.
+	EachRunProduct(ctx context.Context, runID string, fn func(ingest.ProductProcessResult) error) error
+	SummarizeRunIssues(ctx context.Context, runID string, sampleSize int) ([]RunIssueSummary, error)
 
 	// Worker queue (runs)
-	ClaimRuns(ctx context.Context, limit int) ([]RunClaim, error)
-	CompleteRun(ctx context.Context, tenantID uint64, runID string) error
-	FailRun(ctx context.Context, tenantID uint64, runID string, message string) error
+	// Status changes follow the domain.RunStatus lifecycle and are applied compare-and-set;
+	// a disallowed move returns a *RunTransitionError (errors.Is ErrInvalidRunTransition).
+	// ClaimRuns coalesces pending runs per tenant/feed: older ones are marked superseded by the claimed run.
+	// Claims are leased until now+lease; a processing run whose lease expired (its worker
+	// died or stalled) is claimed again before any pending run. RenewRunClaim extends
+	// the lease of a run still processing.
+	// CompleteRun and FailRun enqueue the events outbox builds for the finished run in
+	// the same transaction.
+	ClaimRuns(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]RunClaim, error)
+	RenewRunClaim(ctx context.Context, tenantID uint64, runID string, now time.Time, lease time.Duration) error
+	ListSupersededRuns(ctx context.Context, tenantID uint64, runID string) ([]RunRecord, error)
+	CompleteRun(ctx context.Context, tenantID uint64, runID string, outbox RunOutbox) error
+	FailRun(ctx context.Context, tenantID uint64, runID string, message string, outbox RunOutbox) error
+
+	// Run timeline. Status transitions are recorded by the store itself; AppendRunEvent
+	// is for executor progress and channel milestones. Events list oldest first.
+	AppendRunEvent(ctx context.Context, ev RunEvent) error
+	ListRunEvents(ctx context.Context, tenantID uint64, runID string) ([]RunEvent, error)
+
+	// Webhooks. EnqueueWebhookEvent is the outbox write: one pending delivery per endpoint
+	// subscribed to eventType. ClaimWebhookDeliveries leases due deliveries so concurrent
+	// dispatchers don't send the same one twice.
+	CreateWebhookEndpoint(ctx context.Context, ep WebhookEndpoint) (WebhookEndpoint, error)
+	ListWebhookEndpoints(ctx context.Context, tenantID uint64) ([]WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, tenantID uint64, endpointID uint64) error
+	EnqueueWebhookEvent(ctx context.Context, tenantID uint64, eventType string, runID string, payload []byte) (int, error)
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, deliveryID uint64, attempt WebhookAttempt) error
+	ListWebhookDeliveries(ctx context.Context, tenantID uint64, endpointID uint64, limit int) ([]WebhookDelivery, error)
+
+	// CancelRun cancels a pending run immediately (rolling back the product_state it advanced)
+	// or flags a processing run for cancellation. Finished runs return ErrRunNotCancellable.
+	CancelRun(ctx context.Context, tenantID uint64, runID string) (RunRecord, error)
+	// MarkRunCancelled finishes a processing run whose executor stopped on cancellation.
+	MarkRunCancelled(ctx context.Context, tenantID uint64, runID string) error
 }
@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned for webhook endpoints on loopback, private, link-local
+// or otherwise non-public addresses.
+var ErrBlockedAddress = errors.New("webhook endpoint address is not public")
+
+// cgnat is the shared address space (RFC 6598), internal to carrier networks.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewClient returns the client deliveries are sent with. Unless allowPrivate is set it
+// refuses to connect to non-public addresses; the check runs on the address being
+// dialled, after DNS resolution, so a public hostname resolving to an internal address
+// is refused too. Redirects are never followed: the 3xx is the delivery's response.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !publicAddr(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy: it would do the dialling and bypass the address check
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// ValidateEndpointURL checks a webhook endpoint URL at registration: it must be an
+// absolute https URL whose host is not a loopback or private IP literal. allowInsecure
+// (dev and tests) also accepts http and any host. Hostnames are checked again when
+// dialled, since DNS can change after registration.
+func ValidateEndpointURL(raw string, allowInsecure bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Hostname() == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && allowInsecure:
+	case u.Scheme == "http":
+		return errors.New("url must use https")
+	default:
+		return errors.New("url must be an absolute http(s) URL")
+	}
+
+	if allowInsecure {
+		return nil
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !publicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!cgnat.Contains(ip)
+}
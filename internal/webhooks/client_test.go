@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewClient_BlocksPrivateAddressesAndRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/internal", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	// httptest listens on loopback
+	_, err := NewClient(time.Second, false).Get(srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress dialling loopback, got %v", err)
+	}
+
+	client := NewClient(time.Second, true)
+	resp, err := client.Get(srv.URL + "/redirect")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected the redirect returned unfollowed, got %d", resp.StatusCode)
+	}
+}
+
+func TestValidateEndpointURL(t *testing.T) {
+	cases := []struct {
+		url      string
+		insecure bool
+		ok       bool
+	}{
+		{url: "https://hooks.example.com/conductor", ok: true},
+		{url: "http://hooks.example.com/conductor"},
+		{url: "http://hooks.example.com/conductor", insecure: true, ok: true},
+		{url: "ftp://hooks.example.com/conductor", insecure: true},
+		{url: "/relative"},
+		{url: "https://127.0.0.1/hook"},
+		{url: "https://localhost/hook"},
+		{url: "https://10.1.2.3/hook"},
+		{url: "https://169.254.169.254/latest/meta-data"},
+		{url: "https://[::1]/hook"},
+		{url: "https://[::ffff:192.168.0.1]/hook"},
+		{url: "https://100.64.0.1/hook"},
+		{url: "https://8.8.8.8/hook", ok: true},
+		{url: "http://127.0.0.1:8080/hook", insecure: true, ok: true},
+	}
+
+	for _, tc := range cases {
+		err := ValidateEndpointURL(tc.url, tc.insecure)
+		if (err == nil) != tc.ok {
+			t.Errorf("ValidateEndpointURL(%q, insecure=%v) = %v, want ok=%v", tc.url, tc.insecure, err, tc.ok)
+		}
+	}
+}
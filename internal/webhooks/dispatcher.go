@@ -0,0 +1,211 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+// Dispatcher delivers pending outbox entries, retrying failures with exponential backoff.
+type Dispatcher struct {
+	Store state.Store
+
+	// Client defaults to NewClient(10s, AllowPrivateNetworks).
+	Client *http.Client
+
+	// AllowPrivateNetworks lets the default client deliver to loopback and private
+	// addresses (dev and tests only).
+	AllowPrivateNetworks bool
+
+	PollEvery time.Duration
+	BatchSize int
+
+	// MaxAttempts before a delivery is marked failed. Defaults to 8.
+	MaxAttempts int
+
+	// Backoff after the n-th failed attempt is BaseBackoff * 2^(n-1), capped at MaxBackoff.
+	// Defaults: 10s and 1h.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// Lease hides claimed deliveries from other dispatchers while in flight. Defaults to 1m.
+	Lease time.Duration
+
+	// Concurrency is how many deliveries of a batch are sent at once (default 10). A
+	// batch must be sent within half the Lease: sends still in flight then are cut off
+	// and recorded as failed attempts, so every attempt is recorded while its delivery
+	// is still leased and a slow receiver cannot get a delivery sent twice.
+	Concurrency int
+
+	// Now is a test seam. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func (d Dispatcher) Run(ctx context.Context) error {
+	if d.Store == nil {
+		return errors.New("store is nil")
+	}
+	if d.PollEvery <= 0 {
+		d.PollEvery = 1 * time.Second
+	}
+
+	ticker := time.NewTicker(d.PollEvery)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchOnce(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce claims due deliveries, sends them and records each attempt.
+// It returns how many deliveries were attempted.
+func (d Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	d = d.withDefaults()
+
+	deliveries, err := d.Store.ClaimWebhookDeliveries(ctx, d.Now().UTC(), d.Lease, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, d.Lease/2)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, d.Concurrency)
+	for _, del := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(del state.WebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			attempt := d.send(sendCtx, del)
+			if err := d.Store.RecordWebhookAttempt(ctx, del.ID, attempt); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(del)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	return len(deliveries), nil
+}
+
+func (d Dispatcher) withDefaults() Dispatcher {
+	if d.Client == nil {
+		d.Client = NewClient(10*time.Second, d.AllowPrivateNetworks)
+	}
+	if d.BatchSize <= 0 {
+		d.BatchSize = 50
+	}
+	if d.MaxAttempts <= 0 {
+		d.MaxAttempts = 8
+	}
+	if d.BaseBackoff <= 0 {
+		d.BaseBackoff = 10 * time.Second
+	}
+	if d.MaxBackoff <= 0 {
+		d.MaxBackoff = time.Hour
+	}
+	if d.Lease <= 0 {
+		d.Lease = time.Minute
+	}
+	if d.Concurrency <= 0 {
+		d.Concurrency = 10
+	}
+	if d.Now == nil {
+		d.Now = time.Now
+	}
+	return d
+}
+
+func (d Dispatcher) send(ctx context.Context, del state.WebhookDelivery) state.WebhookAttempt {
+	now := d.Now().UTC()
+	attempt := state.WebhookAttempt{At: now}
+
+	statusCode, err := d.post(ctx, del, now)
+	attempt.StatusCode = statusCode
+
+	if err == nil {
+		attempt.Delivered = true
+		return attempt
+	}
+
+	attempt.Error = err.Error()
+
+	// del.Attempts counts earlier attempts; this one is number del.Attempts+1
+	n := del.Attempts + 1
+	if n < d.MaxAttempts {
+		retryAt := now.Add(d.backoff(n))
+		attempt.RetryAt = &retryAt
+	}
+
+	return attempt
+}
+
+func (d Dispatcher) post(ctx context.Context, del state.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.EndpointURL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "conductor-webhooks")
+	req.Header.Set(HeaderEvent, del.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(del.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(del.EndpointSecret, ts, del.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (d Dispatcher) backoff(n int) time.Duration {
+	b := d.BaseBackoff
+	for i := 1; i < n; i++ {
+		b *= 2
+		if b >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return b
+}
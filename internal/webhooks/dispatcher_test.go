@@ -0,0 +1,272 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+type receivedHook struct {
+	event string
+	ts    int64
+	sig   string
+	body  []byte
+}
+
+// newReceiver returns a local endpoint that fails the first `failures` requests with 500.
+func newReceiver(t *testing.T, failures int) (*httptest.Server, func() []receivedHook) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var got []receivedHook
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+
+		mu.Lock()
+		got = append(got, receivedHook{
+			event: r.Header.Get(HeaderEvent),
+			ts:    ts,
+			sig:   r.Header.Get(HeaderSignature),
+			body:  body,
+		})
+		n := len(got)
+		mu.Unlock()
+
+		if n <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []receivedHook {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedHook(nil), got...)
+	}
+}
+
+func seedFinishedRun(t *testing.T, st *state.MemoryStore, runID string) {
+	t.Helper()
+
+	ctx := context.Background()
+	if err := st.InsertRun(ctx, state.RunRecord{
+		RunID:         runID,
+		TenantID:      1,
+		Status:        "has_changes",
+		PushTriggered: true,
+		Enqueued:      2,
+		CreatedAt:     time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("InsertRun: %v", err)
+	}
+	if _, err := st.ClaimRuns(ctx, time.Now(), time.Minute, 10); err != nil {
+		t.Fatalf("ClaimRuns: %v", err)
+	}
+	if err := st.CompleteRun(ctx, 1, runID, Notifier{}.RunFinished); err != nil {
+		t.Fatalf("CompleteRun: %v", err)
+	}
+}
+
+func TestDispatcher_DeliversSignedPayloadWithRetry(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+
+	srv, received := newReceiver(t, 1)
+
+	ep, _ := st.CreateWebhookEndpoint(ctx, state.WebhookEndpoint{
+		TenantID: 1,
+		URL:      srv.URL,
+		Secret:   "s3cret",
+		Events:   []string{EventRunCompleted},
+	})
+
+	seedFinishedRun(t, st, "run_hook_1")
+
+	now := time.Now().UTC()
+	d := Dispatcher{
+		Store:                st,
+		AllowPrivateNetworks: true,
+		BaseBackoff:          10 * time.Second,
+		Now:                  func() time.Time { return now },
+	}
+
+	// First attempt fails (500) and is rescheduled
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("DispatchOnce: n=%d err=%v", n, err)
+	}
+	// Not due yet
+	if n, _ := d.DispatchOnce(ctx); n != 0 {
+		t.Fatalf("expected no due deliveries before backoff, got %d", n)
+	}
+
+	now = now.Add(11 * time.Second)
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("DispatchOnce after backoff: n=%d err=%v", n, err)
+	}
+
+	got := received()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(got))
+	}
+
+	last := got[1]
+	if last.event != EventRunCompleted {
+		t.Fatalf("expected event header %q, got %q", EventRunCompleted, last.event)
+	}
+	if !Verify("s3cret", last.ts, last.body, last.sig) {
+		t.Fatalf("signature did not verify: %s", last.sig)
+	}
+
+	var p RunPayload
+	if err := json.Unmarshal(last.body, &p); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if p.RunID != "run_hook_1" || p.Status != "completed" || p.Enqueued != 2 {
+		t.Fatalf("unexpected payload: %+v", p)
+	}
+
+	log, _ := st.ListWebhookDeliveries(ctx, 1, ep.ID, 10)
+	if len(log) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(log))
+	}
+	if log[0].Status != state.WebhookDeliveryDelivered || log[0].Attempts != 2 || log[0].LastStatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected delivery log: %+v", log[0])
+	}
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+
+	srv, received := newReceiver(t, 100)
+
+	ep, _ := st.CreateWebhookEndpoint(ctx, state.WebhookEndpoint{
+		TenantID: 1,
+		URL:      srv.URL,
+		Secret:   "s3cret",
+		Events:   []string{EventRunCompleted},
+	})
+	// Endpoints not subscribed to the event get nothing
+	_, _ = st.CreateWebhookEndpoint(ctx, state.WebhookEndpoint{
+		TenantID: 1,
+		URL:      srv.URL,
+		Events:   []string{EventRunFailed},
+	})
+
+	seedFinishedRun(t, st, "run_hook_2")
+
+	now := time.Now().UTC()
+	d := Dispatcher{
+		Store:                st,
+		AllowPrivateNetworks: true,
+		MaxAttempts:          3,
+		BaseBackoff:          time.Second,
+		Now:                  func() time.Time { return now },
+	}
+
+	for i := 0; i < 5; i++ {
+		_, _ = d.DispatchOnce(ctx)
+		now = now.Add(time.Minute)
+	}
+
+	if got := len(received()); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+
+	log, _ := st.ListWebhookDeliveries(ctx, 1, ep.ID, 10)
+	if len(log) != 1 || log[0].Status != state.WebhookDeliveryFailed || log[0].Attempts != 3 {
+		t.Fatalf("expected delivery failed after 3 attempts, got %+v", log)
+	}
+}
+
+func TestDispatcher_SlowReceiversFinishWithinLease(t *testing.T) {
+	st := state.NewMemoryStore()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		if r.URL.Path == "/hang" {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	slow, _ := st.CreateWebhookEndpoint(ctx, state.WebhookEndpoint{
+		TenantID: 1, URL: srv.URL + "/slow", Secret: "s", Events: []string{EventRunCompleted},
+	})
+	hang, _ := st.CreateWebhookEndpoint(ctx, state.WebhookEndpoint{
+		TenantID: 1, URL: srv.URL + "/hang", Secret: "s", Events: []string{EventRunCompleted},
+	})
+	for i := 0; i < 3; i++ {
+		seedFinishedRun(t, st, "run_slow_"+strconv.Itoa(i))
+	}
+
+	// Sent one at a time the six deliveries would outlast the lease
+	lease := 600 * time.Millisecond
+	d := Dispatcher{Store: st, Lease: lease, AllowPrivateNetworks: true}
+
+	start := time.Now()
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 6 {
+		t.Fatalf("DispatchOnce: n=%d err=%v", n, err)
+	}
+	if took := time.Since(start); took >= lease {
+		t.Fatalf("expected the batch to finish within the %s lease, took %s", lease, took)
+	}
+
+	// Once the lease has lapsed another dispatcher finds nothing left to resend
+	time.Sleep(lease)
+	if n, err := (Dispatcher{Store: st, Lease: lease, AllowPrivateNetworks: true}).DispatchOnce(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing to redeliver, got n=%d err=%v", n, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/slow"] != 3 || requests["/hang"] != 3 {
+		t.Fatalf("expected one request per delivery, got %v", requests)
+	}
+
+	for _, tc := range []struct {
+		endpoint uint64
+		status   string
+	}{
+		{endpoint: slow.ID, status: state.WebhookDeliveryDelivered},
+		{endpoint: hang.ID, status: state.WebhookDeliveryPending},
+	} {
+		log, _ := st.ListWebhookDeliveries(ctx, 1, tc.endpoint, 10)
+		if len(log) != 3 {
+			t.Fatalf("endpoint %d: expected 3 deliveries, got %d", tc.endpoint, len(log))
+		}
+		for _, del := range log {
+			if del.Status != tc.status || del.Attempts != 1 {
+				t.Fatalf("endpoint %d: expected %s after 1 attempt, got %+v", tc.endpoint, tc.status, del)
+			}
+		}
+	}
+}
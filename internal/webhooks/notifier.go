@@ -0,0 +1,132 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/ingest"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+const (
+	EventRunCompleted        = "run.completed"
+	EventRunFailed           = "run.failed"
+	EventRunRejectedProducts = "run.rejected_products"
+)
+
+// Events lists the event types endpoints can subscribe to.
+func Events() []string {
+	return []string{EventRunCompleted, EventRunFailed, EventRunRejectedProducts}
+}
+
+func IsEvent(event string) bool {
+	for _, e := range Events() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// RunPayload is the JSON body delivered for run events.
+type RunPayload struct {
+	Event     string `json:"event"`
+	RunID     string `json:"run_id"`
+	Status    string `json:"status"`
+	Received  int    `json:"received"`
+	Valid     int    `json:"valid"`
+	Rejected  int    `json:"rejected"`
+	Unchanged int    `json:"unchanged"`
+	Enqueued  int    `json:"enqueued"`
+
+	Duplicates int `json:"duplicates"`
+	Errored    int `json:"errored"`
+
+	Error string `json:"error,omitempty"`
+
+	// Issues summarizes why products were rejected, overall or by a channel
+	// (run.rejected_products only).
+	Issues []state.RunIssueSummary `json:"issues,omitempty"`
+
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Notifier builds run events for the webhook outbox. The events are handed to the store
+// with the run write they describe, so they commit (or roll back) together; the
+// Dispatcher delivers them.
+type Notifier struct {
+	// IssueSamples bounds the product keys per issue group in run.rejected_products. Defaults to 5.
+	IssueSamples int
+}
+
+// RunIngested builds run.rejected_products when ingestion rejected any products,
+// including products only some of the enabled channels rejected.
+func (n Notifier) RunIngested(run state.RunRecord, products []ingest.ProductProcessResult) ([]state.WebhookEvent, error) {
+	if run.Rejected == 0 && !anyChannelRejected(products) {
+		return nil, nil
+	}
+
+	samples := n.IssueSamples
+	if samples <= 0 {
+		samples = 5
+	}
+
+	p := runPayload(EventRunRejectedProducts, run)
+	p.Issues = state.SummarizeIssues(products, samples)
+	return event(run, p)
+}
+
+// RunFinished builds run.completed (also for partial_success) or run.failed for a run
+// the worker finished.
+// It is a state.RunOutbox for CompleteRun and FailRun.
+func (n Notifier) RunFinished(run state.RunRecord) ([]state.WebhookEvent, error) {
+	var p RunPayload
+	switch domain.RunStatus(run.Status) {
+	case domain.RunStatusCompleted, domain.RunStatusPartialSuccess:
+		p = runPayload(EventRunCompleted, run)
+	case domain.RunStatusFailed:
+		p = runPayload(EventRunFailed, run)
+		p.Error = run.ErrorMessage
+	default:
+		return nil, nil
+	}
+
+	return event(run, p)
+}
+
+func anyChannelRejected(products []ingest.ProductProcessResult) bool {
+	for _, p := range products {
+		for _, c := range p.Channels {
+			if c.Status == ingest.ChannelStatusRejected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func event(run state.RunRecord, p RunPayload) ([]state.WebhookEvent, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return []state.WebhookEvent{{Type: p.Event, RunID: run.RunID, Payload: body}}, nil
+}
+
+func runPayload(event string, run state.RunRecord) RunPayload {
+	return RunPayload{
+		Event:      event,
+		RunID:      run.RunID,
+		Status:     run.Status,
+		Received:   run.Received,
+		Valid:      run.Valid,
+		Rejected:   run.Rejected,
+		Unchanged:  run.Unchanged,
+		Enqueued:   run.Enqueued,
+		Duplicates: run.Duplicates,
+		Errored:    run.Errored,
+		OccurredAt: time.Now().UTC(),
+	}
+}
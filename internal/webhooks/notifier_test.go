@@ -0,0 +1,46 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ETAnderson/conductor/internal/domain"
+	"github.com/ETAnderson/conductor/internal/ingest"
+	"github.com/ETAnderson/conductor/internal/state"
+)
+
+func TestNotifier_RunIngested_ReportsChannelRejections(t *testing.T) {
+	run := state.RunRecord{RunID: "run_ch", TenantID: 1, Status: "has_changes", Valid: 1, Enqueued: 1}
+	products := []ingest.ProductProcessResult{{
+		ProductKey:  "sku1",
+		Disposition: domain.ProductDispositionEnqueued,
+		Issues:      []ingest.ValidationIssue{{Path: "channel.meta.brand", Code: ingest.IssueCodeBrandRequired}},
+		Channels: map[string]ingest.ChannelResult{
+			"google": {Status: ingest.ChannelStatusValid},
+			"meta":   {Status: ingest.ChannelStatusRejected},
+		},
+	}}
+
+	events, err := Notifier{}.RunIngested(run, products)
+	if err != nil {
+		t.Fatalf("RunIngested: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != EventRunRejectedProducts {
+		t.Fatalf("expected one run.rejected_products event, got %+v", events)
+	}
+
+	var p RunPayload
+	if err := json.Unmarshal(events[0].Payload, &p); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if len(p.Issues) != 1 || p.Issues[0].Path != "channel.meta.brand" || p.Issues[0].SampleProductKeys[0] != "sku1" {
+		t.Fatalf("unexpected issues: %+v", p.Issues)
+	}
+
+	// Accepted everywhere: nothing to report
+	products[0].Issues = nil
+	products[0].Channels["meta"] = ingest.ChannelResult{Status: ingest.ChannelStatusValid}
+	if events, _ := (Notifier{}).RunIngested(run, products); len(events) != 0 {
+		t.Fatalf("expected no events, got %+v", events)
+	}
+}
@@ -0,0 +1,31 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	HeaderEvent     = "X-Conductor-Event"
+	HeaderDelivery  = "X-Conductor-Delivery"
+	HeaderTimestamp = "X-Conductor-Timestamp"
+	HeaderSignature = "X-Conductor-Signature"
+)
+
+// Sign returns the X-Conductor-Signature value for a delivery: "sha256=" followed by the
+// hex HMAC-SHA256 of "{timestamp}.{body}" keyed with the endpoint secret. Binding the
+// timestamp lets receivers reject replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/ETAnderson/conductor/internal/api/tenantctx"
+	"github.com/ETAnderson/conductor/internal/state"
 )
 
 type ctxKey string
@@ -29,3 +30,9 @@ func RunID(ctx context.Context) string {
 func WithTenant(ctx context.Context, tenantID uint64) context.Context {
 	return tenantctx.WithTenantID(ctx, tenantID)
 }
+
+// WithWorkerID stores the worker ID on context using the state package, so run events
+// written by the store are attributed to this worker.
+func WithWorkerID(ctx context.Context, workerID string) context.Context {
+	return state.WithWorkerID(ctx, workerID)
+}
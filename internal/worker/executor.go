@@ -1,7 +1,16 @@
 package worker
 
-import "context"
+import (
+	"context"
+
+	"github.com/ETAnderson/conductor/internal/state"
+)
 
 type RunExecutor interface {
 	Execute(ctx context.Context, runID string, tenantID uint64) error
 }
+
+// RunNotifier builds the outbox events for runs the worker finished (completed or failed).
+type RunNotifier interface {
+	RunFinished(run state.RunRecord) ([]state.WebhookEvent, error)
+}
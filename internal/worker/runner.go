@@ -3,20 +3,43 @@ package worker
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"os"
 	"time"
 
 	"github.com/ETAnderson/conductor/internal/state"
 )
 
 type Runner struct {
-	Store       state.Store
-	PollEvery   time.Duration
-	ClaimTTL    time.Duration
+	Store     state.Store
+	PollEvery time.Duration
+
+	// ClaimTTL is the claim lease: the runner renews it while a run executes, and a
+	// run whose lease lapses (its worker died) is claimed again. Defaults to 30s.
+	ClaimTTL time.Duration
+
 	MaxPerClaim int
 	ProcessFn   func(ctx context.Context, job Job) error
 	Executor    RunExecutor
+
+	// WorkerID identifies this runner on run events. Defaults to "<hostname>-<pid>".
+	WorkerID string
+
+	// Notifier, if set, builds the events owed for a run the worker completed or failed
+	// (e.g. webhooks).
+	Notifier RunNotifier
+
+	// FinishAttempts bounds tries to record a run's outcome (default 3); failures
+	// after the last are logged and the run is left to be reclaimed.
+	FinishAttempts int
+
+	// Logger reports store errors the runner cannot return. Defaults to log.Default().
+	Logger *log.Logger
 }
 
+const finishRetryBackoff = 100 * time.Millisecond
+
 type Job struct {
 	RunID    string
 	TenantID uint64
@@ -27,18 +50,7 @@ func (r Runner) Run(ctx context.Context) error {
 	if r.Store == nil {
 		return errors.New("store is nil")
 	}
-	if r.PollEvery <= 0 {
-		r.PollEvery = 500 * time.Millisecond
-	}
-	if r.ClaimTTL <= 0 {
-		r.ClaimTTL = 30 * time.Second
-	}
-	if r.MaxPerClaim <= 0 {
-		r.MaxPerClaim = 10
-	}
-	if r.ProcessFn == nil {
-		r.ProcessFn = func(context.Context, Job) error { return nil }
-	}
+	r = r.withDefaults()
 
 	ticker := time.NewTicker(r.PollEvery)
 	defer ticker.Stop()
@@ -60,8 +72,36 @@ func (r Runner) Run(ctx context.Context) error {
 	}
 }
 
+func (r Runner) withDefaults() Runner {
+	if r.PollEvery <= 0 {
+		r.PollEvery = 500 * time.Millisecond
+	}
+	if r.ClaimTTL <= 0 {
+		r.ClaimTTL = 30 * time.Second
+	}
+	if r.MaxPerClaim <= 0 {
+		r.MaxPerClaim = 10
+	}
+	if r.ProcessFn == nil {
+		r.ProcessFn = func(context.Context, Job) error { return nil }
+	}
+	if r.WorkerID == "" {
+		r.WorkerID = defaultWorkerID()
+	}
+	if r.FinishAttempts <= 0 {
+		r.FinishAttempts = 3
+	}
+	if r.Logger == nil {
+		r.Logger = log.Default()
+	}
+	return r
+}
+
 func (r Runner) tick(ctx context.Context) error {
-	claims, err := r.Store.ClaimRuns(ctx, r.MaxPerClaim)
+	r = r.withDefaults()
+	ctx = WithWorkerID(ctx, r.WorkerID)
+
+	claims, err := r.Store.ClaimRuns(ctx, time.Now().UTC(), r.ClaimTTL, r.MaxPerClaim)
 	if err != nil {
 		return err
 	}
@@ -74,20 +114,106 @@ func (r Runner) tick(ctx context.Context) error {
 
 		jobCtx := WithRunID(WithTenant(ctx, c.TenantID), c.RunID)
 
+		stopRenewing := r.keepClaim(jobCtx, c)
 		var execErr error
 		if r.Executor != nil {
 			execErr = r.Executor.Execute(jobCtx, c.RunID, c.TenantID)
 		} else {
 			execErr = r.ProcessFn(jobCtx, job)
 		}
+		stopRenewing()
 
-		if execErr != nil {
-			_ = r.Store.FailRun(jobCtx, c.TenantID, c.RunID, execErr.Error())
+		if errors.Is(execErr, state.ErrRunCancelled) {
+			r.finish(jobCtx, c, "mark cancelled", func() error {
+				return r.Store.MarkRunCancelled(jobCtx, c.TenantID, c.RunID)
+			})
 			continue
 		}
 
-		_ = r.Store.CompleteRun(jobCtx, c.TenantID, c.RunID)
+		// The notifier's events are enqueued in the transaction that finishes the run.
+		var outbox state.RunOutbox
+		if r.Notifier != nil {
+			outbox = r.Notifier.RunFinished
+		}
+		if execErr != nil {
+			r.finish(jobCtx, c, "fail", func() error {
+				return r.Store.FailRun(jobCtx, c.TenantID, c.RunID, execErr.Error(), outbox)
+			})
+		} else {
+			r.finish(jobCtx, c, "complete", func() error {
+				return r.Store.CompleteRun(jobCtx, c.TenantID, c.RunID, outbox)
+			})
+		}
 	}
 
 	return nil
 }
+
+// keepClaim renews the run's claim lease every third of ClaimTTL until the returned
+// stop func is called, so a run that executes for longer than ClaimTTL is not
+// claimed again by another worker.
+func (r Runner) keepClaim(ctx context.Context, c state.RunClaim) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		t := time.NewTicker(r.ClaimTTL / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				err := r.Store.RenewRunClaim(ctx, c.TenantID, c.RunID, time.Now().UTC(), r.ClaimTTL)
+				if err != nil && ctx.Err() == nil {
+					r.Logger.Printf("run %s: renew claim: %v", c.RunID, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// finish records a run's outcome, retrying store errors with backoff. Lifecycle errors
+// (the run is gone or already moved on) are not retried. A run whose outcome could
+// not be recorded stays processing and is claimed again once its lease expires.
+func (r Runner) finish(ctx context.Context, c state.RunClaim, action string, fn func() error) {
+	backoff := finishRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return
+		}
+
+		permanent := errors.Is(err, state.ErrRunNotFound) || errors.Is(err, state.ErrInvalidRunTransition) ||
+			errors.Is(err, state.ErrRunNotCancellable)
+		if permanent || attempt >= r.FinishAttempts || ctx.Err() != nil {
+			r.Logger.Printf("run %s: %s after %d attempt(s): %v", c.RunID, action, attempt, err)
+			return
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			r.Logger.Printf("run %s: %s: %v", c.RunID, action, err)
+			return
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -1,8 +1,11 @@
 package worker
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log"
+	"strings"
 	"testing"
 	"time"
 
@@ -156,3 +159,152 @@ func TestRunner_Tick_DoesNotReprocessAlreadyClaimedOrCompleted(t *testing.T) {
 		t.Fatalf("expected ProcessFn called once total, got %d", calls)
 	}
 }
+
+func TestRunner_Tick_CancelledMarksCancelled(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	runID := "run_test_cancel_1"
+	tenantID := uint64(1)
+
+	err := st.InsertRun(context.Background(), state.RunRecord{
+		RunID:         runID,
+		TenantID:      tenantID,
+		Status:        "has_changes",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC().Add(-1 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("InsertRun: %v", err)
+	}
+
+	r := Runner{
+		Store:       st,
+		MaxPerClaim: 10,
+		ProcessFn: func(ctx context.Context, job Job) error {
+			return state.ErrRunCancelled
+		},
+	}
+
+	if err := r.tick(context.Background()); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+
+	rec, _, err := st.GetRun(context.Background(), tenantID, runID)
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if rec.Status != "cancelled" {
+		t.Fatalf("expected status=cancelled, got %q", rec.Status)
+	}
+}
+
+func TestRunner_Tick_RecordsTimelineWithWorkerID(t *testing.T) {
+	st := state.NewMemoryStore()
+
+	runID := "run_test_events_1"
+	tenantID := uint64(1)
+
+	err := st.InsertRun(context.Background(), state.RunRecord{
+		RunID:         runID,
+		TenantID:      tenantID,
+		Status:        "has_changes",
+		PushTriggered: true,
+		CreatedAt:     time.Now().UTC().Add(-1 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("InsertRun: %v", err)
+	}
+
+	r := Runner{
+		Store:       st,
+		MaxPerClaim: 10,
+		WorkerID:    "worker-a",
+		ProcessFn: func(ctx context.Context, job Job) error {
+			if got := state.WorkerID(ctx); got != "worker-a" {
+				t.Fatalf("expected ctx worker id worker-a, got %q", got)
+			}
+			return nil
+		},
+	}
+
+	if err := r.tick(context.Background()); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+
+	events, err := st.ListRunEvents(context.Background(), tenantID, runID)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+
+	var statuses []string
+	for _, ev := range events {
+		statuses = append(statuses, ev.Status)
+	}
+	if len(events) != 3 || statuses[0] != "has_changes" || statuses[1] != "processing" || statuses[2] != "completed" {
+		t.Fatalf("expected has_changes -> processing -> completed, got %v", statuses)
+	}
+	if events[0].WorkerID != "" || events[1].WorkerID != "worker-a" || events[2].WorkerID != "worker-a" {
+		t.Fatalf("expected worker id on worker transitions, got %+v", events)
+	}
+}
+
+// flakyFinishStore fails the first failures CompleteRun calls.
+type flakyFinishStore struct {
+	state.Store
+	failures int
+	calls    int
+}
+
+func (s *flakyFinishStore) CompleteRun(ctx context.Context, tenantID uint64, runID string, outbox state.RunOutbox) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("connection reset")
+	}
+	return s.Store.CompleteRun(ctx, tenantID, runID, outbox)
+}
+
+func TestRunner_Tick_RetriesAndLogsFinishErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		failures   int
+		wantStatus string
+		wantLog    bool
+	}{
+		{name: "transient", failures: 1, wantStatus: "completed"},
+		{name: "persistent", failures: 10, wantStatus: "processing", wantLog: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := state.NewMemoryStore()
+			st := &flakyFinishStore{Store: mem, failures: tc.failures}
+
+			err := mem.InsertRun(context.Background(), state.RunRecord{
+				RunID:         "run_finish",
+				TenantID:      1,
+				Status:        "has_changes",
+				PushTriggered: true,
+				CreatedAt:     time.Now().UTC(),
+			})
+			if err != nil {
+				t.Fatalf("InsertRun: %v", err)
+			}
+
+			var logs bytes.Buffer
+			r := Runner{
+				Store:          st,
+				FinishAttempts: 2,
+				Logger:         log.New(&logs, "", 0),
+			}
+			if err := r.tick(context.Background()); err != nil {
+				t.Fatalf("tick: %v", err)
+			}
+
+			rec, _, _ := mem.GetRun(context.Background(), 1, "run_finish")
+			if rec.Status != tc.wantStatus {
+				t.Fatalf("expected status=%s, got %s", tc.wantStatus, rec.Status)
+			}
+			if got := strings.Contains(logs.String(), "connection reset"); got != tc.wantLog {
+				t.Fatalf("expected logged=%v, got log %q", tc.wantLog, logs.String())
+			}
+		})
+	}
+}
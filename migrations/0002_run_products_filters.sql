@@ -0,0 +1,5 @@
+-- Indexes backing run product filters on /v1/debug/runs/{run_id}
+-- (product_key prefix filters use the (run_id, product_key) primary key)
+ALTER TABLE run_products
+  ADD KEY idx_run_products_run_disposition (run_id, disposition),
+  ADD KEY idx_run_products_run_reason (run_id, reason);
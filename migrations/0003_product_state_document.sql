@@ -0,0 +1,4 @@
+-- Keep the last accepted normalized product document alongside its hash
+ALTER TABLE product_state
+  ADD COLUMN document_json JSON NULL AFTER normalized_hash,
+  ADD COLUMN last_run_id VARCHAR(64) NULL AFTER document_json;
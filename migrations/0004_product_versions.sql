@@ -0,0 +1,13 @@
+-- History of accepted product documents (one row per accepted change)
+CREATE TABLE IF NOT EXISTS product_versions (
+  tenant_id BIGINT UNSIGNED NOT NULL,
+  product_key VARCHAR(255) NOT NULL,
+  version INT UNSIGNED NOT NULL,
+  run_id VARCHAR(64) NOT NULL,
+  normalized_hash CHAR(64) NOT NULL,
+  changed_fields_json JSON NULL,
+  document_json JSON NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (tenant_id, product_key, version),
+  KEY idx_product_versions_run (run_id)
+) ENGINE=InnoDB;
@@ -0,0 +1,3 @@
+-- Top-level fields that changed for enqueued products
+ALTER TABLE run_products
+  ADD COLUMN changed_fields_json JSON NULL AFTER issues_json;
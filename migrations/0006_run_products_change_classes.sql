@@ -0,0 +1,3 @@
+-- Change classes (lifecycle, price_inventory, media, content) for enqueued products
+ALTER TABLE run_products
+  ADD COLUMN change_classes_json JSON NULL AFTER changed_fields_json;
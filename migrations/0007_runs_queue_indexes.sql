@@ -0,0 +1,4 @@
+-- Queue scans: pending runs oldest first, and in-flight runs per tenant/feed
+ALTER TABLE runs
+  ADD KEY idx_runs_status_created (status, created_at),
+  ADD KEY idx_runs_tenant_feed_status (tenant_id, feed_id, status);
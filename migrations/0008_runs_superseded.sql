@@ -0,0 +1,4 @@
+-- Coalesced runs point at the run that carried their changes
+ALTER TABLE runs
+  ADD COLUMN superseded_by VARCHAR(64) NULL AFTER warnings_json,
+  ADD KEY idx_runs_superseded_by (superseded_by);
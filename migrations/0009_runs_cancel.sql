@@ -0,0 +1,3 @@
+-- Cancellation requested for a processing run (executor stops between batches)
+ALTER TABLE runs
+  ADD COLUMN cancel_requested TINYINT(1) NOT NULL DEFAULT 0 AFTER superseded_by;
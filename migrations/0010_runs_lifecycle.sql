@@ -0,0 +1,5 @@
+-- Run lifecycle timestamps and failure message
+ALTER TABLE runs
+  ADD COLUMN claimed_at TIMESTAMP NULL AFTER created_at,
+  ADD COLUMN finished_at TIMESTAMP NULL AFTER claimed_at,
+  ADD COLUMN error_message TEXT NULL AFTER finished_at;
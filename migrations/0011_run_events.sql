@@ -0,0 +1,15 @@
+-- Run timeline: lifecycle transitions, executor progress and channel milestones
+CREATE TABLE IF NOT EXISTS run_events (
+  event_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  run_id VARCHAR(64) NOT NULL,
+  tenant_id BIGINT UNSIGNED NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  status VARCHAR(64) NULL,
+  channel VARCHAR(64) NULL,
+  worker_id VARCHAR(255) NULL,
+  details_json JSON NULL,
+  created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  PRIMARY KEY (event_id),
+  KEY idx_run_events_run (tenant_id, run_id, event_id),
+  CONSTRAINT fk_run_events_run FOREIGN KEY (run_id) REFERENCES runs(run_id)
+) ENGINE=InnoDB;
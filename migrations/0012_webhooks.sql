@@ -0,0 +1,34 @@
+-- Tenant webhook endpoints for run lifecycle callbacks
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+  endpoint_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  tenant_id BIGINT UNSIGNED NOT NULL,
+  url VARCHAR(2048) NOT NULL,
+  secret VARCHAR(255) NOT NULL,
+  events_json JSON NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  deleted_at TIMESTAMP NULL,
+  PRIMARY KEY (endpoint_id),
+  KEY idx_webhook_endpoints_tenant (tenant_id, deleted_at),
+  CONSTRAINT fk_webhook_endpoints_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+) ENGINE=InnoDB;
+
+-- Outbox: one row per event owed to an endpoint, retried with backoff until delivered or failed
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  delivery_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  tenant_id BIGINT UNSIGNED NOT NULL,
+  endpoint_id BIGINT UNSIGNED NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  run_id VARCHAR(64) NULL,
+  payload_json JSON NOT NULL,
+  status VARCHAR(32) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP(6) NOT NULL,
+  last_status_code INT NULL,
+  last_error TEXT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  delivered_at TIMESTAMP NULL,
+  PRIMARY KEY (delivery_id),
+  KEY idx_webhook_deliveries_due (status, next_attempt_at),
+  KEY idx_webhook_deliveries_endpoint (tenant_id, endpoint_id, delivery_id),
+  CONSTRAINT fk_webhook_deliveries_endpoint FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints(endpoint_id)
+) ENGINE=InnoDB;
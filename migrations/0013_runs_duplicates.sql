@@ -0,0 +1,3 @@
+-- Products skipped because their product_key repeated within the batch
+ALTER TABLE runs
+  ADD COLUMN duplicates INT NOT NULL DEFAULT 0 AFTER enqueued;
@@ -0,0 +1,3 @@
+-- Products that hit a store error during ingest (run ends as partial_success)
+ALTER TABLE runs
+  ADD COLUMN errored INT NOT NULL DEFAULT 0 AFTER duplicates;
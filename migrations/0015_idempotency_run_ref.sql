@@ -0,0 +1,4 @@
+-- Idempotent bulk ingests cache a reference to their run instead of the full response body
+ALTER TABLE idempotency
+  MODIFY COLUMN response_body_json JSON NULL,
+  ADD COLUMN run_id VARCHAR(64) NULL AFTER response_body_json;
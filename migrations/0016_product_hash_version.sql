@@ -0,0 +1,6 @@
+-- Hash version each stored normalized_hash was computed with (existing hashes are v1)
+ALTER TABLE product_state
+  ADD COLUMN hash_version INT NOT NULL DEFAULT 1 AFTER normalized_hash;
+
+ALTER TABLE product_versions
+  ADD COLUMN hash_version INT NOT NULL DEFAULT 1 AFTER normalized_hash;
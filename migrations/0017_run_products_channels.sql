@@ -0,0 +1,3 @@
+-- Per-channel validation outcome (status and issues) for each run product
+ALTER TABLE run_products
+  ADD COLUMN channels_json JSON NULL AFTER change_classes_json;
@@ -0,0 +1,10 @@
+-- A run adds at most one version per product, so retried appends cannot duplicate history
+DELETE pv FROM product_versions pv
+JOIN product_versions earlier
+  ON earlier.tenant_id = pv.tenant_id
+ AND earlier.product_key = pv.product_key
+ AND earlier.run_id = pv.run_id
+ AND earlier.version < pv.version;
+
+ALTER TABLE product_versions
+  ADD UNIQUE KEY uq_product_versions_run (tenant_id, product_key, run_id);
@@ -0,0 +1,10 @@
+-- product_key is an exact identifier: SKU1 and sku1 (or "sku1 ") are different products,
+-- as they are to the ingest pipeline, so compare keys byte for byte without padding
+ALTER TABLE product_state
+  MODIFY product_key VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_bin NOT NULL;
+
+ALTER TABLE run_products
+  MODIFY product_key VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_bin NOT NULL;
+
+ALTER TABLE product_versions
+  MODIFY product_key VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_bin NOT NULL;
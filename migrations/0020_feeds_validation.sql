@@ -0,0 +1,3 @@
+-- Validation policy applied to the feed's ingest requests (NULL: the default policy)
+ALTER TABLE feeds
+  ADD COLUMN validation_json JSON NULL AFTER name;
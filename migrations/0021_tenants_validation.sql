@@ -0,0 +1,3 @@
+-- Validation policy for the tenant's feeds that have none of their own (NULL: the default policy)
+ALTER TABLE tenants
+  ADD COLUMN validation_json JSON NULL AFTER name;
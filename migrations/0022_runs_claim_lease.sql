@@ -0,0 +1,9 @@
+-- Claim lease: a processing run whose lease expired is claimed again by the next worker
+ALTER TABLE runs
+  ADD COLUMN claim_expires_at TIMESTAMP NULL AFTER claimed_at,
+  ADD KEY idx_runs_status_claim_expires (status, claim_expires_at);
+
+-- Runs claimed before leases existed get one, so they cannot block their feed forever
+UPDATE runs
+SET claim_expires_at = COALESCE(claimed_at, CURRENT_TIMESTAMP) + INTERVAL 10 MINUTE
+WHERE status = 'processing';
@@ -0,0 +1,5 @@
+-- Ingest order of a run's products, so responses rebuilt from the run keep input order
+-- (rows written before this migration share position 0 and fall back to product_key order)
+ALTER TABLE run_products
+  ADD COLUMN position INT UNSIGNED NOT NULL DEFAULT 0 AFTER product_key,
+  ADD KEY idx_run_products_position (run_id, position);
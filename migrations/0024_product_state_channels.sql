@@ -0,0 +1,5 @@
+-- Channels that accepted each product's current document, so a change in the
+-- accepted set is pushed even when the document itself is unchanged.
+-- NULL for existing rows: not recorded.
+ALTER TABLE product_state
+  ADD COLUMN channels_json JSON NULL AFTER document_json;